@@ -1,11 +1,26 @@
 import type { Component, JSX } from 'solid-js';
-import { A, Navigate } from '@solidjs/router';
+import { A, Navigate, useLocation } from '@solidjs/router';
 import { Show, Switch, Match } from 'solid-js';
 import { useAuthManager } from '../auth';
 import { Button } from "@suid/material";
 
+// useLoginHref links to the twitch login so that it comes back to the page
+// the user is on once they've logged in.
+const useLoginHref = () => {
+  let location = useLocation();
+
+  return () => {
+    if (location.pathname === "/login") {
+      return "/oauth/login";
+    }
+    let params = new URLSearchParams({ return: location.pathname + location.search });
+    return `/oauth/login?${params}`;
+  };
+}
+
 export const NavLogin: Component = () => {
   let am = useAuthManager();
+  let loginHref = useLoginHref();
 
   return (
     <Switch>
@@ -13,7 +28,7 @@ export const NavLogin: Component = () => {
         <a href="#">-</a>
       </Match>
       <Match when={am.user() === null}>
-        <a href="/oauth/login">Login</a>
+        <a href={loginHref()}>Login</a>
       </Match>
       <Match when={am.user()}>
         <A href={`/user/${am.user()!.id}`}>{am.user()!.name}</A>
@@ -35,18 +50,19 @@ export const IfLoggedIn: Component<{ children: JSX.Element }> = (props) => {
 
 export const LoginButton: Component = () => {
   let am = useAuthManager();
+  let loginHref = useLoginHref();
 
   return (
     <Switch>
       <Match when={am.user.loading}>
-        <a href="/oauth/login">
+        <a href={loginHref()}>
           <div class="button">
             Login with Twitch
           </div>
         </a>
       </Match>
       <Match when={am.user() === null}>
-        <Button variant="contained" color="primary" size="large" href="/oauth/login">
+        <Button variant="contained" color="primary" size="large" href={loginHref()}>
           Login with Twitch
         </Button>
       </Match>
@@ -59,12 +75,22 @@ export const LoginButton: Component = () => {
   )
 }
 
+// safeReturnPath only allows paths on this site, the same rule the server
+// applies, so the lander can't be used as an open redirect.
+const safeReturnPath = (p: string | null): string | null => {
+  if (p === null || !p.startsWith("/") || p.startsWith("//") || p.startsWith("/\\")) {
+    return null;
+  }
+  return p;
+}
+
 export const LoginLander: Component = () => {
   let am = useAuthManager();
 
   let url = new URL(window.location.href);
   let params = new URLSearchParams(url.hash.slice(1));
   let token = params.get("token");
+  let returnTo = safeReturnPath(url.searchParams.get("return"));
   if (token) {
     am.setToken(token);
   }
@@ -78,7 +104,7 @@ export const LoginLander: Component = () => {
         <div>{am.user.error.toString()}</div>
       </Match>
       <Match when={am.user()}>
-        <Navigate href={`/user/${am.user()!.id}`} />
+        <Navigate href={returnTo ?? `/user/${am.user()!.id}`} />
       </Match>
     </Switch>
   );
@@ -12,7 +12,13 @@ type MiniCardProps = {
 export const MiniCard: Component<MiniCardProps> = (props) => {
   const { collectable } = props;
 
-  const imageURL = "https://images.shindaggers.io/images/" + collectable.image_path;
+  // Images uploaded before thumbnails existed only have the full size version
+  const fallback = (e: Event) => {
+    const img = e.currentTarget as HTMLImageElement;
+    if (img.src !== collectable.image_url) {
+      img.src = collectable.image_url;
+    }
+  };
 
   const cls = ["mini-card", rarityclass(collectable.rarity)].join(" ");
 
@@ -33,7 +39,7 @@ export const MiniCard: Component<MiniCardProps> = (props) => {
       </div>
 
       <div class="card-image">
-        <img src={imageURL} />
+        <img src={collectable.mini_image_url || collectable.image_url} onError={fallback} />
       </div>
 
       <div class="author">
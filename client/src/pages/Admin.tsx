@@ -203,7 +203,14 @@ const CollectableList: Component = () => {
                   {(collectable) => (
                     <tr>
                       <td> <A href={`/admin/knife/${collectable.id}`}>{collectable.id}</A></td>
-                      <td> <A href={`/admin/knife/${collectable.id}`}>{collectable.name}</A></td>
+                      <td>
+                        <A href={`/admin/knife/${collectable.id}`}>{collectable.name}</A>
+                        <Show when={collectable.possible_duplicate_of}>
+                          {(dupe) => (
+                            <div>Looks like <A href={`/admin/knife/${dupe().id}`}>{dupe().name}</A></div>
+                          )}
+                        </Show>
+                      </td>
                       <td>{collectable.author.name}</td>
                       <td>{collectable.rarity}</td>
                       <td><A href={collectable.image_url}>{collectable.image_path}</A></td>
@@ -13,14 +13,31 @@ type UserCollection = {
   User: User;
   Collectables: IssuedCollectable[];
   Equipped: IssuedCollectable | null;
+  NextCursor?: string;
 }
 
+// The collection endpoint is paged, follow the cursors to get all of it.
 const fetchUserCollection = async (id: string): Promise<UserCollection> => {
-  let response = await fetch(`/api/user/${id}/collection`)
-  if (response.status !== 200) {
-    throw new Error("unexpected status code " + response.statusText);
-  }
-  return await response.json();
+  let collection: UserCollection | undefined;
+  let cursor = "";
+  do {
+    let params = new URLSearchParams({ limit: "500" });
+    if (cursor) {
+      params.set("cursor", cursor);
+    }
+    let response = await fetch(`/api/user/${id}/collection?${params}`)
+    if (response.status !== 200) {
+      throw new Error("unexpected status code " + response.statusText);
+    }
+    let page: UserCollection = await response.json();
+    if (collection) {
+      collection.Collectables.push(...page.Collectables);
+    } else {
+      collection = page;
+    }
+    cursor = page.NextCursor || "";
+  } while (cursor);
+  return collection;
 }
 
 export const UserCollection: Component = (props) => {
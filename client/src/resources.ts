@@ -10,11 +10,19 @@ export type Collectable = {
   rarity: Rarity;
   image_path: string;
   image_url: string;
+  card_image_url?: string;
+  mini_image_url?: string;
 };
 
 export type AdminCollectable = Collectable & {
   deleted: boolean;
   approved: boolean;
+  possible_duplicate_of?: {
+    id: string;
+    name: string;
+    image_url: string;
+    distance: number;
+  };
 }
 
 export type IssuedCollectable = Collectable & {
@@ -3,7 +3,6 @@ package main
 import (
 	"bytes"
 	"context"
-	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -17,6 +16,7 @@ import (
 
 	"github.com/cconger/shindaggers/pkg/db"
 	model "github.com/cconger/shindaggers/pkg/db/.gen/postgres/public/model"
+	"github.com/cconger/shindaggers/pkg/images"
 
 	"github.com/gorilla/mux"
 )
@@ -24,7 +24,8 @@ import (
 var (
 	errMissingField  = fmt.Errorf("missing required field")
 	errUnimplmeneted = fmt.Errorf("unimplemented")
-	errAdminOnly     = fmt.Errorf("admin only")
+	errTwitchLookup  = fmt.Errorf("unable to get user from twitch")
+	errSessionEnded  = fmt.Errorf("session expired")
 )
 
 const (
@@ -35,6 +36,11 @@ const (
 	RarityUltraRare = "Ultra Rare"
 )
 
+const defaultImageBaseURL = "https://images.shindaggers.io/images/"
+
+// imageBaseURL is prepended to image paths, set from IMAGE_BASE_URL at startup.
+var imageBaseURL = defaultImageBaseURL
+
 var rarities = []string{
 	RarityCommon,
 	RarityUncommon,
@@ -57,12 +63,15 @@ func serveAPIErr(w http.ResponseWriter, err error, statusCode int, userMessage s
 		userMessage = "Unexpected Error"
 	}
 
-	slog.Error("apierror", "statuscode", statusCode, "userMessage", userMessage, "err", err)
+	// requestIDMiddleware has already put the id on the response
+	requestID := w.Header().Get("X-Request-Id")
+
+	slog.Error("apierror", "statuscode", statusCode, "userMessage", userMessage, "requestID", requestID, "err", err)
 
 	writeErr := json.NewEncoder(w).Encode(&apierror{
 		StatusCode:   statusCode,
 		ErrorMessage: userMessage,
-		RequestID:    "", // TODO(cconger): extract created request id
+		RequestID:    requestID,
 	})
 
 	if writeErr != nil {
@@ -90,6 +99,8 @@ type IssuedCollectable struct {
 	Edition    string    `json:"edition"`
 	IssuedAt   time.Time `json:"issued_at"`
 	Deleted    bool      `json:"deleted"`
+
+	Provenance []OwnershipTransfer `json:"provenance,omitempty"`
 }
 
 type Tags struct {
@@ -139,9 +150,14 @@ func CollectableFromDBCollectable(c *db.Collectable) Collectable {
 			ID:   strconv.FormatInt(c.Creator.ID, 10),
 			Name: c.Creator.Name,
 		},
-		Rarity:    c.Rarity,
-		ImagePath: c.Imagepath,
-		ImageURL:  "https://images.shindaggers.io/images/" + c.Imagepath,
+		Rarity:       c.Rarity,
+		ImagePath:    c.Imagepath,
+		ImageURL:     imageBaseURL + c.Imagepath,
+		CardImageURL: imageBaseURL + images.CardName(c.Imagepath),
+		MiniImageURL: imageBaseURL + images.MiniName(c.Imagepath),
+	}
+	if c.CollectionID != nil {
+		res.CollectionID = strconv.FormatInt(*c.CollectionID, 10)
 	}
 	return res
 }
@@ -155,27 +171,49 @@ func IssuedCollectableFromDBCollectable(c *db.Collectable) IssuedCollectable {
 }
 
 type Collectable struct {
-	ID        string `json:"id"`
-	Name      string `json:"name"`
-	Author    User   `json:"author"`
-	Rarity    string `json:"rarity"`
-	ImagePath string `json:"image_path"`
-	ImageURL  string `json:"image_url"`
+	ID           string         `json:"id"`
+	Name         string         `json:"name"`
+	Author       User           `json:"author"`
+	Rarity       string         `json:"rarity"`
+	ImagePath    string         `json:"image_path"`
+	ImageURL     string         `json:"image_url"`
+	CardImageURL string         `json:"card_image_url"`
+	MiniImageURL string         `json:"mini_image_url"`
+	CollectionID string         `json:"collection_id,omitempty"`
+	Editions     []EditionCount `json:"editions,omitempty"`
 }
 
 type AdminCollectable struct {
 	Collectable
 
-	Deleted  bool `json:"deleted"`
-	Approved bool `json:"approved"`
+	Deleted        bool   `json:"deleted"`
+	Approved       bool   `json:"approved"`
+	ReviewStatus   string `json:"review_status"`
+	ReviewFeedback string `json:"review_feedback,omitempty"`
+
+	PossibleDuplicateOf *DuplicateMatch `json:"possible_duplicate_of,omitempty"`
+}
+
+// DuplicateMatch is an approved collectable whose image looks like the one
+// on a pending submission.
+type DuplicateMatch struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	ImageURL string `json:"image_url"`
+	Distance int    `json:"distance"`
 }
 
 func AdminCollectableFromDBCollectable(k *db.Collectable) AdminCollectable {
-	return AdminCollectable{
-		Collectable: CollectableFromDBCollectable(k),
-		Deleted:     k.DeletedAt != nil,
-		Approved:    k.ApprovedAt != nil,
+	c := AdminCollectable{
+		Collectable:  CollectableFromDBCollectable(k),
+		Deleted:      k.DeletedAt != nil,
+		Approved:     k.ApprovedAt != nil,
+		ReviewStatus: k.ReviewStatus,
 	}
+	if k.ReviewFeedback != nil {
+		c.ReviewFeedback = *k.ReviewFeedback
+	}
+	return c
 }
 
 func UserFromDBUser(u *db.User) User {
@@ -226,6 +264,16 @@ func (s *Server) getIssuedCollectable(w http.ResponseWriter, r *http.Request) {
 
 	res := IssuedCollectableFromCollectableInstance(&c[0])
 
+	history, err := s.db.GetOwnershipHistory(ctx, id)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+	res.Provenance = make([]OwnershipTransfer, len(history))
+	for i, h := range history {
+		res.Provenance[i] = OwnershipTransferFromDBOwnershipEntry(&h)
+	}
+
 	serveAPIPayload(
 		w,
 		&res,
@@ -235,17 +283,37 @@ func (s *Server) getIssuedCollectable(w http.ResponseWriter, r *http.Request) {
 func (s *Server) getCollection(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	c, err := s.db.GetCollectables(ctx, db.GetCollectablesOptions{
-		Collection: 1,
+	collections, err := s.db.GetCollections(ctx, db.GetCollectionsOptions{
+		OnlyActive: true,
 	})
 	if err != nil {
 		serveAPIErr(w, err, http.StatusInternalServerError, "")
 		return
 	}
 
-	res := make([]Collectable, len(c))
-	for i, c := range c {
-		res[i] = CollectableFromDBCollectable(c)
+	res := []Collectable{}
+	for _, collection := range collections {
+		c, err := s.db.GetCollectables(ctx, db.GetCollectablesOptions{
+			Collection: collection.ID,
+		})
+		if err != nil {
+			serveAPIErr(w, err, http.StatusInternalServerError, "")
+			return
+		}
+
+		counts, err := s.db.GetEditionCounts(ctx, db.GetEditionCountsOptions{
+			ByCollection: collection.ID,
+		})
+		if err != nil {
+			serveAPIErr(w, err, http.StatusInternalServerError, "")
+			return
+		}
+		editions := EditionCountsByCollectable(counts)
+
+		for _, c := range c {
+			res = append(res, CollectableFromDBCollectable(c))
+			res[len(res)-1].Editions = editions[c.ID]
+		}
 	}
 
 	serveAPIPayload(
@@ -266,12 +334,25 @@ func (s *Server) getCollectable(w http.ResponseWriter, r *http.Request) {
 	}
 
 	c, err := s.db.GetCollectable(ctx, id, db.GetCollectableOptions{})
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			serveAPIErr(w, err, http.StatusNotFound, "Unknown collectable")
+			return
+		}
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+
+	counts, err := s.db.GetEditionCounts(ctx, db.GetEditionCountsOptions{
+		ByCollectable: c.ID,
+	})
 	if err != nil {
 		serveAPIErr(w, err, http.StatusInternalServerError, "")
 		return
 	}
 
 	res := CollectableFromDBCollectable(c)
+	res.Editions = EditionCountsByCollectable(counts)[c.ID]
 
 	serveAPIPayload(
 		w,
@@ -279,11 +360,49 @@ func (s *Server) getCollectable(w http.ResponseWriter, r *http.Request) {
 	)
 }
 
+const (
+	defaultLatestLimit = 15
+	maxLatestLimit     = 100
+
+	defaultCollectionLimit = 100
+	maxCollectionLimit     = 500
+)
+
+// getLatest lists recent pulls, newest first. The body stays a bare array for
+// existing clients so the cursor for the next page is in X-Next-Cursor.
 func (s *Server) getLatest(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
+	filter, err := parseInstanceFilter(r.URL.Query(), defaultLatestLimit, maxLatestLimit)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit := filter.Limit
+	filter.Limit++
+
 	options := db.GetLatestIssuesOptions{
-		ByCollection: 1,
+		InstanceFilter: filter,
+	}
+
+	collectable := r.URL.Query().Get("collectable")
+	if collectable != "" {
+		collectableID, err := strconv.ParseInt(collectable, 10, 64)
+		if err != nil {
+			serveAPIErr(w, err, http.StatusBadRequest, "collectable is not numeric")
+			return
+		}
+		options.ByCollectable = collectableID
+	}
+
+	collection := r.URL.Query().Get("collection")
+	if collection != "" {
+		collectionID, err := strconv.ParseInt(collection, 10, 64)
+		if err != nil {
+			serveAPIErr(w, err, http.StatusBadRequest, "collection is not numeric")
+			return
+		}
+		options.ByCollection = collectionID
 	}
 
 	since := r.URL.Query().Get("since")
@@ -302,6 +421,12 @@ func (s *Server) getLatest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	lp, next := nextPage(lp, limit)
+	w.Header().Set("Access-Control-Expose-Headers", "X-Next-Cursor")
+	if next != "" {
+		w.Header().Set("X-Next-Cursor", next)
+	}
+
 	res := make([]IssuedCollectable, len(lp))
 	for i, c := range lp {
 		res[i] = IssuedCollectableFromCollectableInstance(&c)
@@ -314,14 +439,13 @@ func (s *Server) getLatest(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) getAuthUser(ctx context.Context, r *http.Request) (*db.User, error) {
-	rawToken := r.Header.Get("Authorization")
-	t, err := base64.URLEncoding.DecodeString(rawToken)
+	auth, err := s.getSession(ctx, r)
 	if err != nil {
-		return nil, fmt.Errorf("authorization token unreadable")
+		return nil, err
 	}
 
 	u, err := s.db.GetUser(ctx, db.GetUserOptions{
-		AuthToken: t,
+		ID: auth.UserID,
 	})
 	if err != nil {
 		return nil, err
@@ -339,12 +463,20 @@ func (s *Server) getLoggedInUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	roles, err := s.db.GetUserRoles(ctx, u.ID)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+
 	serveAPIPayload(
 		w,
 		&struct {
-			User User
+			User  User
+			Roles []string
 		}{
-			User: UserFromDBUser(u),
+			User:  UserFromDBUser(u),
+			Roles: roles,
 		},
 	)
 }
@@ -563,9 +695,30 @@ func (s *Server) getUserCollection(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	issuedRaw, err := s.db.GetCollectableInstances(ctx, db.GetCollectableInstancesOptions{
-		ByOwner: user.ID,
-	})
+	filter, err := parseInstanceFilter(r.URL.Query(), defaultCollectionLimit, maxCollectionLimit)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit := filter.Limit
+	filter.Limit++
+
+	options := db.GetCollectableInstancesOptions{
+		InstanceFilter: filter,
+		ByOwner:        user.ID,
+	}
+
+	collectable := r.URL.Query().Get("collectable")
+	if collectable != "" {
+		collectableID, err := strconv.ParseInt(collectable, 10, 64)
+		if err != nil {
+			serveAPIErr(w, err, http.StatusBadRequest, "collectable is not numeric")
+			return
+		}
+		options.ByCollectable = collectableID
+	}
+
+	issuedRaw, err := s.db.GetCollectableInstances(ctx, options)
 	if err != nil {
 		if errors.Is(err, db.ErrNotFound) {
 			serveAPIErr(w, err, http.StatusNotFound, "Unknown user")
@@ -575,11 +728,23 @@ func (s *Server) getUserCollection(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	issuedRaw, next := nextPage(issuedRaw, limit)
+
 	issuedCollectables := make([]IssuedCollectable, len(issuedRaw))
 	for i, raw := range issuedRaw {
 		issuedCollectables[i] = IssuedCollectableFromCollectableInstance(&raw)
 	}
 
+	// Editions summarises the whole collection, not just this page
+	counts, err := s.db.GetEditionCounts(ctx, db.GetEditionCountsOptions{
+		ByOwner: user.ID,
+	})
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+	editions := EditionTotals(counts)
+
 	eqRaw, err := s.db.GetEquippedForUser(ctx, user.ID)
 	if err != nil {
 		serveAPIErr(w, err, http.StatusInternalServerError, "Unable to fetch equipped knife")
@@ -597,6 +762,8 @@ func (s *Server) getUserCollection(w http.ResponseWriter, r *http.Request) {
 			User         User
 			Collectables []IssuedCollectable
 			Equipped     *IssuedCollectable
+			Editions     []EditionCount
+			NextCursor   string `json:",omitempty"`
 		}{
 			User: User{
 				ID:   strconv.FormatInt(user.ID, 10),
@@ -604,6 +771,8 @@ func (s *Server) getUserCollection(w http.ResponseWriter, r *http.Request) {
 			},
 			Collectables: issuedCollectables,
 			Equipped:     equipped,
+			Editions:     editions,
+			NextCursor:   next,
 		},
 	)
 }
@@ -651,14 +820,21 @@ func (s *Server) EquipHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if (user.Admin == nil || !*user.Admin) && (user.ID != parseduid) {
-		serveAPIErr(
-			w,
-			fmt.Errorf("non admin user (%d) tried to equip knife for someone else", user.ID),
-			http.StatusForbidden,
-			"You cannot equip knives for other users",
-		)
-		return
+	if user.ID != parseduid {
+		isAdmin, err := s.hasRole(ctx, user, db.RoleAdmin)
+		if err != nil {
+			serveAPIErr(w, err, http.StatusInternalServerError, "")
+			return
+		}
+		if !isAdmin {
+			serveAPIErr(
+				w,
+				fmt.Errorf("non admin user (%d) tried to equip knife for someone else", user.ID),
+				http.StatusForbidden,
+				"You cannot equip knives for other users",
+			)
+			return
+		}
 	}
 
 	// Lookup if knife is owned by user
@@ -688,27 +864,32 @@ func (s *Server) EquipHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var before any
+	if user.ID != parseduid {
+		previous, err := s.db.GetEquippedForUser(ctx, parseduid)
+		if err != nil {
+			serveAPIErr(w, err, http.StatusInternalServerError, "")
+			return
+		}
+		if previous != nil {
+			before = previous.CollectableInstances
+		}
+	}
+
 	err = s.db.SetEquipped(ctx, issuedID, parseduid)
 	if err != nil {
 		serveAPIErr(w, err, http.StatusInternalServerError, "")
 		return
 	}
+
+	if user.ID != parseduid {
+		s.audit(ctx, user.ID, auditEquip, auditTargetUser, parseduid, before, issuedRaw[0].CollectableInstances)
+	}
 }
 
 func (s *Server) adminListCollectables(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	u, err := s.getAuthUser(ctx, r)
-	if err != nil {
-		serveAPIErr(w, err, http.StatusForbidden, "could not identify user")
-		return
-	}
-
-	if u.Admin == nil || !*u.Admin {
-		serveAPIErr(w, errAdminOnly, http.StatusForbidden, "")
-		return
-	}
-
 	dbknives, err := s.db.GetCollectables(ctx, db.GetCollectablesOptions{
 		GetDeleted: true,
 	})
@@ -726,15 +907,23 @@ func (s *Server) adminListCollectables(w http.ResponseWriter, r *http.Request) {
 	pendingknives, err := s.db.GetCollectables(ctx, db.GetCollectablesOptions{
 		GetUnapproved:  true,
 		OnlyUnapproved: true,
+		ReviewStatus:   db.ReviewPending,
 	})
 	if err != nil {
 		serveAPIErr(w, err, http.StatusInternalServerError, "")
 		return
 	}
 
+	duplicates, err := s.findDuplicateImages(ctx, pendingknives, dbknives)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+
 	pendingApproval := make([]AdminCollectable, len(pendingknives))
 	for i, k := range pendingknives {
 		pendingApproval[i] = AdminCollectableFromDBCollectable(k)
+		pendingApproval[i].PossibleDuplicateOf = duplicates[k.ID]
 	}
 
 	serveAPIPayload(
@@ -749,6 +938,56 @@ func (s *Server) adminListCollectables(w http.ResponseWriter, r *http.Request) {
 	)
 }
 
+// findDuplicateImages matches each pending collectable to the approved one
+// with the closest image, if any is close enough to be the same art.
+func (s *Server) findDuplicateImages(ctx context.Context, pending []*db.Collectable, existing []*db.Collectable) (map[int64]*DuplicateMatch, error) {
+	var paths []string
+	for _, c := range pending {
+		paths = append(paths, c.Imagepath)
+	}
+	for _, c := range existing {
+		paths = append(paths, c.Imagepath)
+	}
+
+	hashes, err := s.db.GetImageHashes(ctx, paths)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := map[int64]*DuplicateMatch{}
+	for _, p := range pending {
+		hash, ok := hashes[p.Imagepath]
+		if !ok {
+			continue
+		}
+
+		for _, e := range existing {
+			if e.ApprovedAt == nil || e.DeletedAt != nil || e.ID == p.ID {
+				continue
+			}
+			existingHash, ok := hashes[e.Imagepath]
+			if !ok {
+				continue
+			}
+
+			d := images.Distance(uint64(hash), uint64(existingHash))
+			if d > images.NearDuplicate {
+				continue
+			}
+			if m, ok := matches[p.ID]; ok && m.Distance <= d {
+				continue
+			}
+			matches[p.ID] = &DuplicateMatch{
+				ID:       strconv.FormatInt(e.ID, 10),
+				Name:     e.Name,
+				ImageURL: imageBaseURL + e.Imagepath,
+				Distance: d,
+			}
+		}
+	}
+	return matches, nil
+}
+
 type CollectablePayload struct {
 	Collectable Collectable
 }
@@ -785,10 +1024,24 @@ func (s Server) createCollectable(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	collectionID := int64(1)
+	collection, err := s.collectionForPayload(ctx, payload.Collectable.CollectionID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			serveAPIErr(w, err, http.StatusBadRequest, "Unknown collection")
+			return
+		}
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+
+	if !collection.IsActive(time.Now()) {
+		serveAPIErr(w, fmt.Errorf("collection %d is not active", collection.ID), http.StatusBadRequest, "Collection is not accepting submissions")
+		return
+	}
+
 	created, err := s.db.CreateCollectable(ctx, model.Collectables{
 		ID:           s.idGenerator.Generate().Int64(),
-		CollectionID: &collectionID,
+		CollectionID: &collection.ID,
 		Name:         payload.Collectable.Name,
 		CreatorID:    u.ID,
 		Rarity:       payload.Collectable.Rarity,
@@ -799,6 +1052,8 @@ func (s Server) createCollectable(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	s.notifySubmission(created)
+
 	serveAPIPayload(w, struct {
 		Collectable AdminCollectable
 	}{
@@ -809,19 +1064,10 @@ func (s Server) createCollectable(w http.ResponseWriter, r *http.Request) {
 func (s *Server) adminCreateCollectable(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	u, err := s.getAuthUser(ctx, r)
-	if err != nil {
-		serveAPIErr(w, err, http.StatusForbidden, "could not identify user")
-		return
-	}
-
-	if u.Admin == nil || !*u.Admin {
-		serveAPIErr(w, errAdminOnly, http.StatusForbidden, "")
-		return
-	}
+	u := staffUser(ctx)
 
 	var payload CollectablePayload
-	err = json.NewDecoder(r.Body).Decode(&payload)
+	err := json.NewDecoder(r.Body).Decode(&payload)
 	if err != nil {
 		serveAPIErr(w, err, http.StatusBadRequest, "could not parse body")
 		return
@@ -854,11 +1100,20 @@ func (s *Server) adminCreateCollectable(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	collectionID := int64(1)
+	collection, err := s.collectionForPayload(ctx, payload.Collectable.CollectionID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			serveAPIErr(w, err, http.StatusBadRequest, "Unknown collection")
+			return
+		}
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+
 	now := time.Now()
 	created, err := s.db.CreateCollectable(ctx, model.Collectables{
 		ID:           s.idGenerator.Generate().Int64(),
-		CollectionID: &collectionID,
+		CollectionID: &collection.ID,
 		Name:         payload.Collectable.Name,
 		CreatorID:    authorID,
 		Rarity:       payload.Collectable.Rarity,
@@ -871,6 +1126,8 @@ func (s *Server) adminCreateCollectable(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	s.audit(ctx, u.ID, auditCreate, auditTargetCollectable, created.ID, nil, created.Collectables)
+
 	serveAPIPayload(w, struct {
 		Collectable AdminCollectable
 	}{
@@ -881,17 +1138,6 @@ func (s *Server) adminCreateCollectable(w http.ResponseWriter, r *http.Request)
 func (s *Server) adminDeleteCollectable(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	u, err := s.getAuthUser(ctx, r)
-	if err != nil {
-		serveAPIErr(w, err, http.StatusForbidden, "could not identify user")
-		return
-	}
-
-	if u.Admin == nil || !*u.Admin {
-		serveAPIErr(w, errAdminOnly, http.StatusForbidden, "")
-		return
-	}
-
 	vars := mux.Vars(r)
 
 	id, err := strconv.ParseInt(vars["id"], 10, 64)
@@ -900,29 +1146,30 @@ func (s *Server) adminDeleteCollectable(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	before, err := s.db.GetCollectable(ctx, id, db.GetCollectableOptions{GetUnapproved: true})
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			serveAPIErr(w, err, http.StatusNotFound, "Unknown collectable")
+			return
+		}
+		serveAPIErr(w, err, http.StatusInternalServerError, "could not delete knife")
+		return
+	}
+
 	err = s.db.DeleteCollectable(ctx, id)
 	if err != nil {
 		serveAPIErr(w, err, http.StatusInternalServerError, "could not delete knife")
 		return
 	}
 
+	s.audit(ctx, staffUser(ctx).ID, auditDelete, auditTargetCollectable, id, before.Collectables, nil)
+
 	serveAPIPayload(w, true)
 }
 
 func (s *Server) adminUpdateCollectable(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	u, err := s.getAuthUser(ctx, r)
-	if err != nil {
-		serveAPIErr(w, err, http.StatusForbidden, "could not identify user")
-		return
-	}
-
-	if u.Admin == nil || !*u.Admin {
-		serveAPIErr(w, errAdminOnly, http.StatusForbidden, "")
-		return
-	}
-
 	vars := mux.Vars(r)
 
 	id, err := strconv.ParseInt(vars["id"], 10, 64)
@@ -965,6 +1212,16 @@ func (s *Server) adminUpdateCollectable(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	before, err := s.db.GetCollectable(ctx, id, db.GetCollectableOptions{GetUnapproved: true})
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			serveAPIErr(w, err, http.StatusNotFound, "Unknown collectable")
+			return
+		}
+		serveAPIErr(w, err, http.StatusInternalServerError, "could not update collectable")
+		return
+	}
+
 	created, err := s.db.UpdateCollectable(ctx, model.Collectables{
 		ID:        id,
 		Name:      payload.Collectable.Name,
@@ -977,6 +1234,8 @@ func (s *Server) adminUpdateCollectable(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	s.audit(ctx, staffUser(ctx).ID, auditUpdate, auditTargetCollectable, id, before.Collectables, created.Collectables)
+
 	serveAPIPayload(w, struct {
 		Collectable AdminCollectable
 	}{
@@ -984,61 +1243,266 @@ func (s *Server) adminUpdateCollectable(w http.ResponseWriter, r *http.Request)
 	})
 }
 
+// IssuePayload is the request body for an admin granting a collectable
+// directly to a user. UserID accepts any form understood by ParseUserID.
+type IssuePayload struct {
+	CollectableID string
+	UserID        string
+	EditionID     string
+	Tags          Tags
+	Reason        string
+}
+
 func (s *Server) adminIssueCollectable(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	u, err := s.getAuthUser(ctx, r)
+	u := staffUser(ctx)
+
+	var payload IssuePayload
+	err := json.NewDecoder(r.Body).Decode(&payload)
 	if err != nil {
-		serveAPIErr(w, err, http.StatusForbidden, "could not identify user")
+		serveAPIErr(w, err, http.StatusBadRequest, "could not parse body")
+		return
+	}
+	r.Body.Close()
+
+	if payload.CollectableID == "" {
+		serveAPIErr(w, errMissingField, http.StatusBadRequest, "CollectableID cannot be empty")
+		return
+	}
+
+	collectableID, err := strconv.ParseInt(payload.CollectableID, 10, 64)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusBadRequest, "CollectableID is not numeric")
+		return
+	}
+
+	if payload.UserID == "" {
+		serveAPIErr(w, errMissingField, http.StatusBadRequest, "UserID cannot be empty")
+		return
+	}
+
+	if payload.Reason == "" {
+		serveAPIErr(w, errMissingField, http.StatusBadRequest, "Reason cannot be empty")
+		return
+	}
+
+	editionID := int64(db.DefaultEditionID)
+	if payload.EditionID != "" {
+		editionID, err = strconv.ParseInt(payload.EditionID, 10, 64)
+		if err != nil {
+			serveAPIErr(w, err, http.StatusBadRequest, "EditionID is not numeric")
+			return
+		}
+	}
+
+	edition, err := s.db.GetEdition(ctx, editionID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			serveAPIErr(w, err, http.StatusNotFound, "Unknown edition")
+			return
+		}
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+
+	// The default edition is never scheduled, it is always available
+	if edition.ID != db.DefaultEditionID && !edition.IsActive(time.Now()) {
+		serveAPIErr(w, fmt.Errorf("edition %d is not active", edition.ID), http.StatusBadRequest, "Edition is not active")
+		return
+	}
+
+	user, err := s.getUserByUserID(ctx, ParseUserID(payload.UserID))
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			serveAPIErr(w, err, http.StatusNotFound, "Unknown user")
+			return
+		}
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
 		return
 	}
 
-	if u.Admin == nil || !*u.Admin {
-		serveAPIErr(w, errAdminOnly, http.StatusForbidden, "")
+	collectable, err := s.db.GetCollectable(ctx, collectableID, db.GetCollectableOptions{})
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			serveAPIErr(w, err, http.StatusNotFound, "Unknown collectable")
+			return
+		}
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+
+	tags, err := json.Marshal(payload.Tags)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "unexpected error")
+		return
+	}
+	tagString := string(tags)
+
+	issued, err := s.db.CreateCollectableInstance(ctx, model.CollectableInstances{
+		ID:            s.idGenerator.Generate().Int64(),
+		CollectableID: collectable.ID,
+		OwnerID:       user.ID,
+		EditionID:     editionID,
+		CreatedAt:     time.Now().UTC(),
+		Tags:          &tagString,
+		IssuedBy:      &u.ID,
+		IssueReason:   &payload.Reason,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrEditionFull) {
+			serveAPIErr(w, err, http.StatusConflict, "Edition has no more of this collectable")
+			return
+		}
+		serveAPIErr(w, err, http.StatusInternalServerError, "could not issue collectable")
 		return
 	}
 
-	serveAPIErr(w, fmt.Errorf("not implemented"), http.StatusNotImplemented, "Not Implemented")
+	slog.Info("admin issued collectable", "admin", u.ID, "user", user.ID, "collectable", collectable.ID, "reason", payload.Reason)
+	s.audit(ctx, u.ID, auditIssue, auditTargetInstance, issued.ID, nil, issued.CollectableInstances)
+
+	c := IssuedCollectableFromCollectableInstance(issued)
+
+	serveAPIPayload(
+		w,
+		&c,
+	)
+}
+
+type RevokePayload struct {
+	Reason string
 }
 
 func (s *Server) adminRevokeIssuedCollectable(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	u, err := s.getAuthUser(ctx, r)
+	u := staffUser(ctx)
+
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
 	if err != nil {
-		serveAPIErr(w, err, http.StatusForbidden, "could not identify user")
+		serveAPIErr(w, err, http.StatusBadRequest, "id is not numeric")
+		return
+	}
+
+	var payload RevokePayload
+	err = json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusBadRequest, "could not parse body")
+		return
+	}
+	r.Body.Close()
+
+	if payload.Reason == "" {
+		serveAPIErr(w, errMissingField, http.StatusBadRequest, "Reason cannot be empty")
+		return
+	}
+
+	before := s.auditInstance(ctx, id)
+
+	err = s.db.RevokeCollectableInstance(ctx, id, u.ID, payload.Reason)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			serveAPIErr(w, err, http.StatusNotFound, "Unknown or already revoked issued collectable")
+			return
+		}
+		serveAPIErr(w, err, http.StatusInternalServerError, "could not revoke issued collectable")
+		return
+	}
+
+	slog.Info("admin revoked issued collectable", "admin", u.ID, "instance", id, "reason", payload.Reason)
+	s.audit(ctx, u.ID, auditRevoke, auditTargetInstance, id, before, s.auditInstance(ctx, id))
+
+	serveAPIPayload(w, true)
+}
+
+func (s *Server) adminRestoreIssuedCollectable(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	u := staffUser(ctx)
+
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusBadRequest, "id is not numeric")
 		return
 	}
 
-	if u.Admin == nil || !*u.Admin {
-		serveAPIErr(w, errAdminOnly, http.StatusForbidden, "")
+	before := s.auditInstance(ctx, id)
+
+	restored, err := s.db.RestoreCollectableInstance(ctx, id)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			serveAPIErr(w, err, http.StatusNotFound, "Unknown or not revoked issued collectable")
+			return
+		}
+		serveAPIErr(w, err, http.StatusInternalServerError, "could not restore issued collectable")
 		return
 	}
 
-	serveAPIErr(w, fmt.Errorf("not implemented"), http.StatusNotImplemented, "Not Implemented")
+	slog.Info("admin restored issued collectable", "admin", u.ID, "instance", id)
+	s.audit(ctx, u.ID, auditRestore, auditTargetInstance, id, before, restored.CollectableInstances)
+
+	c := IssuedCollectableFromCollectableInstance(restored)
+
+	serveAPIPayload(
+		w,
+		&c,
+	)
 }
 
 type IssuedConfig struct {
 	Weights map[string]int
+	History []IssuedConfigVersion `json:",omitempty"`
 }
 
-func (s *Server) adminGetIssueConfig(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+type IssuedConfigVersion struct {
+	Version   int64
+	Weights   map[string]int
+	ChangedBy *User
+	ChangedAt time.Time
+}
 
-	u, err := s.getAuthUser(ctx, r)
+func IssuedConfigVersionFromDBWeightHistory(h *db.WeightHistory) IssuedConfigVersion {
+	weights := make(map[string]int)
+	err := json.Unmarshal([]byte(h.Weights), &weights)
 	if err != nil {
-		serveAPIErr(w, err, http.StatusForbidden, "could not identify user")
-		return
+		slog.Error("unable to unmarshal weight history", "err", err, "id", h.ID)
+	}
+
+	var changedBy *User
+	if h.ChangedBy != nil {
+		changedBy = &User{
+			ID:   strconv.FormatInt(h.ChangedBy.ID, 10),
+			Name: h.ChangedBy.Name,
+		}
+	}
+
+	return IssuedConfigVersion{
+		Version:   h.Version,
+		Weights:   weights,
+		ChangedBy: changedBy,
+		ChangedAt: h.CreatedAt,
 	}
+}
+
+func (s *Server) adminGetIssueConfig(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 
-	if u.Admin == nil || !*u.Admin {
-		serveAPIErr(w, errAdminOnly, http.StatusForbidden, "")
+	collectionID, err := s.collectionFromRequest(ctx, r)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			serveAPIErr(w, err, http.StatusNotFound, "Unknown collection")
+			return
+		}
+		serveAPIErr(w, err, http.StatusBadRequest, "could not determine collection")
 		return
 	}
 
-	pullWeight, err := s.db.GetWeights(ctx, 1)
+	pullWeight, err := s.db.GetWeights(ctx, collectionID)
 	if err != nil {
 		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
 	}
 
 	res := make(map[string]int)
@@ -1046,30 +1510,97 @@ func (s *Server) adminGetIssueConfig(w http.ResponseWriter, r *http.Request) {
 		res[w.Rarity] = w.Weight
 	}
 
-	serveAPIPayload(w, IssuedConfig{Weights: res})
+	rawHistory, err := s.db.GetWeightHistory(ctx, collectionID)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+
+	history := make([]IssuedConfigVersion, len(rawHistory))
+	for i, h := range rawHistory {
+		history[i] = IssuedConfigVersionFromDBWeightHistory(&h)
+	}
+
+	serveAPIPayload(w, IssuedConfig{Weights: res, History: history})
+}
+
+func validateWeights(weights map[string]int) error {
+	for rarity := range weights {
+		if !slices.Contains(rarities, rarity) {
+			return fmt.Errorf("unknown rarity %q", rarity)
+		}
+	}
+
+	sum := 0
+	for _, rarity := range rarities {
+		weight, ok := weights[rarity]
+		if !ok {
+			return fmt.Errorf("missing weight for %q", rarity)
+		}
+		if weight < 0 {
+			return fmt.Errorf("weight for %q cannot be negative", rarity)
+		}
+		sum += weight
+	}
+
+	if sum <= 0 {
+		return fmt.Errorf("weights must sum to more than zero")
+	}
+
+	return nil
 }
 
 func (s *Server) adminUpdateIssueConfig(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	u, err := s.getAuthUser(ctx, r)
+	u := staffUser(ctx)
+
+	var payload IssuedConfig
+	err := json.NewDecoder(r.Body).Decode(&payload)
 	if err != nil {
-		serveAPIErr(w, err, http.StatusForbidden, "could not identify user")
+		serveAPIErr(w, err, http.StatusBadRequest, "could not parse body")
 		return
 	}
+	r.Body.Close()
 
-	if u.Admin == nil || !*u.Admin {
-		serveAPIErr(w, errAdminOnly, http.StatusForbidden, "")
+	err = validateWeights(payload.Weights)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	serveAPIErr(w, fmt.Errorf("not implemented"), http.StatusNotImplemented, "Not Implemented")
+	collectionID, err := s.collectionFromRequest(ctx, r)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			serveAPIErr(w, err, http.StatusNotFound, "Unknown collection")
+			return
+		}
+		serveAPIErr(w, err, http.StatusBadRequest, "could not determine collection")
+		return
+	}
+
+	before := s.auditCollection(ctx, collectionID)
+
+	err = s.db.UpdateWeights(ctx, collectionID, payload.Weights, u.ID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			serveAPIErr(w, err, http.StatusNotFound, "Unknown collection")
+			return
+		}
+		serveAPIErr(w, err, http.StatusInternalServerError, "could not update weights")
+		return
+	}
+
+	s.audit(ctx, u.ID, auditUpdate, auditTargetCollection, collectionID, before, s.auditCollection(ctx, collectionID))
+
+	serveAPIPayload(w, IssuedConfig{Weights: payload.Weights})
 }
 
+// RandomPullRequest is sent by the bot. Older bots also send a subscriber
+// flag, it is ignored in favour of asking twitch.
 type RandomPullRequest struct {
-	TwitchID   string `json:"twitch_id"`
-	Subscriber bool   `json:"subscriber"`
-	DryRun     bool   `json:"dry_run"`
+	TwitchID string `json:"twitch_id"`
+	DryRun   bool   `json:"dry_run"`
 }
 
 func (s *Server) RandomPullHandler(w http.ResponseWriter, r *http.Request) {
@@ -1100,106 +1631,195 @@ func (s *Server) RandomPullHandler(w http.ResponseWriter, r *http.Request) {
 
 	slog.Info("RandomPull", "payload", reqBody)
 
-	user, err := s.db.GetUser(ctx, db.GetUserOptions{
-		TwitchID: reqBody.TwitchID,
-	})
+	c, err := s.randomPull(ctx, reqBody)
 	if err != nil {
-		if errors.Is(err, db.ErrNotFound) {
-
-			twusers, nerr := s.twitchClient.GetUsersByID(ctx, reqBody.TwitchID)
-			if nerr != nil {
-				serveAPIErr(w, nerr, http.StatusInternalServerError, "unable to get user from twitch")
-				return
-			}
-
-			if len(twusers) < 1 {
-				serveAPIErr(w, fmt.Errorf("wtf"), http.StatusInternalServerError, "unable to get user from twitch")
-				return
-			}
-			twuser := twusers[0]
-
-			user, nerr = s.db.CreateUser(ctx, db.User{
-				Users: model.Users{
-					ID:       s.idGenerator.Generate().Int64(),
-					TwitchID: &twuser.ID,
-					Name:     twuser.DisplayName,
-				},
-			})
-			err = nerr
-		}
-		if err != nil {
-			serveAPIErr(w, err, http.StatusInternalServerError, "unexpected error")
+		if errors.Is(err, errTwitchLookup) {
+			serveAPIErr(w, err, http.StatusInternalServerError, "unable to get user from twitch")
 			return
 		}
+		serveAPIErr(w, err, http.StatusInternalServerError, "unexpected error")
+		return
+	}
+
+	serveAPIPayload(
+		w,
+		c,
+	)
+}
+
+// randomPull rolls a collectable for the requesting twitch user and issues it
+// to them, unless the request is a dry run.
+func (s *Server) randomPull(ctx context.Context, req RandomPullRequest) (*IssuedCollectable, error) {
+	user, err := s.getOrCreateTwitchUser(ctx, req.TwitchID)
+	if err != nil {
+		return nil, err
 	}
 
 	collectable, err := s.getRandomCollectable(ctx)
 	if err != nil {
-		serveAPIErr(w, err, http.StatusInternalServerError, "unexpected error")
-		return
+		return nil, err
 	}
 
 	verified := (rand.Intn(100) == 0)
 
+	// Without a broadcaster to ask nobody is tagged as a subscriber, the
+	// caller is never trusted to say so
+	subscriber := false
+	if s.broadcasterID != "" {
+		subscriber, err = s.isSubscriber(ctx, req.TwitchID)
+		if err != nil {
+			// The pull still happens, it just isn't tagged as a subscriber pull
+			slog.Error("checking subscriber status", "twitch_id", req.TwitchID, "err", err)
+			subscriber = false
+		}
+	}
+
 	tags, err := json.Marshal(map[string]bool{
-		"subscriber": reqBody.Subscriber,
+		"subscriber": subscriber,
 		"verified":   verified,
 	})
 	if err != nil {
-		serveAPIErr(w, err, http.StatusInternalServerError, "unexpected error")
-		return
+		return nil, err
 	}
 	tagString := string(tags)
 
 	var issued *db.CollectableInstance
-	if !reqBody.DryRun {
-		issued, err = s.db.CreateCollectableInstance(ctx, model.CollectableInstances{
-			ID:            s.idGenerator.Generate().Int64(),
-			CollectableID: collectable.ID,
-			OwnerID:       user.ID,
-			EditionID:     1,
-			CreatedAt:     time.Now().UTC(),
-			Tags:          &tagString,
+	if !req.DryRun {
+		issued, err = s.createWithEdition(ctx, collectable, func(edition *db.Edition) (*db.CollectableInstance, error) {
+			return s.db.CreateCollectableInstance(ctx, model.CollectableInstances{
+				ID:            s.idGenerator.Generate().Int64(),
+				CollectableID: collectable.ID,
+				OwnerID:       user.ID,
+				EditionID:     edition.ID,
+				CreatedAt:     time.Now().UTC(),
+				Tags:          &tagString,
+			})
 		})
 		if err != nil {
-			serveAPIErr(w, err, http.StatusInternalServerError, "unexpected error")
-			return
+			return nil, err
 		}
 	} else {
+		edition, err := s.resolveEdition(ctx, collectable)
+		if err != nil {
+			return nil, err
+		}
 		issued = &db.CollectableInstance{
 			CollectableInstances: model.CollectableInstances{
 				ID:            s.idGenerator.Generate().Int64(),
 				CollectableID: collectable.ID,
 				OwnerID:       user.ID,
-				EditionID:     1,
+				EditionID:     edition.ID,
 				CreatedAt:     time.Now().UTC(),
 				Tags:          &tagString,
 			},
 			Collectable: collectable,
 			Owner:       &user.Users,
+			Edition:     &edition.Editions,
 		}
 	}
 
 	c := IssuedCollectableFromCollectableInstance(issued)
 
-	serveAPIPayload(
-		w,
-		&c,
-	)
+	if !req.DryRun {
+		// Pulls come from bots and redemptions rather than staff, so the
+		// puller is the actor
+		s.audit(ctx, user.ID, auditPull, auditTargetInstance, issued.ID, nil, issued.CollectableInstances)
+		s.latest.Publish(c)
+		s.notifyPull(c)
+	}
+
+	return &c, nil
+}
+
+// getOrCreateTwitchUser finds the user for a twitch id, creating them from
+// their twitch profile the first time they are seen.
+func (s *Server) getOrCreateTwitchUser(ctx context.Context, twitchID string) (*db.User, error) {
+	user, err := s.db.GetUser(ctx, db.GetUserOptions{
+		TwitchID: twitchID,
+	})
+	if err == nil {
+		return user, nil
+	}
+	if !errors.Is(err, db.ErrNotFound) {
+		return nil, err
+	}
+
+	twusers, err := s.twitchClient.GetUsersByID(ctx, twitchID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errTwitchLookup, err)
+	}
+
+	if len(twusers) < 1 {
+		return nil, fmt.Errorf("%w: no user with id %s", errTwitchLookup, twitchID)
+	}
+	twuser := twusers[0]
+
+	return s.db.CreateUser(ctx, db.User{
+		Users: model.Users{
+			ID:       s.idGenerator.Generate().Int64(),
+			TwitchID: &twuser.ID,
+			Name:     twuser.DisplayName,
+		},
+	})
 }
 
 func (s *Server) getRandomCollectable(ctx context.Context) (*db.Collectable, error) {
-	weights, err := s.db.GetWeights(ctx, 1)
+	collections, err := s.db.GetCollections(ctx, db.GetCollectionsOptions{
+		OnlyActive: true,
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	if len(collections) == 0 {
+		return nil, fmt.Errorf("no active collections to pull from")
+	}
+
+	// Every active collection is equally likely, then its own weights pick the
+	// rarity. Collections that can't produce a collectable are skipped.
+	var lastErr error
+	for _, i := range rand.Perm(len(collections)) {
+		c, err := s.getRandomCollectableFromCollection(ctx, collections[i].ID, "")
+		if err != nil {
+			slog.Warn("unable to pull from collection", "collection", collections[i].ID, "err", err)
+			lastErr = err
+			continue
+		}
+		return c, nil
+	}
+
+	return nil, lastErr
+}
+
+// getRandomCollectableFromCollection rolls a collectable using the collection's
+// weights. When minRarity is set, rarities below it are left out of the roll.
+func (s *Server) getRandomCollectableFromCollection(ctx context.Context, collectionID int64, minRarity string) (*db.Collectable, error) {
+	allWeights, err := s.db.GetWeights(ctx, collectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	weights := allWeights
+	if minRarity != "" {
+		min := slices.Index(rarities, minRarity)
+		weights = []*db.PullWeight{}
+		for _, w := range allWeights {
+			if slices.Index(rarities, w.Rarity) >= min {
+				weights = append(weights, w)
+			}
+		}
+	}
+
 	// Roll to Pick Rarity
 	var sum int64 = 0
 	for _, w := range weights {
 		sum += int64(w.Weight)
 	}
 
+	if sum <= 0 {
+		return nil, fmt.Errorf("collection %d has no pull weights", collectionID)
+	}
+
 	// Roll to Pick Knife
 	rarityRoll := rand.Int63n(sum)
 	rarity := ""
@@ -1217,13 +1837,17 @@ func (s *Server) getRandomCollectable(ctx context.Context) (*db.Collectable, err
 	}
 
 	c, err := s.db.GetCollectables(ctx, db.GetCollectablesOptions{
-		Collection: 1,
+		Collection: collectionID,
 		Rarity:     rarity,
 	})
 	if err != nil {
 		return nil, err
 	}
 
+	if len(c) == 0 {
+		return nil, fmt.Errorf("collection %d has no %s collectables", collectionID, rarity)
+	}
+
 	// Give me a random knifetype
 	hit := c[rand.Intn(len(c))]
 
@@ -1233,17 +1857,6 @@ func (s *Server) getRandomCollectable(ctx context.Context) (*db.Collectable, err
 func (s *Server) adminGetCollectable(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	u, err := s.getAuthUser(ctx, r)
-	if err != nil {
-		serveAPIErr(w, err, http.StatusForbidden, "could not identify user")
-		return
-	}
-
-	if u.Admin == nil || !*u.Admin {
-		serveAPIErr(w, errAdminOnly, http.StatusForbidden, "")
-		return
-	}
-
 	vars := mux.Vars(r)
 	id, err := strconv.ParseInt(vars["id"], 10, 64)
 	if err != nil {
@@ -1273,16 +1886,7 @@ func (s *Server) adminGetCollectable(w http.ResponseWriter, r *http.Request) {
 func (s *Server) adminApproveCollectable(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	u, err := s.getAuthUser(ctx, r)
-	if err != nil {
-		serveAPIErr(w, err, http.StatusForbidden, "could not identify user")
-		return
-	}
-
-	if u.Admin == nil || !*u.Admin {
-		serveAPIErr(w, errAdminOnly, http.StatusForbidden, "")
-		return
-	}
+	u := staffUser(ctx)
 
 	vars := mux.Vars(r)
 	id, err := strconv.ParseInt(vars["id"], 10, 64)
@@ -1291,12 +1895,25 @@ func (s *Server) adminApproveCollectable(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	before, err := s.db.GetCollectable(ctx, id, db.GetCollectableOptions{GetUnapproved: true})
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			serveAPIErr(w, err, http.StatusNotFound, "Unknown collectable")
+			return
+		}
+		serveAPIErr(w, err, http.StatusInternalServerError, "unable to get collectable")
+		return
+	}
+
 	c, err := s.db.ApproveCollectable(ctx, id, u.ID)
 	if err != nil {
 		serveAPIErr(w, err, http.StatusInternalServerError, "unable to get collectable")
 		return
 	}
 
+	s.audit(ctx, u.ID, auditApprove, auditTargetCollectable, id, before.Collectables, c.Collectables)
+	s.notifyApproval(c)
+
 	serveAPIPayload(
 		w,
 		&struct {
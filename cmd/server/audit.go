@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cconger/shindaggers/pkg/db"
+	model "github.com/cconger/shindaggers/pkg/db/.gen/postgres/public/model"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	auditApprove  = "approve"
+	auditReject   = "reject"
+	auditRequest  = "request_changes"
+	auditCreate   = "create"
+	auditUpdate   = "update"
+	auditDelete   = "delete"
+	auditIssue    = "issue"
+	auditPull     = "pull"
+	auditRevoke   = "revoke"
+	auditRestore  = "restore"
+	auditActivate = "activate"
+	auditRetire   = "retire"
+	auditClose    = "close"
+	auditEquip    = "equip"
+	auditGrant    = "grant"
+	auditSalvage  = "salvage"
+	auditForge    = "forge"
+	auditTrade    = "trade"
+
+	auditTargetCollectable = "collectable"
+	auditTargetInstance    = "collectable_instance"
+	auditTargetCollection  = "collection"
+	auditTargetEdition     = "edition"
+	auditTargetEvent       = "event"
+	auditTargetFight       = "fight"
+	auditTargetUser        = "user"
+	auditTargetTrade       = "trade_offer"
+)
+
+type requestIDContextKey struct{}
+
+// requestIDMiddleware tags every request with an id that ties audit entries
+// back to traces. The trace id is used when tracing is running.
+func requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		var id string
+		if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+			id = sc.TraceID().String()
+		} else {
+			b := make([]byte, 16)
+			rand.Read(b)
+			id = hex.EncodeToString(b)
+		}
+
+		w.Header().Set("X-Request-Id", id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, requestIDContextKey{}, id)))
+	})
+}
+
+func requestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey{}).(string)
+	return id
+}
+
+// audit records that actorID did action to the target. before and after are
+// stored as json, nil for creations and deletions. The action has already
+// happened so failures are logged rather than failing the request.
+func (s *Server) audit(ctx context.Context, actorID int64, action, targetType string, targetID int64, before, after any) {
+	entry := model.AuditLog{
+		ActorID:    actorID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		RequestID:  requestID(ctx),
+	}
+
+	var err error
+	entry.Before, err = auditJSON(before)
+	if err != nil {
+		slog.Error("encoding audit entry", "action", action, "target", targetID, "err", err)
+	}
+	entry.After, err = auditJSON(after)
+	if err != nil {
+		slog.Error("encoding audit entry", "action", action, "target", targetID, "err", err)
+	}
+
+	err = s.db.AddAuditEntry(ctx, entry)
+	if err != nil {
+		slog.Error("writing audit entry", "actor", actorID, "action", action, "target_type", targetType, "target", targetID, "err", err)
+	}
+}
+
+// auditInstance loads an instance, revoked or not, to record in the audit
+// log. A failed lookup is recorded as null rather than failing the request.
+func (s *Server) auditInstance(ctx context.Context, id int64) any {
+	instances, err := s.db.GetCollectableInstances(ctx, db.GetCollectableInstancesOptions{
+		ByID:       id,
+		GetDeleted: true,
+	})
+	if err != nil || len(instances) == 0 {
+		slog.Error("loading instance for audit", "instance", id, "err", err)
+		return nil
+	}
+	return instances[0].CollectableInstances
+}
+
+func (s *Server) auditCollection(ctx context.Context, id int64) any {
+	collection, err := s.db.GetCollection(ctx, id)
+	if err != nil {
+		slog.Error("loading collection for audit", "collection", id, "err", err)
+		return nil
+	}
+	return collection.Collections
+}
+
+func auditJSON(v any) (*string, error) {
+	if v == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	encoded := string(b)
+	return &encoded, nil
+}
+
+type AuditEntry struct {
+	ID         string          `json:"id"`
+	ActorID    string          `json:"actor_id"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"target_type"`
+	TargetID   string          `json:"target_id"`
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+	RequestID  string          `json:"request_id"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+func AuditEntryFromDBAuditLog(e model.AuditLog) AuditEntry {
+	entry := AuditEntry{
+		ID:         strconv.FormatInt(e.ID, 10),
+		ActorID:    strconv.FormatInt(e.ActorID, 10),
+		Action:     e.Action,
+		TargetType: e.TargetType,
+		TargetID:   strconv.FormatInt(e.TargetID, 10),
+		RequestID:  e.RequestID,
+		CreatedAt:  e.CreatedAt,
+	}
+	if e.Before != nil {
+		entry.Before = json.RawMessage(*e.Before)
+	}
+	if e.After != nil {
+		entry.After = json.RawMessage(*e.After)
+	}
+	return entry
+}
+
+// adminGetAuditLog lists audit entries, newest first. Filters are actor,
+// target_type, target_id, since and until (RFC3339) and limit.
+func (s *Server) adminGetAuditLog(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+
+	options := db.GetAuditLogOptions{
+		TargetType: query.Get("target_type"),
+	}
+
+	var err error
+	if actor := query.Get("actor"); actor != "" {
+		options.ActorID, err = strconv.ParseInt(actor, 10, 64)
+		if err != nil {
+			serveAPIErr(w, err, http.StatusBadRequest, "actor is not numeric")
+			return
+		}
+	}
+	if target := query.Get("target_id"); target != "" {
+		options.TargetID, err = strconv.ParseInt(target, 10, 64)
+		if err != nil {
+			serveAPIErr(w, err, http.StatusBadRequest, "target_id is not numeric")
+			return
+		}
+	}
+	if since := query.Get("since"); since != "" {
+		options.Since, err = time.Parse(time.RFC3339, since)
+		if err != nil {
+			serveAPIErr(w, err, http.StatusBadRequest, "since must be an RFC3339 time")
+			return
+		}
+	}
+	if until := query.Get("until"); until != "" {
+		options.Until, err = time.Parse(time.RFC3339, until)
+		if err != nil {
+			serveAPIErr(w, err, http.StatusBadRequest, "until must be an RFC3339 time")
+			return
+		}
+	}
+	if limit := query.Get("limit"); limit != "" {
+		options.Limit, err = strconv.ParseInt(limit, 10, 64)
+		if err != nil || options.Limit > 500 {
+			serveAPIErr(w, err, http.StatusBadRequest, "limit must be a number up to 500")
+			return
+		}
+	}
+
+	entries, err := s.db.GetAuditLog(ctx, options)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+
+	payload := make([]AuditEntry, len(entries))
+	for i, e := range entries {
+		payload[i] = AuditEntryFromDBAuditLog(e)
+	}
+
+	serveAPIPayload(w, struct {
+		Entries []AuditEntry
+	}{
+		Entries: payload,
+	})
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// fsBlobClient stores objects as files under Root/bucket/key so uploads work
+// without object storage. The server serves them itself, see imagesHandler.
+type fsBlobClient struct {
+	Root string
+}
+
+func (f *fsBlobClient) path(bucket, key string) (string, error) {
+	p := filepath.Join(bucket, filepath.FromSlash(key))
+	if !filepath.IsLocal(p) {
+		return "", fmt.Errorf("invalid object key %q", key)
+	}
+	return filepath.Join(f.Root, p), nil
+}
+
+func (f *fsBlobClient) PutObject(ctx context.Context, bucket string, key string, contents io.Reader, size int64, options minio.PutObjectOptions) (minio.UploadInfo, error) {
+	p, err := f.path(bucket, key)
+	if err != nil {
+		return minio.UploadInfo{}, err
+	}
+
+	err = os.MkdirAll(filepath.Dir(p), 0o755)
+	if err != nil {
+		return minio.UploadInfo{}, err
+	}
+
+	// Write to a temp file first so a failed upload never leaves a partial
+	// image where it can be served
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".upload-*")
+	if err != nil {
+		return minio.UploadInfo{}, err
+	}
+	defer os.Remove(tmp.Name())
+
+	n, err := io.Copy(tmp, contents)
+	if err != nil {
+		tmp.Close()
+		return minio.UploadInfo{}, err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return minio.UploadInfo{}, err
+	}
+
+	err = os.Rename(tmp.Name(), p)
+	if err != nil {
+		return minio.UploadInfo{}, err
+	}
+
+	return minio.UploadInfo{Bucket: bucket, Key: key, Size: n}, nil
+}
+
+func (f *fsBlobClient) RemoveObject(ctx context.Context, bucket string, key string, options minio.RemoveObjectOptions) error {
+	p, err := f.path(bucket, key)
+	if err != nil {
+		return err
+	}
+
+	// Like object storage, removing something that isn't there is fine
+	err = os.Remove(p)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (f *fsBlobClient) readObject(bucket string, key string) ([]byte, error) {
+	p, err := f.path(bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(p)
+}
+
+// imagesHandler serves images stored by fsBlobClient at /images/.
+func (f *fsBlobClient) imagesHandler(bucket string) http.Handler {
+	return http.StripPrefix("/images/", http.FileServer(noListing{http.Dir(filepath.Join(f.Root, bucket, "images"))}))
+}
+
+// noListing hides directory indexes from http.FileServer.
+type noListing struct {
+	fs http.FileSystem
+}
+
+func (n noListing) Open(name string) (http.File, error) {
+	f, err := n.fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if stat.IsDir() {
+		f.Close()
+		return nil, os.ErrNotExist
+	}
+	return f, nil
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/cconger/shindaggers/pkg/db"
+	model "github.com/cconger/shindaggers/pkg/db/.gen/postgres/public/model"
+
+	"github.com/gorilla/mux"
+)
+
+// maxBracketEntrants keeps brackets to a size that can be fought in a stream.
+const maxBracketEntrants = 64
+
+type BracketRound struct {
+	Round   int            `json:"round"`
+	Matches []BracketMatch `json:"matches"`
+}
+
+// BracketMatch is a pairing within a round, a missing user is a bye.
+type BracketMatch struct {
+	Slot    int    `json:"slot"`
+	First   *User  `json:"first"`
+	Second  *User  `json:"second"`
+	Winner  *User  `json:"winner"`
+	FightID string `json:"fight_id,omitempty"`
+}
+
+func BracketFromDBMatches(matches []db.EventMatch) []BracketRound {
+	rounds := []BracketRound{}
+	for _, m := range matches {
+		if len(rounds) == 0 || rounds[len(rounds)-1].Round != int(m.Round) {
+			rounds = append(rounds, BracketRound{Round: int(m.Round)})
+		}
+
+		match := BracketMatch{
+			Slot:   int(m.Slot),
+			First:  bracketUser(m.FirstUser),
+			Second: bracketUser(m.SecondUser),
+		}
+		if m.WinnerID != nil {
+			if m.FirstUserID != nil && *m.FirstUserID == *m.WinnerID {
+				match.Winner = match.First
+			} else {
+				match.Winner = match.Second
+			}
+		}
+		if m.FightID != nil {
+			match.FightID = strconv.FormatInt(*m.FightID, 10)
+		}
+
+		r := &rounds[len(rounds)-1]
+		r.Matches = append(r.Matches, match)
+	}
+	return rounds
+}
+
+func bracketUser(u *model.Users) *User {
+	if u == nil {
+		return nil
+	}
+	return &User{
+		ID:   strconv.FormatInt(u.ID, 10),
+		Name: u.Name,
+	}
+}
+
+// seedBracket builds the first round of a single elimination bracket for
+// entrants in seed order. The field is padded to a power of two with byes,
+// which go to the top seeds and are decided straight away, and seeds are
+// placed so the top two can only meet in the final.
+func seedBracket(eventID int64, entrants []int64) []model.EventMatches {
+	order := []int{0}
+	for len(order) < len(entrants) {
+		size := len(order) * 2
+		next := make([]int, 0, size)
+		for _, seed := range order {
+			next = append(next, seed, size-1-seed)
+		}
+		order = next
+	}
+
+	matches := make([]model.EventMatches, 0, len(order)/2)
+	for i := 0; i < len(order); i += 2 {
+		match := model.EventMatches{
+			EventID: eventID,
+			Round:   1,
+			Slot:    int32(i / 2),
+		}
+		first, second := order[i], order[i+1]
+		if first < len(entrants) {
+			match.FirstUserID = &entrants[first]
+		}
+		if second < len(entrants) {
+			match.SecondUserID = &entrants[second]
+		}
+		if match.FirstUserID == nil {
+			match.WinnerID = match.SecondUserID
+		} else if match.SecondUserID == nil {
+			match.WinnerID = match.FirstUserID
+		}
+		matches = append(matches, match)
+	}
+	return matches
+}
+
+// nextRound pairs up the winners of round once every match in it is decided.
+// It returns nothing while the round is still being fought or after the final.
+func nextRound(matches []db.EventMatch, round int32) []model.EventMatches {
+	var winners []*int64
+	var eventID int64
+	for _, m := range matches {
+		if m.Round != round {
+			continue
+		}
+		if m.WinnerID == nil {
+			return nil
+		}
+		eventID = m.EventID
+		winners = append(winners, m.WinnerID)
+	}
+	if len(winners) < 2 {
+		return nil
+	}
+
+	next := make([]model.EventMatches, 0, len(winners)/2)
+	for i := 0; i+1 < len(winners); i += 2 {
+		next = append(next, model.EventMatches{
+			EventID:      eventID,
+			Round:        round + 1,
+			Slot:         int32(i / 2),
+			FirstUserID:  winners[i],
+			SecondUserID: winners[i+1],
+		})
+	}
+	return next
+}
+
+// advanceBracket decides the pending bracket match between the two fighters
+// of a fight, if there is one, and starts the next round when it completes.
+// Draws and fights between users who aren't paired leave the bracket alone.
+func (s *Server) advanceBracket(ctx context.Context, fight *db.Fight) error {
+	if fight.EventID == nil || len(fight.Outcomes) != 2 {
+		return nil
+	}
+
+	var winner, loser int64
+	for _, o := range fight.Outcomes {
+		switch o.Outcome {
+		case db.FightOutcomeWin:
+			winner = o.UserID
+		case db.FightOutcomeLoss:
+			loser = o.UserID
+		}
+	}
+	if winner == 0 || loser == 0 {
+		return nil
+	}
+
+	matches, err := s.db.GetEventMatches(ctx, *fight.EventID)
+	if err != nil {
+		return err
+	}
+
+	for i, m := range matches {
+		if m.WinnerID != nil || m.FirstUserID == nil || m.SecondUserID == nil {
+			continue
+		}
+		first, second := *m.FirstUserID, *m.SecondUserID
+		if !(first == winner && second == loser) && !(first == loser && second == winner) {
+			continue
+		}
+
+		m.WinnerID = &winner
+		m.FightID = &fight.ID
+		err = s.db.DecideEventMatch(ctx, m.EventMatches)
+		if err != nil {
+			if errors.Is(err, db.ErrNotFound) {
+				// Another fight decided it first
+				return nil
+			}
+			return err
+		}
+		matches[i] = m
+
+		next := nextRound(matches, m.Round)
+		if len(next) == 0 {
+			return nil
+		}
+		return s.db.CreateEventMatches(ctx, next)
+	}
+
+	return nil
+}
+
+type BracketPayload struct {
+	Entrants []string
+}
+
+// adminCreateBracket seeds a bracket for an open event from entrants listed
+// best seed first. Fights between paired entrants decide their match.
+func (s *Server) adminCreateBracket(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusBadRequest, "id is not numeric")
+		return
+	}
+
+	var payload BracketPayload
+	err = json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusBadRequest, "could not parse body")
+		return
+	}
+	r.Body.Close()
+
+	if len(payload.Entrants) < 2 || len(payload.Entrants) > maxBracketEntrants {
+		serveAPIErr(w, fmt.Errorf("%d entrants", len(payload.Entrants)), http.StatusBadRequest, fmt.Sprintf("A bracket needs between 2 and %d entrants", maxBracketEntrants))
+		return
+	}
+
+	event, err := s.db.GetEvent(ctx, db.GetEventOptions{
+		ID: id,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			serveAPIErr(w, err, http.StatusNotFound, "Unknown event")
+			return
+		}
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+
+	if event.ClosedAt != nil {
+		serveAPIErr(w, fmt.Errorf("event %d is closed", id), http.StatusConflict, "Event is closed")
+		return
+	}
+
+	existing, err := s.db.GetEventMatches(ctx, id)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+	if len(existing) > 0 {
+		serveAPIErr(w, fmt.Errorf("event %d already has a bracket", id), http.StatusConflict, "Event already has a bracket")
+		return
+	}
+
+	entrants := make([]int64, len(payload.Entrants))
+	seen := map[int64]bool{}
+	for i, e := range payload.Entrants {
+		user, err := s.getUserByUserID(ctx, ParseUserID(e))
+		if err != nil {
+			if errors.Is(err, db.ErrNotFound) {
+				serveAPIErr(w, err, http.StatusNotFound, fmt.Sprintf("Unknown user %s", e))
+				return
+			}
+			serveAPIErr(w, err, http.StatusInternalServerError, "")
+			return
+		}
+		if seen[user.ID] {
+			serveAPIErr(w, fmt.Errorf("user %d entered twice", user.ID), http.StatusBadRequest, fmt.Sprintf("%s is entered more than once", user.Name))
+			return
+		}
+		seen[user.ID] = true
+		entrants[i] = user.ID
+	}
+
+	matches := seedBracket(id, entrants)
+	err = s.db.CreateEventMatches(ctx, matches)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "could not create bracket")
+		return
+	}
+
+	s.audit(ctx, staffUser(ctx).ID, auditCreate, auditTargetEvent, id, nil, matches)
+
+	created, err := s.db.GetEventMatches(ctx, id)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+
+	serveAPIPayload(w, struct {
+		Bracket []BracketRound
+	}{
+		Bracket: BracketFromDBMatches(created),
+	})
+}
@@ -0,0 +1,346 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cconger/shindaggers/pkg/db"
+	model "github.com/cconger/shindaggers/pkg/db/.gen/postgres/public/model"
+
+	"github.com/gorilla/mux"
+)
+
+type Collection struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Creator   User       `json:"creator"`
+	ActiveAt  *time.Time `json:"active_at"`
+	RetiredAt *time.Time `json:"retired_at"`
+	Active    bool       `json:"active"`
+}
+
+func CollectionFromDBCollection(c *db.Collection) Collection {
+	res := Collection{
+		ID:        strconv.FormatInt(c.ID, 10),
+		Name:      c.Name,
+		ActiveAt:  c.ActiveAt,
+		RetiredAt: c.RetiredAt,
+		Active:    c.IsActive(time.Now()),
+		Creator: User{
+			ID: strconv.FormatInt(c.CreatorID, 10),
+		},
+	}
+	if c.Creator != nil {
+		res.Creator.Name = c.Creator.Name
+	}
+	return res
+}
+
+// defaultCollection is the most recently activated collection that is still
+// active. It is used when a request doesn't name a collection.
+func (s *Server) defaultCollection(ctx context.Context) (*db.Collection, error) {
+	collections, err := s.db.GetCollections(ctx, db.GetCollectionsOptions{
+		OnlyActive: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(collections) == 0 {
+		return nil, db.ErrNotFound
+	}
+	return collections[0], nil
+}
+
+// collectionForPayload resolves the collection id supplied in a request body,
+// falling back to the default collection when it is empty.
+func (s *Server) collectionForPayload(ctx context.Context, id string) (*db.Collection, error) {
+	if id == "" {
+		return s.defaultCollection(ctx)
+	}
+
+	collectionID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("collection id not numeric: %w", err)
+	}
+
+	return s.db.GetCollection(ctx, collectionID)
+}
+
+// collectionFromRequest reads the collection query parameter, falling back to
+// the default collection.
+func (s *Server) collectionFromRequest(ctx context.Context, r *http.Request) (int64, error) {
+	c, err := s.collectionForPayload(ctx, r.URL.Query().Get("collection"))
+	if err != nil {
+		return 0, err
+	}
+	return c.ID, nil
+}
+
+func (s *Server) getCollections(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	collections, err := s.db.GetCollections(ctx, db.GetCollectionsOptions{})
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+
+	res := make([]Collection, len(collections))
+	for i, c := range collections {
+		res[i] = CollectionFromDBCollection(c)
+	}
+
+	serveAPIPayload(
+		w,
+		&struct {
+			Collections []Collection
+		}{
+			Collections: res,
+		},
+	)
+}
+
+func (s *Server) getCollectionCatalog(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusBadRequest, "Could not parse collection id")
+		return
+	}
+
+	collection, err := s.db.GetCollection(ctx, id)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			serveAPIErr(w, err, http.StatusNotFound, "Unknown collection")
+			return
+		}
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+
+	// Unreleased collections are only visible through the admin apis
+	if collection.ActiveAt == nil || collection.ActiveAt.After(time.Now()) {
+		serveAPIErr(w, fmt.Errorf("collection %d not released", id), http.StatusNotFound, "Unknown collection")
+		return
+	}
+
+	c, err := s.db.GetCollectables(ctx, db.GetCollectablesOptions{
+		Collection: collection.ID,
+	})
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+
+	counts, err := s.db.GetEditionCounts(ctx, db.GetEditionCountsOptions{
+		ByCollection: collection.ID,
+	})
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+	editions := EditionCountsByCollectable(counts)
+
+	collectables := make([]Collectable, len(c))
+	for i, c := range c {
+		collectables[i] = CollectableFromDBCollectable(c)
+		collectables[i].Editions = editions[c.ID]
+	}
+
+	serveAPIPayload(
+		w,
+		&struct {
+			Collection   Collection
+			Collectables []Collectable
+		}{
+			Collection:   CollectionFromDBCollection(collection),
+			Collectables: collectables,
+		},
+	)
+}
+
+func (s *Server) adminListCollections(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	collections, err := s.db.GetCollections(ctx, db.GetCollectionsOptions{
+		GetUnreleased: true,
+	})
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+
+	res := make([]Collection, len(collections))
+	for i, c := range collections {
+		res[i] = CollectionFromDBCollection(c)
+	}
+
+	serveAPIPayload(
+		w,
+		&struct {
+			Collections []Collection
+		}{
+			Collections: res,
+		},
+	)
+}
+
+type CollectionPayload struct {
+	Collection struct {
+		Name      string
+		ActiveAt  *time.Time
+		RetiredAt *time.Time
+		Weights   map[string]int
+	}
+}
+
+func (s *Server) adminCreateCollection(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	u := staffUser(ctx)
+
+	var payload CollectionPayload
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusBadRequest, "could not parse body")
+		return
+	}
+	r.Body.Close()
+
+	if payload.Collection.Name == "" {
+		serveAPIErr(w, errMissingField, http.StatusBadRequest, "Name cannot be empty")
+		return
+	}
+
+	err = validateWeights(payload.Collection.Weights)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	weights, err := json.Marshal(payload.Collection.Weights)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+	weightString := string(weights)
+
+	created, err := s.db.CreateCollection(ctx, model.Collections{
+		ID:        s.idGenerator.Generate().Int64(),
+		Name:      payload.Collection.Name,
+		Weights:   &weightString,
+		CreatorID: u.ID,
+		ActiveAt:  payload.Collection.ActiveAt,
+		RetiredAt: payload.Collection.RetiredAt,
+	})
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "could not create collection")
+		return
+	}
+
+	s.audit(ctx, u.ID, auditCreate, auditTargetCollection, created.ID, nil, created.Collections)
+
+	serveAPIPayload(w, struct {
+		Collection Collection
+	}{
+		Collection: CollectionFromDBCollection(created),
+	})
+}
+
+func (s *Server) adminUpdateCollection(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusBadRequest, "id is not numeric")
+		return
+	}
+
+	var payload CollectionPayload
+	err = json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusBadRequest, "could not parse body")
+		return
+	}
+	r.Body.Close()
+
+	if payload.Collection.Name == "" {
+		serveAPIErr(w, errMissingField, http.StatusBadRequest, "Name cannot be empty")
+		return
+	}
+
+	before := s.auditCollection(ctx, id)
+
+	updated, err := s.db.UpdateCollection(ctx, model.Collections{
+		ID:        id,
+		Name:      payload.Collection.Name,
+		ActiveAt:  payload.Collection.ActiveAt,
+		RetiredAt: payload.Collection.RetiredAt,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			serveAPIErr(w, err, http.StatusNotFound, "Unknown collection")
+			return
+		}
+		serveAPIErr(w, err, http.StatusInternalServerError, "could not update collection")
+		return
+	}
+
+	s.audit(ctx, staffUser(ctx).ID, auditUpdate, auditTargetCollection, id, before, updated.Collections)
+
+	serveAPIPayload(w, struct {
+		Collection Collection
+	}{
+		Collection: CollectionFromDBCollection(updated),
+	})
+}
+
+// adminRetireCollection stops pulls from a collection. Its collectables and
+// issued instances are kept.
+func (s *Server) adminRetireCollection(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusBadRequest, "id is not numeric")
+		return
+	}
+
+	collection, err := s.db.GetCollection(ctx, id)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			serveAPIErr(w, err, http.StatusNotFound, "Unknown collection")
+			return
+		}
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+
+	before := collection.Collections
+
+	now := time.Now()
+	collection.RetiredAt = &now
+
+	updated, err := s.db.UpdateCollection(ctx, collection.Collections)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "could not retire collection")
+		return
+	}
+
+	s.audit(ctx, staffUser(ctx).ID, auditRetire, auditTargetCollection, id, before, updated.Collections)
+
+	serveAPIPayload(w, struct {
+		Collection Collection
+	}{
+		Collection: CollectionFromDBCollection(updated),
+	})
+}
@@ -0,0 +1,464 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/cconger/shindaggers/pkg/db"
+	model "github.com/cconger/shindaggers/pkg/db/.gen/postgres/public/model"
+)
+
+// CraftRecipe prices crafting for a collection. Salvage is the materials
+// credited for salvaging a knife of each rarity, Forge is the materials spent
+// to forge a knife of at least that rarity.
+type CraftRecipe struct {
+	CollectionID string         `json:"collection_id"`
+	Salvage      map[string]int `json:"salvage"`
+	Forge        map[string]int `json:"forge"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+}
+
+func CraftRecipeFromDBCraftRecipe(r *db.CraftRecipe) (CraftRecipe, error) {
+	res := CraftRecipe{
+		CollectionID: strconv.FormatInt(r.CollectionID, 10),
+		UpdatedAt:    r.UpdatedAt,
+	}
+	err := json.Unmarshal([]byte(r.Salvage), &res.Salvage)
+	if err != nil {
+		return res, fmt.Errorf("parsing salvage for collection %d: %w", r.CollectionID, err)
+	}
+	err = json.Unmarshal([]byte(r.Forge), &res.Forge)
+	if err != nil {
+		return res, fmt.Errorf("parsing forge for collection %d: %w", r.CollectionID, err)
+	}
+	return res, nil
+}
+
+type MaterialBalance struct {
+	CollectionID string `json:"collection_id"`
+	Balance      int64  `json:"balance"`
+}
+
+func validateRecipe(salvage map[string]int, forge map[string]int) error {
+	for rarity, value := range salvage {
+		if !slices.Contains(rarities, rarity) {
+			return fmt.Errorf("unknown salvage rarity %q", rarity)
+		}
+		if value < 0 {
+			return fmt.Errorf("salvage for %q cannot be negative", rarity)
+		}
+	}
+
+	for rarity, cost := range forge {
+		if !slices.Contains(rarities, rarity) {
+			return fmt.Errorf("unknown forge rarity %q", rarity)
+		}
+		if cost <= 0 {
+			return fmt.Errorf("forge cost for %q must be positive", rarity)
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) getCraftRecipe(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	collectionID, err := s.collectionFromRequest(ctx, r)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			serveAPIErr(w, err, http.StatusNotFound, "Unknown collection")
+			return
+		}
+		serveAPIErr(w, err, http.StatusBadRequest, "Invalid collection")
+		return
+	}
+
+	recipe, err := s.db.GetCraftRecipe(ctx, collectionID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			serveAPIErr(w, err, http.StatusNotFound, "Collection has no crafting recipe")
+			return
+		}
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+
+	res, err := CraftRecipeFromDBCraftRecipe(recipe)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+
+	serveAPIPayload(w, struct {
+		Recipe CraftRecipe
+	}{
+		Recipe: res,
+	})
+}
+
+func (s *Server) materialBalances(r *http.Request, userID int64) ([]MaterialBalance, error) {
+	balances, err := s.db.GetMaterialBalances(r.Context(), userID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]MaterialBalance, len(balances))
+	for i, b := range balances {
+		res[i] = MaterialBalance{
+			CollectionID: strconv.FormatInt(b.CollectionID, 10),
+			Balance:      b.Balance,
+		}
+	}
+	return res, nil
+}
+
+func (s *Server) getMaterials(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	u, err := s.getAuthUser(ctx, r)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusForbidden, "could not identify user")
+		return
+	}
+
+	balances, err := s.materialBalances(r, u.ID)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+
+	serveAPIPayload(w, struct {
+		Materials []MaterialBalance
+	}{
+		Materials: balances,
+	})
+}
+
+type SalvagePayload struct {
+	InstanceIDs []string
+}
+
+func (s *Server) salvageHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	u, err := s.getAuthUser(ctx, r)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusForbidden, "could not identify user")
+		return
+	}
+
+	var payload SalvagePayload
+	err = json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusBadRequest, "could not parse body")
+		return
+	}
+	r.Body.Close()
+
+	if len(payload.InstanceIDs) == 0 {
+		serveAPIErr(w, errMissingField, http.StatusBadRequest, "InstanceIDs cannot be empty")
+		return
+	}
+
+	recipes := map[int64]CraftRecipe{}
+	credits := map[int64]int64{}
+	ids := make([]int64, len(payload.InstanceIDs))
+	seen := map[int64]bool{}
+	salvaged := make([]model.CollectableInstances, 0, len(payload.InstanceIDs))
+	for i, idstr := range payload.InstanceIDs {
+		id, err := strconv.ParseInt(idstr, 10, 64)
+		if err != nil {
+			serveAPIErr(w, err, http.StatusBadRequest, "InstanceIDs must be numeric")
+			return
+		}
+		if seen[id] {
+			serveAPIErr(w, fmt.Errorf("instance %d listed twice", id), http.StatusBadRequest, fmt.Sprintf("Instance %d listed more than once", id))
+			return
+		}
+		seen[id] = true
+		ids[i] = id
+
+		instances, err := s.db.GetCollectableInstances(ctx, db.GetCollectableInstancesOptions{
+			ByID: id,
+		})
+		if err != nil {
+			serveAPIErr(w, err, http.StatusInternalServerError, "")
+			return
+		}
+		if len(instances) == 0 || instances[0].OwnerID != u.ID {
+			serveAPIErr(w, fmt.Errorf("instance %d not owned by %d", id, u.ID), http.StatusBadRequest, fmt.Sprintf("You don't own instance %d", id))
+			return
+		}
+		instance := instances[0]
+		salvaged = append(salvaged, instance.CollectableInstances)
+
+		if instance.Collectable.CollectionID == nil {
+			serveAPIErr(w, fmt.Errorf("instance %d has no collection", id), http.StatusBadRequest, fmt.Sprintf("Instance %d cannot be salvaged", id))
+			return
+		}
+		collectionID := *instance.Collectable.CollectionID
+
+		recipe, ok := recipes[collectionID]
+		if !ok {
+			dbRecipe, err := s.db.GetCraftRecipe(ctx, collectionID)
+			if err != nil {
+				if errors.Is(err, db.ErrNotFound) {
+					serveAPIErr(w, err, http.StatusBadRequest, fmt.Sprintf("Instance %d cannot be salvaged", id))
+					return
+				}
+				serveAPIErr(w, err, http.StatusInternalServerError, "")
+				return
+			}
+			recipe, err = CraftRecipeFromDBCraftRecipe(dbRecipe)
+			if err != nil {
+				serveAPIErr(w, err, http.StatusInternalServerError, "")
+				return
+			}
+			recipes[collectionID] = recipe
+		}
+
+		value, ok := recipe.Salvage[instance.Collectable.Rarity]
+		if !ok {
+			serveAPIErr(w, fmt.Errorf("no salvage value for %s", instance.Collectable.Rarity), http.StatusBadRequest, fmt.Sprintf("Instance %d cannot be salvaged", id))
+			return
+		}
+		credits[collectionID] += int64(value)
+	}
+
+	err = s.db.SalvageCollectableInstances(ctx, u.ID, ids, credits)
+	if err != nil {
+		if errors.Is(err, db.ErrOwnershipChanged) {
+			serveAPIErr(w, err, http.StatusConflict, "A knife changed hands before it could be salvaged")
+			return
+		}
+		serveAPIErr(w, err, http.StatusInternalServerError, "could not salvage")
+		return
+	}
+
+	for _, instance := range salvaged {
+		s.audit(ctx, u.ID, auditSalvage, auditTargetInstance, instance.ID, instance, nil)
+	}
+
+	balances, err := s.materialBalances(r, u.ID)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+
+	credited := make([]MaterialBalance, 0, len(credits))
+	for collectionID, amount := range credits {
+		credited = append(credited, MaterialBalance{
+			CollectionID: strconv.FormatInt(collectionID, 10),
+			Balance:      amount,
+		})
+	}
+
+	serveAPIPayload(w, struct {
+		Salvaged  int
+		Credited  []MaterialBalance
+		Materials []MaterialBalance
+	}{
+		Salvaged:  len(ids),
+		Credited:  credited,
+		Materials: balances,
+	})
+}
+
+type ForgePayload struct {
+	CollectionID string
+	MinRarity    string
+}
+
+func (s *Server) forgeHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	u, err := s.getAuthUser(ctx, r)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusForbidden, "could not identify user")
+		return
+	}
+
+	var payload ForgePayload
+	err = json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusBadRequest, "could not parse body")
+		return
+	}
+	r.Body.Close()
+
+	if !slices.Contains(rarities, payload.MinRarity) {
+		serveAPIErr(w, fmt.Errorf("unknown rarity %q", payload.MinRarity), http.StatusBadRequest, "MinRarity is not a known rarity")
+		return
+	}
+
+	collection, err := s.collectionForPayload(ctx, payload.CollectionID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			serveAPIErr(w, err, http.StatusBadRequest, "Unknown collection")
+			return
+		}
+		serveAPIErr(w, err, http.StatusBadRequest, "CollectionID is not valid")
+		return
+	}
+	if !collection.IsActive(time.Now()) {
+		serveAPIErr(w, fmt.Errorf("collection %d not active", collection.ID), http.StatusBadRequest, "Collection is not active")
+		return
+	}
+
+	dbRecipe, err := s.db.GetCraftRecipe(ctx, collection.ID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			serveAPIErr(w, err, http.StatusBadRequest, "Collection has no crafting recipe")
+			return
+		}
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+	recipe, err := CraftRecipeFromDBCraftRecipe(dbRecipe)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+
+	cost, ok := recipe.Forge[payload.MinRarity]
+	if !ok {
+		serveAPIErr(w, fmt.Errorf("no forge cost for %s", payload.MinRarity), http.StatusBadRequest, fmt.Sprintf("%s knives cannot be forged", payload.MinRarity))
+		return
+	}
+
+	collectable, err := s.getRandomCollectableFromCollection(ctx, collection.ID, payload.MinRarity)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "unexpected error")
+		return
+	}
+
+	tags, err := json.Marshal(map[string]bool{
+		"subscriber": false,
+		"verified":   rand.Intn(100) == 0,
+	})
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "unexpected error")
+		return
+	}
+	tagString := string(tags)
+
+	forged, err := s.createWithEdition(ctx, collectable, func(edition *db.Edition) (*db.CollectableInstance, error) {
+		return s.db.ForgeCollectableInstance(ctx, model.CollectableInstances{
+			ID:            s.idGenerator.Generate().Int64(),
+			CollectableID: collectable.ID,
+			OwnerID:       u.ID,
+			EditionID:     edition.ID,
+			CreatedAt:     time.Now().UTC(),
+			Tags:          &tagString,
+		}, collection.ID, int64(cost))
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrInsufficientMaterials) {
+			serveAPIErr(w, err, http.StatusBadRequest, fmt.Sprintf("Forging a %s knife costs %d materials", payload.MinRarity, cost))
+			return
+		}
+		serveAPIErr(w, err, http.StatusInternalServerError, "could not forge")
+		return
+	}
+
+	s.audit(ctx, u.ID, auditForge, auditTargetInstance, forged.ID, nil, forged.CollectableInstances)
+
+	c := IssuedCollectableFromCollectableInstance(forged)
+	s.latest.Publish(c)
+
+	serveAPIPayload(
+		w,
+		&c,
+	)
+}
+
+func (s *Server) adminGetCraftRecipe(w http.ResponseWriter, r *http.Request) {
+	s.getCraftRecipe(w, r)
+}
+
+type CraftRecipePayload struct {
+	Salvage map[string]int
+	Forge   map[string]int
+}
+
+func (s *Server) adminUpdateCraftRecipe(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	u := staffUser(ctx)
+
+	collectionID, err := s.collectionFromRequest(ctx, r)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			serveAPIErr(w, err, http.StatusNotFound, "Unknown collection")
+			return
+		}
+		serveAPIErr(w, err, http.StatusBadRequest, "Invalid collection")
+		return
+	}
+
+	var payload CraftRecipePayload
+	err = json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusBadRequest, "could not parse body")
+		return
+	}
+	r.Body.Close()
+
+	err = validateRecipe(payload.Salvage, payload.Forge)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	salvage, err := json.Marshal(payload.Salvage)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+	forge, err := json.Marshal(payload.Forge)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+
+	var before any
+	existing, err := s.db.GetCraftRecipe(ctx, collectionID)
+	if err == nil {
+		before = existing.CraftRecipes
+	} else if !errors.Is(err, db.ErrNotFound) {
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+
+	saved, err := s.db.SaveCraftRecipe(ctx, model.CraftRecipes{
+		CollectionID: collectionID,
+		Salvage:      string(salvage),
+		Forge:        string(forge),
+		UpdatedBy:    &u.ID,
+	})
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "could not save recipe")
+		return
+	}
+
+	s.audit(ctx, u.ID, auditUpdate, auditTargetCollection, collectionID, before, saved.CraftRecipes)
+
+	res, err := CraftRecipeFromDBCraftRecipe(saved)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+
+	serveAPIPayload(w, struct {
+		Recipe CraftRecipe
+	}{
+		Recipe: res,
+	})
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/cconger/shindaggers/pkg/db"
+	model "github.com/cconger/shindaggers/pkg/db/.gen/postgres/public/model"
+
+	"github.com/gorilla/mux"
+)
+
+type Edition struct {
+	ID           string     `json:"id"`
+	Name         string     `json:"name"`
+	CollectionID string     `json:"collection_id,omitempty"`
+	FirstN       *int64     `json:"first_n,omitempty"`
+	ActiveAt     *time.Time `json:"active_at"`
+	RetiredAt    *time.Time `json:"retired_at"`
+	Active       bool       `json:"active"`
+}
+
+func EditionFromDBEdition(e *db.Edition) Edition {
+	res := Edition{
+		ID:        strconv.FormatInt(e.ID, 10),
+		Name:      e.Name,
+		FirstN:    e.FirstN,
+		ActiveAt:  e.ActiveAt,
+		RetiredAt: e.RetiredAt,
+		Active:    e.IsActive(time.Now()),
+	}
+	if e.CollectionID != nil {
+		res.CollectionID = strconv.FormatInt(*e.CollectionID, 10)
+	}
+	return res
+}
+
+type EditionCount struct {
+	EditionID string `json:"edition_id"`
+	Edition   string `json:"edition"`
+	Count     int64  `json:"count"`
+}
+
+func EditionCountsByCollectable(counts []db.EditionCount) map[int64][]EditionCount {
+	res := make(map[int64][]EditionCount)
+	for _, c := range counts {
+		res[c.CollectableID] = append(res[c.CollectableID], EditionCount{
+			EditionID: strconv.FormatInt(c.EditionID, 10),
+			Edition:   c.EditionName,
+			Count:     c.Count,
+		})
+	}
+	return res
+}
+
+// EditionTotals sums counts across collectables, giving how many of each
+// edition there are, most common first.
+func EditionTotals(counts []db.EditionCount) []EditionCount {
+	totals := make(map[int64]*EditionCount)
+	for _, c := range counts {
+		t, ok := totals[c.EditionID]
+		if !ok {
+			t = &EditionCount{
+				EditionID: strconv.FormatInt(c.EditionID, 10),
+				Edition:   c.EditionName,
+			}
+			totals[c.EditionID] = t
+		}
+		t.Count += c.Count
+	}
+
+	res := make([]EditionCount, 0, len(totals))
+	for _, t := range totals {
+		res = append(res, *t)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Count > res[j].Count
+	})
+	return res
+}
+
+// resolveEdition picks the edition for a new pull of collectable. Active
+// first-N editions win while the collectable has fewer than N instances, then
+// the most recently activated seasonal edition, then the default edition.
+func (s *Server) resolveEdition(ctx context.Context, collectable *db.Collectable) (*db.Edition, error) {
+	editions, err := s.db.GetEditions(ctx, db.GetEditionsOptions{
+		OnlyActive: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var seasonal *db.Edition
+	var count int64 = -1
+	for _, e := range editions {
+		if e.CollectionID != nil && (collectable.CollectionID == nil || *e.CollectionID != *collectable.CollectionID) {
+			continue
+		}
+
+		if e.FirstN == nil {
+			if seasonal == nil {
+				seasonal = e
+			}
+			continue
+		}
+
+		if count < 0 {
+			count, err = s.db.CountCollectableInstances(ctx, collectable.ID)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if count < *e.FirstN {
+			return e, nil
+		}
+	}
+
+	if seasonal != nil {
+		return seasonal, nil
+	}
+
+	return s.db.GetEdition(ctx, db.DefaultEditionID)
+}
+
+// createWithEdition creates an instance of collectable in the edition it
+// resolves to. If another pull took the last of a first edition in the
+// meantime the edition is resolved again.
+func (s *Server) createWithEdition(ctx context.Context, collectable *db.Collectable, create func(edition *db.Edition) (*db.CollectableInstance, error)) (*db.CollectableInstance, error) {
+	for attempt := 1; ; attempt++ {
+		edition, err := s.resolveEdition(ctx, collectable)
+		if err != nil {
+			return nil, err
+		}
+
+		issued, err := create(edition)
+		if errors.Is(err, db.ErrEditionFull) && attempt < 3 {
+			continue
+		}
+		return issued, err
+	}
+}
+
+func (s *Server) adminListEditions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	editions, err := s.db.GetEditions(ctx, db.GetEditionsOptions{})
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+
+	res := make([]Edition, len(editions))
+	for i, e := range editions {
+		res[i] = EditionFromDBEdition(e)
+	}
+
+	serveAPIPayload(
+		w,
+		&struct {
+			Editions []Edition
+		}{
+			Editions: res,
+		},
+	)
+}
+
+type EditionPayload struct {
+	Edition struct {
+		Name         string
+		CollectionID string
+		FirstN       *int64
+		ActiveAt     *time.Time
+		RetiredAt    *time.Time
+	}
+}
+
+func (s *Server) adminCreateEdition(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var payload EditionPayload
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusBadRequest, "could not parse body")
+		return
+	}
+	r.Body.Close()
+
+	if payload.Edition.Name == "" {
+		serveAPIErr(w, errMissingField, http.StatusBadRequest, "Name cannot be empty")
+		return
+	}
+
+	if payload.Edition.FirstN != nil && *payload.Edition.FirstN <= 0 {
+		serveAPIErr(w, errMissingField, http.StatusBadRequest, "FirstN must be positive")
+		return
+	}
+
+	var collectionID *int64
+	if payload.Edition.CollectionID != "" {
+		collection, err := s.collectionForPayload(ctx, payload.Edition.CollectionID)
+		if err != nil {
+			if errors.Is(err, db.ErrNotFound) {
+				serveAPIErr(w, err, http.StatusBadRequest, "Unknown collection")
+				return
+			}
+			serveAPIErr(w, err, http.StatusBadRequest, "CollectionID is not valid")
+			return
+		}
+		collectionID = &collection.ID
+	}
+
+	created, err := s.db.CreateEdition(ctx, model.Editions{
+		ID:           s.idGenerator.Generate().Int64(),
+		Name:         payload.Edition.Name,
+		CollectionID: collectionID,
+		FirstN:       payload.Edition.FirstN,
+		ActiveAt:     payload.Edition.ActiveAt,
+		RetiredAt:    payload.Edition.RetiredAt,
+	})
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "could not create edition")
+		return
+	}
+
+	s.audit(ctx, staffUser(ctx).ID, auditCreate, auditTargetEdition, created.ID, nil, created.Editions)
+
+	serveAPIPayload(w, struct {
+		Edition Edition
+	}{
+		Edition: EditionFromDBEdition(created),
+	})
+}
+
+func (s *Server) adminActivateEdition(w http.ResponseWriter, r *http.Request) {
+	s.adminScheduleEdition(w, r, true)
+}
+
+func (s *Server) adminRetireEdition(w http.ResponseWriter, r *http.Request) {
+	s.adminScheduleEdition(w, r, false)
+}
+
+// adminScheduleEdition starts an edition now, or retires it now.
+func (s *Server) adminScheduleEdition(w http.ResponseWriter, r *http.Request, activate bool) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusBadRequest, "id is not numeric")
+		return
+	}
+
+	edition, err := s.db.GetEdition(ctx, id)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			serveAPIErr(w, err, http.StatusNotFound, "Unknown edition")
+			return
+		}
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+
+	before := edition.Editions
+
+	now := time.Now()
+	action := auditRetire
+	if activate {
+		edition.ActiveAt = &now
+		edition.RetiredAt = nil
+		action = auditActivate
+	} else {
+		edition.RetiredAt = &now
+	}
+
+	updated, err := s.db.UpdateEdition(ctx, edition.Editions)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "could not update edition")
+		return
+	}
+
+	s.audit(ctx, staffUser(ctx).ID, action, auditTargetEdition, id, before, updated.Editions)
+
+	serveAPIPayload(w, struct {
+		Edition Edition
+	}{
+		Edition: EditionFromDBEdition(updated),
+	})
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cconger/shindaggers/pkg/db"
+	model "github.com/cconger/shindaggers/pkg/db/.gen/postgres/public/model"
+
+	"github.com/gorilla/mux"
+)
+
+var (
+	slugPattern    = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+	slugSeparators = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// eventFightLimit caps the number of results returned on an event page.
+const eventFightLimit = 500
+
+type Event struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	Slug        string     `json:"slug"`
+	Description string     `json:"description,omitempty"`
+	Creator     User       `json:"creator"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ClosedAt    *time.Time `json:"closed_at"`
+	Active      bool       `json:"active"`
+}
+
+func EventFromDBEvent(e *db.Event) Event {
+	res := Event{
+		ID:        strconv.FormatInt(e.ID, 10),
+		Name:      e.Name,
+		Slug:      e.Slug,
+		CreatedAt: e.CreatedAt,
+		ClosedAt:  e.ClosedAt,
+		Active:    e.IsActive(time.Now()),
+		Creator: User{
+			ID: strconv.FormatInt(e.CreatorID, 10),
+		},
+	}
+	if e.Description != nil {
+		res.Description = *e.Description
+	}
+	if e.Creator != nil {
+		res.Creator.Name = e.Creator.Name
+	}
+	return res
+}
+
+type LeaderboardEntry struct {
+	Rank   int  `json:"rank"`
+	User   User `json:"user"`
+	Wins   int  `json:"wins"`
+	Losses int  `json:"losses"`
+	Ties   int  `json:"ties"`
+}
+
+func slugify(name string) string {
+	return strings.Trim(slugSeparators.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+// activeEvent is the most recently created event that hasn't been closed, or
+// nil when no event is running.
+func (s *Server) activeEvent(ctx context.Context) (*db.Event, error) {
+	events, err := s.db.GetEvents(ctx, db.GetEventsOptions{
+		OnlyActive: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, nil
+	}
+	return events[0], nil
+}
+
+func (s *Server) getEvents(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	events, err := s.db.GetEvents(ctx, db.GetEventsOptions{})
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+
+	res := make([]Event, len(events))
+	for i, e := range events {
+		res[i] = EventFromDBEvent(e)
+	}
+
+	serveAPIPayload(
+		w,
+		&struct {
+			Events []Event
+		}{
+			Events: res,
+		},
+	)
+}
+
+func (s *Server) getEvent(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	event, err := s.db.GetEvent(ctx, db.GetEventOptions{
+		Slug: vars["slug"],
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			serveAPIErr(w, err, http.StatusNotFound, "Unknown event")
+			return
+		}
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+
+	fights, err := s.db.GetFights(ctx, db.GetFightsOptions{
+		ByEvent: event.ID,
+		Limit:   eventFightLimit,
+	})
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+
+	board, err := s.db.GetEventLeaderboard(ctx, event.ID)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+
+	matches, err := s.db.GetEventMatches(ctx, event.ID)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+
+	results := make([]Fight, len(fights))
+	for i, f := range fights {
+		results[i] = FightFromDBFight(&f)
+	}
+
+	participants := make([]User, len(board))
+	leaderboard := make([]LeaderboardEntry, len(board))
+	for i, b := range board {
+		participants[i] = User{
+			ID:   strconv.FormatInt(b.UserID, 10),
+			Name: b.UserName,
+		}
+
+		// Users with the same record share a rank
+		rank := i + 1
+		if i > 0 && b.Wins == board[i-1].Wins && b.Losses == board[i-1].Losses {
+			rank = leaderboard[i-1].Rank
+		}
+		leaderboard[i] = LeaderboardEntry{
+			Rank:   rank,
+			User:   participants[i],
+			Wins:   int(b.Wins),
+			Losses: int(b.Losses),
+			Ties:   int(b.Draws),
+		}
+	}
+
+	serveAPIPayload(
+		w,
+		&struct {
+			Event        Event
+			Participants []User
+			Results      []Fight
+			Leaderboard  []LeaderboardEntry
+			Bracket      []BracketRound
+		}{
+			Event:        EventFromDBEvent(event),
+			Participants: participants,
+			Results:      results,
+			Leaderboard:  leaderboard,
+			Bracket:      BracketFromDBMatches(matches),
+		},
+	)
+}
+
+type EventPayload struct {
+	Event struct {
+		Name        string
+		Slug        string
+		Description string
+	}
+}
+
+func (s *Server) adminCreateEvent(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	u := staffUser(ctx)
+
+	var payload EventPayload
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusBadRequest, "could not parse body")
+		return
+	}
+	r.Body.Close()
+
+	if payload.Event.Name == "" {
+		serveAPIErr(w, errMissingField, http.StatusBadRequest, "Name cannot be empty")
+		return
+	}
+
+	slug := payload.Event.Slug
+	if slug == "" {
+		slug = slugify(payload.Event.Name)
+	}
+	if !slugPattern.MatchString(slug) {
+		serveAPIErr(w, fmt.Errorf("invalid slug %q", slug), http.StatusBadRequest, "Slug must be lowercase letters, numbers and dashes")
+		return
+	}
+
+	_, err = s.db.GetEvent(ctx, db.GetEventOptions{
+		Slug: slug,
+	})
+	if err == nil {
+		serveAPIErr(w, fmt.Errorf("slug %s in use", slug), http.StatusConflict, "An event with that slug already exists")
+		return
+	}
+	if !errors.Is(err, db.ErrNotFound) {
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+
+	// Fights attach to the running event, so only one can be open at a time
+	active, err := s.activeEvent(ctx)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+	if active != nil {
+		serveAPIErr(w, fmt.Errorf("event %d still active", active.ID), http.StatusConflict, fmt.Sprintf("Close %s before starting a new event", active.Name))
+		return
+	}
+
+	var description *string
+	if payload.Event.Description != "" {
+		description = &payload.Event.Description
+	}
+
+	created, err := s.db.CreateEvent(ctx, model.Events{
+		ID:          s.idGenerator.Generate().Int64(),
+		Name:        payload.Event.Name,
+		Slug:        slug,
+		Description: description,
+		CreatorID:   u.ID,
+	})
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "could not create event")
+		return
+	}
+
+	s.audit(ctx, u.ID, auditCreate, auditTargetEvent, created.ID, nil, created.Events)
+
+	serveAPIPayload(w, struct {
+		Event Event
+	}{
+		Event: EventFromDBEvent(created),
+	})
+}
+
+func (s *Server) adminCloseEvent(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusBadRequest, "id is not numeric")
+		return
+	}
+
+	event, err := s.db.GetEvent(ctx, db.GetEventOptions{
+		ID: id,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			serveAPIErr(w, err, http.StatusNotFound, "Unknown event")
+			return
+		}
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+
+	now := time.Now()
+	if !event.IsActive(now) {
+		serveAPIErr(w, fmt.Errorf("event %d already closed", id), http.StatusConflict, "Event already closed")
+		return
+	}
+	before := event.Events
+	event.ClosedAt = &now
+
+	updated, err := s.db.UpdateEvent(ctx, event.Events)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "could not close event")
+		return
+	}
+
+	s.audit(ctx, staffUser(ctx).ID, auditClose, auditTargetEvent, id, before, updated.Events)
+
+	serveAPIPayload(w, struct {
+		Event Event
+	}{
+		Event: EventFromDBEvent(updated),
+	})
+}
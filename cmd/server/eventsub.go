@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	model "github.com/cconger/shindaggers/pkg/db/.gen/postgres/public/model"
+	"github.com/cconger/shindaggers/pkg/twitch"
+)
+
+// Twitch notifications are small, anything larger isn't from twitch.
+const eventSubMaxBody = 1 << 20
+
+// subscribeRedemptions asks twitch to deliver channel point redemptions for
+// the configured broadcaster to EventSubHandler.
+func (s *Server) subscribeRedemptions(ctx context.Context) error {
+	condition := map[string]string{
+		"broadcaster_user_id": s.broadcasterID,
+	}
+	if s.eventSubRewardID != "" {
+		condition["reward_id"] = s.eventSubRewardID
+	}
+
+	sub, err := s.twitchClient.CreateEventSubSubscription(ctx, twitch.EventSubSubscriptionRequest{
+		Type:      twitch.RedemptionAddSubscription,
+		Version:   "1",
+		Condition: condition,
+		Transport: twitch.EventSubTransport{
+			Method:   "webhook",
+			Callback: fmt.Sprintf("%s/api/twitch/eventsub", s.baseURL),
+			Secret:   s.eventSubSecret,
+		},
+	})
+	if err != nil {
+		if errors.Is(err, twitch.ErrSubscriptionExists) {
+			slog.Info("eventsub redemption subscription already exists")
+			return nil
+		}
+		return err
+	}
+
+	slog.Info("created eventsub subscription", "id", sub.ID, "status", sub.Status)
+	return nil
+}
+
+// EventSubHandler receives twitch eventsub webhooks. Channel point redemptions
+// are turned into random pulls for the redeeming user.
+func (s *Server) EventSubHandler(w http.ResponseWriter, r *http.Request) {
+	if s.eventSubSecret == "" {
+		serveAPIErr(w, fmt.Errorf("server running without eventsub secret"), http.StatusInternalServerError, "")
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, eventSubMaxBody))
+	if err != nil {
+		serveAPIErr(w, err, http.StatusBadRequest, "could not read body")
+		return
+	}
+	r.Body.Close()
+
+	err = twitch.VerifyEventSubMessage(s.eventSubSecret, r.Header, body, time.Now())
+	if err != nil {
+		serveAPIErr(w, err, http.StatusForbidden, "")
+		return
+	}
+
+	var msg twitch.EventSubMessage
+	err = json.Unmarshal(body, &msg)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusBadRequest, "could not parse")
+		return
+	}
+
+	switch r.Header.Get(twitch.EventSubMessageType) {
+	case twitch.EventSubTypeVerification:
+		slog.Info("eventsub challenge", "subscription", msg.Subscription.ID, "type", msg.Subscription.Type)
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(msg.Challenge))
+	case twitch.EventSubTypeRevocation:
+		slog.Warn("eventsub subscription revoked", "subscription", msg.Subscription.ID, "type", msg.Subscription.Type, "status", msg.Subscription.Status)
+		w.WriteHeader(http.StatusNoContent)
+	case twitch.EventSubTypeNotification:
+		s.handleEventSubNotification(w, r, msg)
+	default:
+		serveAPIErr(w, fmt.Errorf("unknown eventsub message type %q", r.Header.Get(twitch.EventSubMessageType)), http.StatusBadRequest, "unknown message type")
+	}
+}
+
+func (s *Server) handleEventSubNotification(w http.ResponseWriter, r *http.Request, msg twitch.EventSubMessage) {
+	ctx := r.Context()
+
+	if msg.Subscription.Type != twitch.RedemptionAddSubscription {
+		slog.Warn("ignoring eventsub notification", "type", msg.Subscription.Type)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var event twitch.RedemptionEvent
+	err := json.Unmarshal(msg.Event, &event)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusBadRequest, "could not parse event")
+		return
+	}
+
+	if s.eventSubRewardID != "" && event.Reward.ID != s.eventSubRewardID {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	// Twitch retries anything it doesn't see a 2xx for, so the same redemption
+	// can arrive more than once.
+	messageID := r.Header.Get(twitch.EventSubMessageID)
+	claimed, err := s.db.ClaimEventSubMessage(ctx, model.EventsubMessages{
+		MessageID:        messageID,
+		MessageType:      twitch.EventSubTypeNotification,
+		SubscriptionType: msg.Subscription.Type,
+	})
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+	if !claimed {
+		slog.Info("duplicate eventsub message", "message_id", messageID, "redemption", event.ID)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	slog.Info("Redemption", "redemption", event.ID, "user", event.UserID, "reward", event.Reward.Title)
+
+	c, err := s.randomPull(ctx, RandomPullRequest{
+		TwitchID: event.UserID,
+	})
+	if err != nil {
+		// Let twitch redeliver the message
+		releaseErr := s.db.ReleaseEventSubMessage(ctx, messageID)
+		if releaseErr != nil {
+			slog.Error("releasing eventsub message", "message_id", messageID, "err", releaseErr)
+		}
+		if errors.Is(err, errTwitchLookup) {
+			serveAPIErr(w, err, http.StatusInternalServerError, "unable to get user from twitch")
+			return
+		}
+		serveAPIErr(w, err, http.StatusInternalServerError, "unexpected error")
+		return
+	}
+
+	serveAPIPayload(
+		w,
+		c,
+	)
+}
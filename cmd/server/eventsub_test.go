@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/snowflake"
+
+	"github.com/cconger/shindaggers/pkg/db"
+	"github.com/cconger/shindaggers/pkg/twitch"
+)
+
+const testEventSubSecret = "eventsub-secret"
+
+// newEventSubTestServer runs the handler against a seeded in-memory db and a
+// local stand-in for the twitch token and users endpoints.
+func newEventSubTestServer(t *testing.T) *Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "app-token",
+			"expires_in":   3600,
+		})
+	})
+	mux.HandleFunc("/helix/users", func(w http.ResponseWriter, r *http.Request) {
+		users := []map[string]string{}
+		for _, id := range r.URL.Query()["id"] {
+			users = append(users, map[string]string{
+				"id":           id,
+				"login":        "viewer" + id,
+				"display_name": "Viewer" + id,
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]any{"data": users})
+	})
+	helix := httptest.NewServer(mux)
+	t.Cleanup(helix.Close)
+
+	tc, err := twitch.NewClient("client-id", "client-secret", helix.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tc.SetEndpoints(helix.URL, helix.URL+"/helix")
+
+	store := db.NewMemoryDB()
+	err = store.Seed(hashAuthToken([]byte("admin")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	node, err := snowflake.NewNode(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &Server{
+		db:             store,
+		twitchClient:   tc,
+		idGenerator:    node,
+		latest:         newLatestBroker(),
+		eventSubSecret: testEventSubSecret,
+	}
+}
+
+func eventSubRequest(t *testing.T, secret, messageID, messageType string, sent time.Time, msg any) *http.Request {
+	t.Helper()
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	timestamp := sent.UTC().Format(time.RFC3339Nano)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(messageID))
+	mac.Write([]byte(timestamp))
+	mac.Write(body)
+
+	r := httptest.NewRequest(http.MethodPost, "/api/twitch/eventsub", bytes.NewReader(body))
+	r.Header.Set(twitch.EventSubMessageID, messageID)
+	r.Header.Set(twitch.EventSubMessageTimestamp, timestamp)
+	r.Header.Set(twitch.EventSubMessageSignature, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	r.Header.Set(twitch.EventSubMessageType, messageType)
+	return r
+}
+
+func redemption(twitchID string) map[string]any {
+	return map[string]any{
+		"subscription": map[string]any{
+			"id":   "sub-1",
+			"type": twitch.RedemptionAddSubscription,
+		},
+		"event": map[string]any{
+			"id":      "redemption-" + twitchID,
+			"user_id": twitchID,
+			"reward":  map[string]any{"id": "reward-1", "title": "Pull a knife"},
+		},
+	}
+}
+
+func serveEventSub(s *Server, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	s.EventSubHandler(w, r)
+	return w
+}
+
+func pullsFor(t *testing.T, s *Server, twitchID string) int {
+	t.Helper()
+
+	ctx := context.Background()
+	user, err := s.db.GetUser(ctx, db.GetUserOptions{TwitchID: twitchID})
+	if err != nil {
+		return 0
+	}
+	instances, err := s.db.GetCollectableInstances(ctx, db.GetCollectableInstancesOptions{ByOwner: user.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(instances)
+}
+
+func TestEventSubRedemption(t *testing.T) {
+	s := newEventSubTestServer(t)
+
+	w := serveEventSub(s, eventSubRequest(t, testEventSubSecret, "msg-1", twitch.EventSubTypeNotification, time.Now(), redemption("4242")))
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", w.Code, w.Body)
+	}
+
+	var pulled IssuedCollectable
+	err := json.NewDecoder(w.Body).Decode(&pulled)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pulled.Owner.Name != "Viewer4242" {
+		t.Errorf("pull went to %q", pulled.Owner.Name)
+	}
+	if n := pullsFor(t, s, "4242"); n != 1 {
+		t.Errorf("got %d pulls, want 1", n)
+	}
+}
+
+func TestEventSubRejectsBadSignature(t *testing.T) {
+	s := newEventSubTestServer(t)
+
+	w := serveEventSub(s, eventSubRequest(t, "not-the-secret", "msg-1", twitch.EventSubTypeNotification, time.Now(), redemption("4242")))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("got status %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if n := pullsFor(t, s, "4242"); n != 0 {
+		t.Errorf("got %d pulls, want 0", n)
+	}
+}
+
+func TestEventSubRejectsStaleMessage(t *testing.T) {
+	s := newEventSubTestServer(t)
+
+	sent := time.Now().Add(-time.Hour)
+	w := serveEventSub(s, eventSubRequest(t, testEventSubSecret, "msg-1", twitch.EventSubTypeNotification, sent, redemption("4242")))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("got status %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if n := pullsFor(t, s, "4242"); n != 0 {
+		t.Errorf("got %d pulls, want 0", n)
+	}
+}
+
+func TestEventSubVerificationChallenge(t *testing.T) {
+	s := newEventSubTestServer(t)
+
+	w := serveEventSub(s, eventSubRequest(t, testEventSubSecret, "msg-1", twitch.EventSubTypeVerification, time.Now(), map[string]any{
+		"challenge": "pogchamp-kappa-360noscope-vohiyo",
+		"subscription": map[string]any{
+			"id":   "sub-1",
+			"type": twitch.RedemptionAddSubscription,
+		},
+	}))
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", w.Code, w.Body)
+	}
+	if got := w.Body.String(); got != "pogchamp-kappa-360noscope-vohiyo" {
+		t.Errorf("got challenge response %q", got)
+	}
+}
+
+func TestEventSubIgnoresRepeatedMessage(t *testing.T) {
+	s := newEventSubTestServer(t)
+
+	now := time.Now()
+	w := serveEventSub(s, eventSubRequest(t, testEventSubSecret, "msg-1", twitch.EventSubTypeNotification, now, redemption("4242")))
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", w.Code, w.Body)
+	}
+
+	// Twitch redelivers with the same message id
+	w = serveEventSub(s, eventSubRequest(t, testEventSubSecret, "msg-1", twitch.EventSubTypeNotification, now, redemption("4242")))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("got status %d, want %d", w.Code, http.StatusNoContent)
+	}
+	if n := pullsFor(t, s, "4242"); n != 1 {
+		t.Errorf("got %d pulls, want 1", n)
+	}
+}
@@ -0,0 +1,393 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cconger/shindaggers/pkg/db"
+	model "github.com/cconger/shindaggers/pkg/db/.gen/postgres/public/model"
+
+	"github.com/gorilla/mux"
+)
+
+type Fight struct {
+	ID           string             `json:"id"`
+	EventID      string             `json:"event_id,omitempty"`
+	CreatedAt    time.Time          `json:"created_at"`
+	Participants []FightParticipant `json:"participants"`
+}
+
+type FightParticipant struct {
+	User        User         `json:"user"`
+	InstanceID  string       `json:"instance_id,omitempty"`
+	Collectable *Collectable `json:"collectable,omitempty"`
+	Outcome     string       `json:"outcome"`
+}
+
+func FightFromDBFight(f *db.Fight) Fight {
+	res := Fight{
+		ID:           strconv.FormatInt(f.ID, 10),
+		CreatedAt:    f.CreatedAt,
+		Participants: make([]FightParticipant, len(f.Outcomes)),
+	}
+	if f.EventID != nil {
+		res.EventID = strconv.FormatInt(*f.EventID, 10)
+	}
+	for i, o := range f.Outcomes {
+		p := FightParticipant{
+			User: User{
+				ID: strconv.FormatInt(o.UserID, 10),
+			},
+			Outcome: o.Outcome,
+		}
+		if o.User != nil {
+			p.User.Name = o.User.Name
+		}
+		if o.InstanceID != nil {
+			p.InstanceID = strconv.FormatInt(*o.InstanceID, 10)
+		}
+		if o.Collectable != nil {
+			c := CollectableFromDBCollectable(o.Collectable)
+			p.Collectable = &c
+		}
+		res.Participants[i] = p
+	}
+	return res
+}
+
+// maxFightClientID bounds the id the bot can attach to a fight report
+const maxFightClientID = 128
+
+type FightRequest struct {
+	// ClientID is optional, a report repeating one that was already recorded
+	// returns the recorded fight instead of recording it again.
+	ClientID     string                    `json:"client_id"`
+	Participants []FightParticipantRequest `json:"participants"`
+}
+
+type FightParticipantRequest struct {
+	TwitchID string `json:"twitch_id"`
+	Outcome  string `json:"outcome"`
+}
+
+// validateFight checks that a fight has at least two distinct participants and
+// that the outcomes agree: either everyone draws, or there is at least one
+// winner and one loser.
+func validateFight(req FightRequest) error {
+	if len(req.ClientID) > maxFightClientID {
+		return fmt.Errorf("client_id is longer than %d characters", maxFightClientID)
+	}
+	if len(req.Participants) < 2 {
+		return fmt.Errorf("a fight needs at least two participants")
+	}
+
+	seen := map[string]bool{}
+	counts := map[string]int{}
+	for _, p := range req.Participants {
+		if p.TwitchID == "" {
+			return fmt.Errorf("participant missing twitch_id")
+		}
+		if seen[p.TwitchID] {
+			return fmt.Errorf("participant %s listed more than once", p.TwitchID)
+		}
+		seen[p.TwitchID] = true
+
+		switch p.Outcome {
+		case db.FightOutcomeWin, db.FightOutcomeLoss, db.FightOutcomeDraw:
+			counts[p.Outcome]++
+		default:
+			return fmt.Errorf("unknown outcome %q", p.Outcome)
+		}
+	}
+
+	if counts[db.FightOutcomeDraw] > 0 {
+		if counts[db.FightOutcomeDraw] != len(req.Participants) {
+			return fmt.Errorf("a draw cannot have winners or losers")
+		}
+		return nil
+	}
+
+	if counts[db.FightOutcomeWin] == 0 || counts[db.FightOutcomeLoss] == 0 {
+		return fmt.Errorf("a fight needs a winner and a loser")
+	}
+
+	return nil
+}
+
+// FightHandler records a fight reported by the bot. Each participant fights
+// with whatever knife they have equipped at the time, and the fight counts
+// towards the active event if there is one. Reports are retried by the bot,
+// so a client_id that was already recorded returns the recorded fight.
+func (s *Server) FightHandler(w http.ResponseWriter, r *http.Request) {
+	if s.webhookSecret == "" {
+		serveAPIErr(w, fmt.Errorf("server running without webhook secret"), http.StatusInternalServerError, "")
+		return
+	}
+
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	token := vars["token"]
+	if token != s.webhookSecret {
+		serveAPIErr(w, fmt.Errorf("invalid webhook secret"), http.StatusForbidden, "")
+		return
+	}
+
+	var reqBody FightRequest
+	err := json.NewDecoder(r.Body).Decode(&reqBody)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusBadRequest, "could not parse")
+		return
+	}
+	defer r.Body.Close()
+
+	err = validateFight(reqBody)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	fight := model.Fights{
+		ID:        s.idGenerator.Generate().Int64(),
+		CreatedAt: time.Now().UTC(),
+	}
+	if reqBody.ClientID != "" {
+		fight.ClientID = &reqBody.ClientID
+
+		existing, err := s.db.GetFightByClientID(ctx, reqBody.ClientID)
+		if err == nil {
+			serveRecordedFight(w, existing)
+			return
+		}
+		if !errors.Is(err, db.ErrNotFound) {
+			serveAPIErr(w, err, http.StatusInternalServerError, "unexpected error")
+			return
+		}
+	}
+
+	event, err := s.activeEvent(ctx)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "unexpected error")
+		return
+	}
+	if event != nil {
+		fight.EventID = &event.ID
+	}
+
+	outcomes := make([]model.FightOutcomes, len(reqBody.Participants))
+	for i, p := range reqBody.Participants {
+		user, err := s.getOrCreateTwitchUser(ctx, p.TwitchID)
+		if err != nil {
+			if errors.Is(err, errTwitchLookup) {
+				serveAPIErr(w, err, http.StatusInternalServerError, "unable to get user from twitch")
+				return
+			}
+			serveAPIErr(w, err, http.StatusInternalServerError, "unexpected error")
+			return
+		}
+
+		outcome := model.FightOutcomes{
+			FightID: fight.ID,
+			UserID:  user.ID,
+			Outcome: p.Outcome,
+		}
+
+		equipped, err := s.db.GetEquippedForUser(ctx, user.ID)
+		if err != nil {
+			serveAPIErr(w, err, http.StatusInternalServerError, "unexpected error")
+			return
+		}
+		if equipped != nil {
+			outcome.InstanceID = &equipped.ID
+			outcome.CollectableID = &equipped.CollectableID
+		}
+
+		outcomes[i] = outcome
+	}
+
+	created, err := s.db.CreateFight(ctx, fight, outcomes)
+	if err != nil {
+		if errors.Is(err, db.ErrFightExists) {
+			// A retry of the same report got in first
+			existing, err := s.db.GetFightByClientID(ctx, reqBody.ClientID)
+			if err != nil {
+				serveAPIErr(w, err, http.StatusInternalServerError, "could not record fight")
+				return
+			}
+			serveRecordedFight(w, existing)
+			return
+		}
+		serveAPIErr(w, err, http.StatusInternalServerError, "could not record fight")
+		return
+	}
+
+	// Fights are reported by the bot rather than a user, the first
+	// participant stands in as the actor
+	s.audit(ctx, outcomes[0].UserID, auditCreate, auditTargetFight, created.ID, nil, struct {
+		Fight    model.Fights
+		Outcomes []model.FightOutcomes
+	}{fight, outcomes})
+
+	// The fight is recorded either way, a bracket that failed to advance can
+	// be fixed up by fighting again
+	err = s.advanceBracket(ctx, created)
+	if err != nil {
+		slog.Error("advancing bracket", "fight", created.ID, "err", err)
+	}
+
+	f := FightFromDBFight(created)
+
+	serveAPIPayload(
+		w,
+		&f,
+	)
+}
+
+// serveRecordedFight answers a repeated report with the fight recorded for it.
+func serveRecordedFight(w http.ResponseWriter, fight *db.Fight) {
+	slog.Info("fight already recorded", "fight", fight.ID, "client_id", *fight.ClientID)
+
+	f := FightFromDBFight(fight)
+	serveAPIPayload(w, &f)
+}
+
+func (s *Server) getFight(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusBadRequest, "id is not numeric")
+		return
+	}
+
+	fight, err := s.db.GetFight(ctx, id)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			serveAPIErr(w, err, http.StatusNotFound, "Unknown fight")
+			return
+		}
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+
+	f := FightFromDBFight(fight)
+
+	serveAPIPayload(
+		w,
+		&f,
+	)
+}
+
+func (s *Server) getFights(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	fights, err := s.db.GetFights(ctx, db.GetFightsOptions{})
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+
+	res := make([]Fight, len(fights))
+	for i, f := range fights {
+		res[i] = FightFromDBFight(&f)
+	}
+
+	serveAPIPayload(
+		w,
+		&res,
+	)
+}
+
+func (s *Server) getUserStats(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+
+	useridstr, ok := vars["userid"]
+	if !ok {
+		serveAPIErr(w, fmt.Errorf("id required"), http.StatusBadRequest, "User ID Required")
+		return
+	}
+
+	user, err := s.getUserByUserID(ctx, ParseUserID(useridstr))
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			serveAPIErr(w, err, http.StatusNotFound, "Unknown user")
+			return
+		}
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+
+	stats, err := s.db.GetFightStats(ctx, user.ID)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+
+	serveAPIPayload(
+		w,
+		&struct {
+			User  User
+			Stats UserStats
+		}{
+			User: UserFromDBUser(user),
+			Stats: UserStats{
+				Wins:   int(stats.Wins),
+				Losses: int(stats.Losses),
+				Ties:   int(stats.Draws),
+			},
+		},
+	)
+}
+
+func (s *Server) getUserFights(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+
+	useridstr, ok := vars["userid"]
+	if !ok {
+		serveAPIErr(w, fmt.Errorf("id required"), http.StatusBadRequest, "User ID Required")
+		return
+	}
+
+	user, err := s.getUserByUserID(ctx, ParseUserID(useridstr))
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			serveAPIErr(w, err, http.StatusNotFound, "Unknown user")
+			return
+		}
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+
+	fights, err := s.db.GetFights(ctx, db.GetFightsOptions{
+		ByUser: user.ID,
+	})
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+
+	res := make([]Fight, len(fights))
+	for i, f := range fights {
+		res[i] = FightFromDBFight(&f)
+	}
+
+	serveAPIPayload(
+		w,
+		&struct {
+			User   User
+			Fights []Fight
+		}{
+			User:   UserFromDBUser(user),
+			Fights: res,
+		},
+	)
+}
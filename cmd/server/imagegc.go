@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"time"
+
+	"github.com/cconger/shindaggers/pkg/images"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// collectOrphanedImages removes uploads older than olderThan that no
+// collectable uses, along with their thumbnails. These are mostly previews
+// from submissions that were never sent. With dryRun it only reports them.
+func (s *Server) collectOrphanedImages(ctx context.Context, olderThan time.Duration, dryRun bool) error {
+	if !dryRun && s.minioClient == nil {
+		return fmt.Errorf("no image storage configured")
+	}
+
+	uploads, err := s.db.GetOrphanedImageUploads(ctx, time.Now().Add(-olderThan))
+	if err != nil {
+		return err
+	}
+
+	removed := 0
+	for _, u := range uploads {
+		log.Printf("orphaned upload %d: %s uploaded %s by user %d", u.ID, u.Imagepath, u.UploadedAt.Format(time.RFC3339), u.UserID)
+		if dryRun {
+			continue
+		}
+
+		// The row goes last so anything that fails is found again next run
+		err = s.removeImage(ctx, u.Imagepath)
+		if err != nil {
+			log.Printf("removing %s: %s", u.Imagepath, err)
+			continue
+		}
+		err = s.db.DeleteImageUpload(ctx, u.ID)
+		if err != nil {
+			log.Printf("deleting upload %d: %s", u.ID, err)
+			continue
+		}
+		removed++
+	}
+
+	if dryRun {
+		log.Printf("dry run: %d orphaned uploads older than %s would be removed", len(uploads), olderThan)
+	} else {
+		log.Printf("removed %d of %d orphaned uploads older than %s", removed, len(uploads), olderThan)
+	}
+	return nil
+}
+
+func (s *Server) removeImage(ctx context.Context, name string) error {
+	for _, n := range []string{name, images.CardName(name), images.MiniName(name)} {
+		err := s.minioClient.RemoveObject(ctx, s.bucketName, path.Join("images", n), minio.RemoveObjectOptions{})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
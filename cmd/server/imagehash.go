@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path"
+
+	"github.com/cconger/shindaggers/pkg/images"
+)
+
+// Uploads are parsed with a 32MB limit so no stored image is bigger
+const maxImageBytes = 32 << 20
+
+// hashExistingImages computes the perceptual hash of images from before
+// uploads were hashed so the approval queue can match re-uploads of them.
+// Collectables whose image was imported without an upload get an upload row
+// owned by their creator to hold the hash.
+func (s *Server) hashExistingImages(ctx context.Context) error {
+	uploads, err := s.db.GetUnhashedImageUploads(ctx)
+	if err != nil {
+		return err
+	}
+
+	hashed := 0
+	for _, u := range uploads {
+		hash, err := s.hashImage(ctx, u.Imagepath)
+		if err != nil {
+			log.Printf("hashing upload %d %s: %s", u.ID, u.Imagepath, err)
+			continue
+		}
+		err = s.db.SetImageUploadHash(ctx, u.ID, hash)
+		if err != nil {
+			log.Printf("saving hash for upload %d: %s", u.ID, err)
+			continue
+		}
+		hashed++
+	}
+	log.Printf("hashed %d of %d unhashed uploads", hashed, len(uploads))
+
+	collectables, err := s.db.GetCollectablesWithoutImageUpload(ctx)
+	if err != nil {
+		return err
+	}
+
+	hashed = 0
+	seen := map[string]bool{}
+	for _, c := range collectables {
+		if c.Imagepath == "" || seen[c.Imagepath] {
+			continue
+		}
+		seen[c.Imagepath] = true
+
+		hash, err := s.hashImage(ctx, c.Imagepath)
+		if err != nil {
+			log.Printf("hashing collectable %d %s: %s", c.ID, c.Imagepath, err)
+			continue
+		}
+		err = s.db.CreateImageUpload(ctx, s.idGenerator.Generate().Int64(), c.CreatorID, c.Imagepath, "", hash)
+		if err != nil {
+			log.Printf("saving hash for collectable %d: %s", c.ID, err)
+			continue
+		}
+		hashed++
+	}
+	log.Printf("hashed %d of %d imported collectable images", hashed, len(seen))
+
+	return nil
+}
+
+func (s *Server) hashImage(ctx context.Context, name string) (int64, error) {
+	data, err := s.readImage(ctx, name)
+	if err != nil {
+		return 0, err
+	}
+	processed, err := images.Process(data)
+	if err != nil {
+		return 0, err
+	}
+	return int64(processed.Hash), nil
+}
+
+// readImage reads an uploaded image from disk when images are stored there
+// and from the CDN otherwise.
+func (s *Server) readImage(ctx context.Context, name string) ([]byte, error) {
+	if fs, ok := s.minioClient.(*fsBlobClient); ok {
+		return fs.readObject(s.bucketName, path.Join("images", name))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, imageBaseURL+name, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s: %s", name, resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxImageBytes))
+}
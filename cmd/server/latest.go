@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cconger/shindaggers/pkg/db"
+
+	"github.com/bwmarrin/snowflake"
+)
+
+const (
+	// Subscribers that fall this far behind are dropped, they can reconnect and
+	// resume with Last-Event-ID.
+	latestSubscriberBuffer = 32
+	latestKeepAlive        = 30 * time.Second
+
+	// Clients that missed more than latestBackfillMax pulls are told to
+	// refetch rather than being sent all of them.
+	latestBackfillPage = 100
+	latestBackfillMax  = 1000
+)
+
+// latestBroker fans newly issued collectables out to every connected
+// /api/latest/stream client in this process.
+type latestBroker struct {
+	mu     sync.Mutex
+	subs   map[chan IssuedCollectable]struct{}
+	closed bool
+}
+
+func newLatestBroker() *latestBroker {
+	return &latestBroker{
+		subs: make(map[chan IssuedCollectable]struct{}),
+	}
+}
+
+// Subscribe registers a new listener. The returned channel is closed when the
+// listener is unsubscribed, falls too far behind, or the broker shuts down.
+func (b *latestBroker) Subscribe() chan IssuedCollectable {
+	ch := make(chan IssuedCollectable, latestSubscriberBuffer)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		close(ch)
+		return ch
+	}
+	b.subs[ch] = struct{}{}
+	return ch
+}
+
+func (b *latestBroker) Unsubscribe(ch chan IssuedCollectable) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subs[ch]; ok {
+		delete(b.subs, ch)
+		close(ch)
+	}
+}
+
+func (b *latestBroker) Publish(c IssuedCollectable) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subs {
+		select {
+		case ch <- c:
+		default:
+			slog.Warn("dropping slow latest subscriber")
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// Close disconnects all subscribers so that open streams don't hold up
+// server shutdown.
+func (b *latestBroker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for ch := range b.subs {
+		delete(b.subs, ch)
+		close(ch)
+	}
+}
+
+func (s *Server) getLatestStream(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		serveAPIErr(w, fmt.Errorf("response writer does not support flushing"), http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+
+	var lastID int64
+	if lastEventID := r.Header.Get("Last-Event-ID"); lastEventID != "" {
+		var err error
+		lastID, err = strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil {
+			serveAPIErr(w, err, http.StatusBadRequest, "Last-Event-ID not encoded properly")
+			return
+		}
+	}
+
+	// Subscribe before backfilling so nothing issued in between is missed
+	events := s.latest.Subscribe()
+	defer s.latest.Unsubscribe(events)
+
+	var backfill []db.CollectableInstance
+	missedTooMany := false
+	if lastID != 0 {
+		var err error
+		backfill, missedTooMany, err = s.latestBackfill(ctx, lastID)
+		if err != nil {
+			serveAPIErr(w, err, http.StatusInternalServerError, "Internal Error")
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	send := func(c IssuedCollectable) error {
+		b, err := json.Marshal(c)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "id: %s\nevent: issued\ndata: %s\n\n", c.InstanceID, b)
+		if err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+
+	if missedTooMany {
+		// Skip ahead to the newest pull, the client refetches what it missed
+		for i := range backfill {
+			if backfill[i].ID > lastID {
+				lastID = backfill[i].ID
+			}
+		}
+		backfill = nil
+
+		_, err := fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+		if err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+
+	for i := range backfill {
+		if backfill[i].ID <= lastID {
+			continue
+		}
+		err := send(IssuedCollectableFromCollectableInstance(&backfill[i]))
+		if err != nil {
+			return
+		}
+		lastID = backfill[i].ID
+	}
+
+	keepAlive := time.NewTicker(latestKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-keepAlive.C:
+			_, err := fmt.Fprint(w, ": keep-alive\n\n")
+			if err != nil {
+				return
+			}
+			flusher.Flush()
+		case c, ok := <-events:
+			if !ok {
+				return
+			}
+			id, err := strconv.ParseInt(c.InstanceID, 10, 64)
+			if err == nil && id <= lastID {
+				// Already delivered by the backfill
+				continue
+			}
+			err = send(c)
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+// latestBackfill pages through everything issued since lastID, returned
+// oldest first. If more than latestBackfillMax were issued it stops early and
+// reports that the client missed too many to catch up on.
+func (s *Server) latestBackfill(ctx context.Context, lastID int64) ([]db.CollectableInstance, bool, error) {
+	options := db.GetLatestIssuesOptions{
+		After: time.UnixMilli(snowflake.ParseInt64(lastID).Time() - 1),
+	}
+	options.Limit = latestBackfillPage
+
+	var backfill []db.CollectableInstance
+	for {
+		page, err := s.db.GetLatestIssues(ctx, options)
+		if err != nil {
+			return nil, false, err
+		}
+		backfill = append(backfill, page...)
+
+		if int64(len(page)) < options.Limit {
+			break
+		}
+		if len(backfill) >= latestBackfillMax {
+			return backfill, true, nil
+		}
+		last := page[len(page)-1]
+		options.Cursor = &db.InstanceCursor{
+			CreatedAt: last.CreatedAt,
+			ID:        last.ID,
+		}
+	}
+
+	sort.Slice(backfill, func(i, j int) bool {
+		return backfill[i].ID < backfill[j].ID
+	})
+	return backfill, false, nil
+}
@@ -5,20 +5,22 @@ import (
 	"crypto/rand"
 	"crypto/sha256"
 	"database/sql"
+	"encoding/base64"
 	"flag"
-	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/cconger/shindaggers/pkg/db"
+	"github.com/cconger/shindaggers/pkg/discord"
 	"github.com/cconger/shindaggers/pkg/twitch"
 
 	"github.com/bwmarrin/snowflake"
@@ -34,12 +36,7 @@ import (
 
 type blobClient interface {
 	PutObject(context.Context, string, string, io.Reader, int64, minio.PutObjectOptions) (minio.UploadInfo, error)
-}
-
-type mockBlobClient struct{}
-
-func (m *mockBlobClient) PutObject(ctx context.Context, bucket string, file string, contents io.Reader, size int64, options minio.PutObjectOptions) (minio.UploadInfo, error) {
-	return minio.UploadInfo{}, fmt.Errorf("cannot upload files in dev mode")
+	RemoveObject(context.Context, string, string, minio.RemoveObjectOptions) error
 }
 
 type UserID struct {
@@ -116,6 +113,10 @@ func (id *UserID) String() string {
 func main() {
 	devMode := flag.Bool("dev", false, "enable dev mode which reloads the templates at runtime to allow rapid iteration")
 	isolated := flag.Bool("nodb", false, "enable the application to use mock intefaces to dependencies, allows you to develop without having access to other services")
+	gcImages := flag.Bool("gc-images", false, "remove uploaded images that no collectable uses, then exit")
+	gcOlderThan := flag.Duration("gc-older-than", 7*24*time.Hour, "with -gc-images, only remove uploads older than this")
+	dryRun := flag.Bool("dry-run", false, "with -gc-images, report what would be removed without removing it")
+	hashImages := flag.Bool("hash-images", false, "compute perceptual hashes for images from before uploads were hashed, then exit")
 	flag.Parse()
 
 	var err error
@@ -129,6 +130,13 @@ func main() {
 	defer otelShutdown()
 
 	discordWebhook := os.Getenv("DISCORD_WEBHOOK")
+	notifyRarity := os.Getenv("DISCORD_NOTIFY_RARITY")
+	if notifyRarity == "" {
+		notifyRarity = defaultNotifyRarity
+	}
+	if !slices.Contains(rarities, notifyRarity) {
+		log.Fatalf("DISCORD_NOTIFY_RARITY must be one of %v", rarities)
+	}
 	clientID := os.Getenv("TWITCH_CLIENT_ID")
 	clientSecret := os.Getenv("TWITCH_SECRET")
 	webhookSecret := os.Getenv("WEBHOOK_SECRET")
@@ -137,36 +145,101 @@ func main() {
 		baseURL = "http://localhost:8080"
 	}
 
+	// Signs the cookie that protects the twitch login, a random key works but
+	// logins in progress won't survive a restart or span multiple instances.
+	stateKey := []byte(os.Getenv("SESSION_SECRET"))
+	if len(stateKey) == 0 {
+		log.Println("SESSION_SECRET not set, using a random key for oauth state")
+		stateKey = make([]byte, 32)
+		_, err = rand.Read(stateKey)
+		if err != nil {
+			log.Fatalf("generating oauth state key: %s", err)
+		}
+	}
+
+	// Channel point redemptions are delivered over eventsub when configured
+	eventSubSecret := os.Getenv("EVENTSUB_SECRET")
+	broadcasterID := os.Getenv("TWITCH_BROADCASTER_ID")
+	rewardID := os.Getenv("TWITCH_REWARD_ID")
+
+	if broadcasterID == "" {
+		log.Println("TWITCH_BROADCASTER_ID not set, pulls will not be tagged as subscriber pulls")
+	}
+
+	// Override the twitch endpoints to run against a local stand-in
+	twitchAuthURL := os.Getenv("TWITCH_AUTH_URL")
+	twitchAPIURL := os.Getenv("TWITCH_API_URL")
+
+	newTwitchClient := func() (*twitch.Client, error) {
+		c, err := twitch.NewClient(
+			clientID,
+			clientSecret,
+			&http.Client{
+				Transport: otelhttp.NewTransport(http.DefaultTransport),
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+		c.SetEndpoints(twitchAuthURL, twitchAPIURL)
+		return c, nil
+	}
+
+	// Images are stored on disk under IMAGE_DIR instead of object storage when
+	// it is set, -nodb always stores them on disk
+	imageDir := os.Getenv("IMAGE_DIR")
+
 	var blobClient blobClient
 	var twitchClient twitch.TwitchClient
-	var newDBClient db.PostgresDB
+	var newDBClient db.Store
 
 	if *isolated {
-		blobClient = &mockBlobClient{}
+		if imageDir == "" {
+			imageDir, err = os.MkdirTemp("", "shindaggers-images")
+			if err != nil {
+				log.Fatalf("creating image dir: %s", err)
+			}
+		}
+
 		twitchClient = &twitch.MockClient{}
-		// TODO: Better mockDB
+		if twitchAPIURL != "" {
+			c, err := newTwitchClient()
+			if err != nil {
+				log.Fatalf("failed to create twitchclient: %s", err)
+			}
+			log.Printf("Using twitch stand-in at %s", twitchAPIURL)
+			twitchClient = c
+		}
+
+		adminToken, err := createAuthToken()
+		if err != nil {
+			log.Fatalf("creating dev admin token: %s", err)
+		}
+		memDB := db.NewMemoryDB()
+		err = memDB.Seed(hashAuthToken(adminToken))
+		if err != nil {
+			log.Fatalf("seeding memory db: %s", err)
+		}
+		log.Printf("Using in-memory db, admin token: %s", base64.URLEncoding.EncodeToString(adminToken))
+		newDBClient = memDB
 	} else {
 		// Credentials to be able to upload images
 		r2AccessKey := os.Getenv("CLOUDFLARE_SECRET")
 		r2KeyID := os.Getenv("CLOUDFLARE_CLIENT_ID")
 		storageEndpoint := os.Getenv("STORAGE_ENDPOINT")
 
-		blobClient, err = minio.New(storageEndpoint, &minio.Options{
-			Creds:  credentials.NewStaticV4(r2KeyID, r2AccessKey, ""),
-			Secure: true,
-		})
-		if err != nil {
-			log.Printf("Could not initialize minioClient, image uploading will not work: %s", err)
-			blobClient = nil
+		if imageDir == "" {
+			blobClient, err = minio.New(storageEndpoint, &minio.Options{
+				Creds:  credentials.NewStaticV4(r2KeyID, r2AccessKey, ""),
+				Secure: true,
+			})
+			if err != nil {
+				log.Printf("Could not initialize minioClient, image uploading will not work: %s", err)
+				blobClient = nil
+			}
 		}
 
-		twitchClient, err = twitch.NewClient(
-			clientID,
-			clientSecret,
-			&http.Client{
-				Transport: otelhttp.NewTransport(http.DefaultTransport),
-			},
-		)
+		twitchClient, err = newTwitchClient()
 		if err != nil {
 			log.Fatalf("failed to create twitchclient: %s", err)
 		}
@@ -176,11 +249,41 @@ func main() {
 			log.Fatal(err)
 		}
 
-		newDBClient = db.PostgresDB{
+		newDBClient = &db.PostgresDB{
 			DB: pdb,
 		}
 	}
 
+	var fsBlobs *fsBlobClient
+	if imageDir != "" {
+		log.Printf("Storing images in %s", imageDir)
+		fsBlobs = &fsBlobClient{Root: imageDir}
+		blobClient = fsBlobs
+	}
+
+	// Images are served from the CDN in front of object storage, or by us
+	// when they are on disk
+	imageBaseURL = os.Getenv("IMAGE_BASE_URL")
+	if imageBaseURL == "" {
+		imageBaseURL = defaultImageBaseURL
+		if fsBlobs != nil {
+			imageBaseURL = baseURL + "/images/"
+		}
+	}
+	if !strings.HasSuffix(imageBaseURL, "/") {
+		imageBaseURL += "/"
+	}
+
+	// Pulls, submissions and approvals are announced when a webhook is set,
+	// point it at a local server to see what would be posted
+	var discordClient *discord.Webhook
+	if discordWebhook != "" {
+		discordClient = discord.NewWebhook(discordWebhook, &http.Client{
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
+			Timeout:   10 * time.Second,
+		})
+	}
+
 	alloc_id := os.Getenv("FLY_ALLOC_ID")
 	if alloc_id == "" {
 		alloc_id = "3ff" // Dev Node
@@ -205,58 +308,156 @@ func main() {
 		minioClient:    blobClient,
 		bucketName:     "sd-images",
 		idGenerator:    node,
-		discordWebhook: discordWebhook,
+		discord:        discordClient,
+		notifyRarity:   notifyRarity,
+		latest:         newLatestBroker(),
+		subscribers:    newSubscriberCache(subscriberCacheTTL),
+
+		eventSubSecret:   eventSubSecret,
+		broadcasterID:    broadcasterID,
+		eventSubRewardID: rewardID,
+
+		stateKey: stateKey,
 
 		baseURL: baseURL,
 	}
 
+	if *gcImages {
+		err = s.collectOrphanedImages(context.Background(), *gcOlderThan, *dryRun)
+		if err != nil {
+			log.Fatalf("collecting orphaned images: %s", err)
+		}
+		return
+	}
+
+	if *hashImages {
+		err = s.hashExistingImages(context.Background())
+		if err != nil {
+			log.Fatalf("hashing images: %s", err)
+		}
+		return
+	}
+
 	r := mux.NewRouter()
 	r.Use(otelmux.Middleware("shindaggers"))
+	r.Use(requestIDMiddleware)
 
 	r.HandleFunc("/oauth/login", s.LoginHandler).Methods(http.MethodGet)
 	r.HandleFunc("/oauth/handler", s.LoginResponseHandler).Methods(http.MethodGet)
 
 	r.HandleFunc("/api/catalog", s.getCollection).Methods(http.MethodGet)
+	r.HandleFunc("/api/collections", s.getCollections).Methods(http.MethodGet)
+	r.HandleFunc("/api/collection/{id:[0-9]+}", s.getCollectionCatalog).Methods(http.MethodGet)
 	r.HandleFunc("/api/collectable/{id:[0-9]+}", s.getCollectable).Methods(http.MethodGet)
 	r.HandleFunc("/api/collectable", s.createCollectable).Methods(http.MethodPost)
 	r.HandleFunc("/api/issued/{id:[0-9]+}", s.getIssuedCollectable).Methods(http.MethodGet)
 
 	r.HandleFunc("/api/latest", s.getLatest).Methods(http.MethodGet)
+	r.HandleFunc("/api/latest/stream", s.getLatestStream).Methods(http.MethodGet)
 	r.HandleFunc("/api/user/me", s.getLoggedInUser).Methods(http.MethodGet)
+	r.HandleFunc("/api/logout", s.logoutHandler).Methods(http.MethodPost)
+	r.HandleFunc("/api/logout/all", s.logoutEverywhereHandler).Methods(http.MethodPost)
+	r.HandleFunc("/api/user/me/trades", s.getMyTradeOffers).Methods(http.MethodGet)
+	r.HandleFunc("/api/user/me/submissions", s.getMySubmissions).Methods(http.MethodGet)
+	r.HandleFunc("/api/user/me/submissions/{id:[0-9]+}", s.resubmitCollectable).Methods(http.MethodPut)
 
 	r.HandleFunc("/api/user/{userid}", s.getUser).Methods(http.MethodGet)
 	r.HandleFunc("/api/user/{userid}/equipped", s.getEquippedForUser).Methods(http.MethodGet)
 	r.HandleFunc("/api/user/{userid}/collection", s.getUserCollection).Methods(http.MethodGet)
+	r.HandleFunc("/api/user/{userid}/stats", s.getUserStats).Methods(http.MethodGet)
+	r.HandleFunc("/api/user/{userid}/fights", s.getUserFights).Methods(http.MethodGet)
+
+	r.HandleFunc("/api/fights", s.getFights).Methods(http.MethodGet)
+	r.HandleFunc("/api/fight/{id:[0-9]+}", s.getFight).Methods(http.MethodGet)
+	r.HandleFunc("/api/events", s.getEvents).Methods(http.MethodGet)
+	r.HandleFunc("/api/event/{slug}", s.getEvent).Methods(http.MethodGet)
 
 	// Search Users
 	r.HandleFunc("/api/users", s.getUsers).Methods(http.MethodGet)
 
 	r.HandleFunc("/api/randompull/{token}", s.RandomPullHandler).Methods(http.MethodPost)
+	r.HandleFunc("/api/fight/{token}", s.FightHandler).Methods(http.MethodPost)
+	r.HandleFunc("/api/twitch/eventsub", s.EventSubHandler).Methods(http.MethodPost)
 	r.HandleFunc("/api/user/equip", s.EquipHandler).Methods(http.MethodPost)
 
-	// ADMIN APIs
-	r.HandleFunc("/api/admin/collectables", s.adminListCollectables).Methods(http.MethodGet)
+	// Crafting
+	r.HandleFunc("/api/craft/recipe", s.getCraftRecipe).Methods(http.MethodGet)
+	r.HandleFunc("/api/craft/materials", s.getMaterials).Methods(http.MethodGet)
+	r.HandleFunc("/api/craft/salvage", s.salvageHandler).Methods(http.MethodPost)
+	r.HandleFunc("/api/craft/forge", s.forgeHandler).Methods(http.MethodPost)
+
+	// Trading
+	r.HandleFunc("/api/trade", s.createTradeOffer).Methods(http.MethodPost)
+	r.HandleFunc("/api/trade/{id:[0-9]+}", s.getTradeOffer).Methods(http.MethodGet)
+	r.HandleFunc("/api/trade/{id:[0-9]+}/accept", s.acceptTradeOffer).Methods(http.MethodPost)
+	r.HandleFunc("/api/trade/{id:[0-9]+}/decline", s.declineTradeOffer).Methods(http.MethodPost)
+	r.HandleFunc("/api/trade/{id:[0-9]+}/cancel", s.cancelTradeOffer).Methods(http.MethodPost)
+
+	// ADMIN APIs, every route needs a role and admins can use all of them
+	admin := r.PathPrefix("/api/admin").Subrouter()
+	admin.Use(s.requireRole(db.Roles...))
+
+	admin.Handle("/collectables", s.withRole(s.adminListCollectables, db.RoleModerator, db.RoleCurator)).Methods(http.MethodGet)
 	// Create Collectable
-	r.HandleFunc("/api/admin/collectable", s.adminCreateCollectable).Methods(http.MethodPost)
+	admin.Handle("/collectable", s.withRole(s.adminCreateCollectable, db.RoleCurator)).Methods(http.MethodPost)
 	// Get Collectable
-	r.HandleFunc("/api/admin/collectable/{id}", s.adminGetCollectable).Methods(http.MethodGet)
+	admin.Handle("/collectable/{id}", s.withRole(s.adminGetCollectable, db.RoleModerator, db.RoleCurator)).Methods(http.MethodGet)
 	// Modify Collectable
-	r.HandleFunc("/api/admin/collectable/{id}", s.adminUpdateCollectable).Methods(http.MethodPut)
+	admin.Handle("/collectable/{id}", s.withRole(s.adminUpdateCollectable, db.RoleCurator)).Methods(http.MethodPut)
 	// Modify Collectable
-	r.HandleFunc("/api/admin/collectable/{id}/approve", s.adminApproveCollectable).Methods(http.MethodPost)
+	admin.Handle("/collectable/{id}/approve", s.withRole(s.adminApproveCollectable, db.RoleModerator)).Methods(http.MethodPost)
+	// Reject Collectable
+	admin.Handle("/collectable/{id}/reject", s.withRole(s.adminRejectCollectable, db.RoleModerator)).Methods(http.MethodPost)
+	// Send Collectable back to its creator
+	admin.Handle("/collectable/{id}/request-changes", s.withRole(s.adminRequestChanges, db.RoleModerator)).Methods(http.MethodPost)
 	// Delete Collectable
-	r.HandleFunc("/api/admin/collectable/{id}", s.adminDeleteCollectable).Methods(http.MethodDelete)
+	admin.Handle("/collectable/{id}", s.withRole(s.adminDeleteCollectable, db.RoleModerator, db.RoleCurator)).Methods(http.MethodDelete)
+
+	// Collections
+	admin.Handle("/collections", s.withRole(s.adminListCollections, db.RoleCurator)).Methods(http.MethodGet)
+	admin.Handle("/collection", s.withRole(s.adminCreateCollection, db.RoleCurator)).Methods(http.MethodPost)
+	admin.Handle("/collection/{id}", s.withRole(s.adminUpdateCollection, db.RoleCurator)).Methods(http.MethodPut)
+	// Retire Collection
+	admin.Handle("/collection/{id}", s.withRole(s.adminRetireCollection, db.RoleCurator)).Methods(http.MethodDelete)
+
+	// Editions
+	admin.Handle("/editions", s.withRole(s.adminListEditions, db.RoleCurator)).Methods(http.MethodGet)
+	admin.Handle("/edition", s.withRole(s.adminCreateEdition, db.RoleCurator)).Methods(http.MethodPost)
+	admin.Handle("/edition/{id}/activate", s.withRole(s.adminActivateEdition, db.RoleCurator)).Methods(http.MethodPost)
+	admin.Handle("/edition/{id}/retire", s.withRole(s.adminRetireEdition, db.RoleCurator)).Methods(http.MethodPost)
+
+	// Events
+	admin.Handle("/event", s.withRole(s.adminCreateEvent, db.RoleFightJudge)).Methods(http.MethodPost)
+	admin.Handle("/event/{id}/close", s.withRole(s.adminCloseEvent, db.RoleFightJudge)).Methods(http.MethodPost)
+	admin.Handle("/event/{id}/bracket", s.withRole(s.adminCreateBracket, db.RoleFightJudge)).Methods(http.MethodPost)
+
+	// Sessions
+	admin.Handle("/user/{userid}/tokens", s.withRole(s.adminRevokeUserTokens, db.RoleAdmin)).Methods(http.MethodDelete)
+
+	// Roles
+	admin.Handle("/user/{userid}/roles", s.withRole(s.adminGetUserRoles, db.RoleAdmin)).Methods(http.MethodGet)
+	admin.Handle("/user/{userid}/roles/{role}", s.withRole(s.adminGrantRole, db.RoleAdmin)).Methods(http.MethodPut)
+	admin.Handle("/user/{userid}/roles/{role}", s.withRole(s.adminRevokeRole, db.RoleAdmin)).Methods(http.MethodDelete)
 
 	// Issue IssuedCollectable to User
-	r.HandleFunc("/api/admin/issue", s.adminIssueCollectable).Methods(http.MethodPost)
+	admin.Handle("/issue", s.withRole(s.adminIssueCollectable, db.RoleAdmin)).Methods(http.MethodPost)
 
 	// Revoke IssuedCollectable
-	r.HandleFunc("/api/admin/issued/{id}", s.adminRevokeIssuedCollectable).Methods(http.MethodDelete)
+	admin.Handle("/issued/{id}", s.withRole(s.adminRevokeIssuedCollectable, db.RoleAdmin)).Methods(http.MethodDelete)
+	// Restore a revoked IssuedCollectable
+	admin.Handle("/issued/{id}/restore", s.withRole(s.adminRestoreIssuedCollectable, db.RoleAdmin)).Methods(http.MethodPost)
 
 	// IssueConfig changes manages the weights of random pulls
-	r.HandleFunc("/api/admin/issueconfig", s.adminGetIssueConfig).Methods(http.MethodGet)
+	admin.Handle("/issueconfig", s.withRole(s.adminGetIssueConfig, db.RoleCurator)).Methods(http.MethodGet)
 	// ChangeIssueConfig
-	r.HandleFunc("/api/admin/issueconfig", s.adminUpdateIssueConfig).Methods(http.MethodPut)
+	admin.Handle("/issueconfig", s.withRole(s.adminUpdateIssueConfig, db.RoleCurator)).Methods(http.MethodPut)
+
+	// Crafting recipes
+	admin.Handle("/craft/recipe", s.withRole(s.adminGetCraftRecipe, db.RoleCurator)).Methods(http.MethodGet)
+	admin.Handle("/craft/recipe", s.withRole(s.adminUpdateCraftRecipe, db.RoleCurator)).Methods(http.MethodPut)
+
+	// Audit log
+	admin.Handle("/audit", s.withRole(s.adminGetAuditLog, db.RoleAdmin)).Methods(http.MethodGet)
 
 	// Image Upload
 	r.HandleFunc("/api/image", s.ImageUpload).Methods(http.MethodPost)
@@ -264,6 +465,9 @@ func main() {
 	// Overlay is a mini SPA for OBS
 	r.HandleFunc("/overlay/{id}", s.overlayHandler).Methods(http.MethodGet)
 	r.PathPrefix("/assets").HandlerFunc(s.assetHandler)
+	if fsBlobs != nil {
+		r.PathPrefix("/images/").Handler(fsBlobs.imagesHandler(s.bucketName)).Methods(http.MethodGet, http.MethodHead)
+	}
 	r.PathPrefix("/").HandlerFunc(s.spaHandler)
 
 	http.Handle("/", r)
@@ -275,6 +479,8 @@ func main() {
 	srv := &http.Server{
 		Addr: ":8080",
 	}
+	srv.RegisterOnShutdown(s.latest.Close)
+	srv.RegisterOnShutdown(s.discord.Close)
 
 	go func() {
 		log.Println("starting webserver")
@@ -283,6 +489,17 @@ func main() {
 		}
 	}()
 
+	if eventSubSecret != "" && broadcasterID != "" {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			err := s.subscribeRedemptions(ctx)
+			if err != nil {
+				log.Printf("Unable to subscribe to channel point redemptions: %s", err)
+			}
+		}()
+	}
+
 	<-interrupt
 	log.Println("Interrupt signal recieved. Shutting down...")
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/cconger/shindaggers/pkg/db"
+	"github.com/cconger/shindaggers/pkg/discord"
+)
+
+// Pulls below this rarity aren't announced unless DISCORD_NOTIFY_RARITY says
+// otherwise.
+const defaultNotifyRarity = RaritySuperRare
+
+var rarityColors = map[string]int{
+	RarityCommon:    0x9e9e9e,
+	RarityUncommon:  0x4caf50,
+	RarityRare:      0x2196f3,
+	RaritySuperRare: 0x9c27b0,
+	RarityUltraRare: 0xff9800,
+}
+
+func collectableEmbed(c Collectable, title, link string) discord.Embed {
+	now := time.Now()
+	return discord.Embed{
+		Title:       title,
+		Description: c.Name,
+		URL:         link,
+		Color:       rarityColors[c.Rarity],
+		Image:       &discord.EmbedImage{URL: c.ImageURL},
+		Fields: []discord.EmbedField{
+			{Name: "Rarity", Value: c.Rarity, Inline: true},
+			{Name: "Creator", Value: c.Author.Name, Inline: true},
+		},
+		Timestamp: &now,
+	}
+}
+
+// notifySubmission lets reviewers know there is something in the queue.
+func (s *Server) notifySubmission(c *db.Collectable) {
+	embed := collectableEmbed(CollectableFromDBCollectable(c), "Submission awaiting review", fmt.Sprintf("%s/admin/knife/%d", s.baseURL, c.ID))
+	s.discord.Send(discord.Message{Embeds: []discord.Embed{embed}})
+}
+
+func (s *Server) notifyApproval(c *db.Collectable) {
+	embed := collectableEmbed(CollectableFromDBCollectable(c), "New knife approved", fmt.Sprintf("%s/catalog/%d", s.baseURL, c.ID))
+	s.discord.Send(discord.Message{Embeds: []discord.Embed{embed}})
+}
+
+// notifyPull announces pulls at or above the configured rarity.
+func (s *Server) notifyPull(c IssuedCollectable) {
+	if slices.Index(rarities, c.Rarity) < slices.Index(rarities, s.notifyRarity) {
+		return
+	}
+
+	title := fmt.Sprintf("%s knife pulled by %s", c.Rarity, c.Owner.Name)
+	embed := collectableEmbed(c.Collectable, title, fmt.Sprintf("%s/knife/%s", s.baseURL, c.InstanceID))
+	embed.Fields = append(embed.Fields, discord.EmbedField{Name: "Owner", Value: c.Owner.Name, Inline: true})
+	// Discord rejects embeds with empty field values
+	if c.Edition != "" {
+		embed.Fields = append(embed.Fields, discord.EmbedField{Name: "Edition", Value: c.Edition, Inline: true})
+	}
+	if c.Verified {
+		embed.Fields = append(embed.Fields, discord.EmbedField{Name: "Verified", Value: "Yes", Inline: true})
+	}
+	s.discord.Send(discord.Message{Embeds: []discord.Embed{embed}})
+}
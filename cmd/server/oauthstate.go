@@ -0,0 +1,134 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	oauthStateCookie = "sd_oauth_state"
+	// Long enough to get through the twitch consent screen
+	oauthStateLifetime = 10 * time.Minute
+)
+
+var errInvalidOAuthState = errors.New("invalid oauth state")
+
+// oauthState is carried through the twitch login in a signed cookie, the
+// nonce is also sent to twitch as the state parameter and must come back.
+type oauthState struct {
+	Nonce     string
+	ReturnTo  string `json:",omitempty"`
+	ExpiresAt int64
+}
+
+// oauthRedirectURI is where twitch sends users back to, it must match
+// between the authorize request and the token exchange.
+func (s *Server) oauthRedirectURI() string {
+	return s.baseURL + "/oauth/handler"
+}
+
+// safeReturnPath only allows paths on this site so the login can't be used
+// as an open redirect.
+func safeReturnPath(p string) string {
+	if !strings.HasPrefix(p, "/") || strings.HasPrefix(p, "//") || strings.HasPrefix(p, "/\\") {
+		return ""
+	}
+	return p
+}
+
+func (s *Server) signOAuthState(payload []byte) []byte {
+	mac := hmac.New(sha256.New, s.stateKey)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// setOAuthState starts a login, returning the nonce to send to twitch.
+func (s *Server) setOAuthState(w http.ResponseWriter, returnTo string) (string, error) {
+	nonce := make([]byte, 32)
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return "", err
+	}
+
+	state := oauthState{
+		Nonce:     base64.RawURLEncoding.EncodeToString(nonce),
+		ReturnTo:  safeReturnPath(returnTo),
+		ExpiresAt: time.Now().Add(oauthStateLifetime).Unix(),
+	}
+
+	payload, err := json.Marshal(state)
+	if err != nil {
+		return "", err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(s.signOAuthState(payload)),
+		Path:     "/oauth",
+		MaxAge:   int(oauthStateLifetime.Seconds()),
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(s.baseURL, "https://"),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return state.Nonce, nil
+}
+
+// checkOAuthState verifies the state twitch returned against the cookie set
+// by setOAuthState. The cookie is cleared either way so it can't be reused.
+func (s *Server) checkOAuthState(w http.ResponseWriter, r *http.Request) (*oauthState, error) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    "",
+		Path:     "/oauth",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(s.baseURL, "https://"),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	c, err := r.Cookie(oauthStateCookie)
+	if err != nil {
+		return nil, errInvalidOAuthState
+	}
+
+	encoded, sig, ok := strings.Cut(c.Value, ".")
+	if !ok {
+		return nil, errInvalidOAuthState
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errInvalidOAuthState
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return nil, errInvalidOAuthState
+	}
+	if !hmac.Equal(mac, s.signOAuthState(payload)) {
+		return nil, errInvalidOAuthState
+	}
+
+	var state oauthState
+	err = json.Unmarshal(payload, &state)
+	if err != nil {
+		return nil, errInvalidOAuthState
+	}
+	if time.Now().Unix() > state.ExpiresAt {
+		return nil, errInvalidOAuthState
+	}
+
+	returned := r.URL.Query().Get("state")
+	if returned == "" || subtle.ConstantTimeCompare([]byte(returned), []byte(state.Nonce)) != 1 {
+		return nil, errInvalidOAuthState
+	}
+
+	return &state, nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cconger/shindaggers/pkg/db"
+)
+
+var errBadCursor = errors.New("invalid cursor")
+
+// encodeCursor makes the opaque cursor clients pass back to get the page
+// after i. It is only meant to be round tripped so the format can change.
+func encodeCursor(i *db.CollectableInstance) string {
+	raw := fmt.Sprintf("%d:%d", i.CreatedAt.UnixNano(), i.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(cursor string) (*db.InstanceCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errBadCursor
+	}
+	ts, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return nil, errBadCursor
+	}
+	nanos, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return nil, errBadCursor
+	}
+	instanceID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil, errBadCursor
+	}
+	return &db.InstanceCursor{
+		CreatedAt: time.Unix(0, nanos).UTC(),
+		ID:        instanceID,
+	}, nil
+}
+
+// parseInstanceFilter reads the filters and paging shared by endpoints that
+// list issued collectables. The error is suitable to show the client.
+func parseInstanceFilter(q url.Values, defaultLimit, maxLimit int64) (db.InstanceFilter, error) {
+	filter := db.InstanceFilter{
+		Limit: defaultLimit,
+	}
+
+	if rarity := q.Get("rarity"); rarity != "" {
+		if !slices.Contains(rarities, rarity) {
+			return filter, fmt.Errorf("rarity is unknown")
+		}
+		filter.Rarity = rarity
+	}
+
+	if edition := q.Get("edition"); edition != "" {
+		editionID, err := strconv.ParseInt(edition, 10, 64)
+		if err != nil {
+			return filter, fmt.Errorf("edition is not numeric")
+		}
+		filter.ByEdition = editionID
+	}
+
+	for name, dest := range map[string]**bool{
+		"verified":   &filter.Verified,
+		"subscriber": &filter.Subscriber,
+	} {
+		v := q.Get(name)
+		if v == "" {
+			continue
+		}
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return filter, fmt.Errorf("%s must be true or false", name)
+		}
+		*dest = &b
+	}
+
+	if cursor := q.Get("cursor"); cursor != "" {
+		c, err := decodeCursor(cursor)
+		if err != nil {
+			return filter, fmt.Errorf("cursor is invalid")
+		}
+		filter.Cursor = c
+	}
+
+	if limit := q.Get("limit"); limit != "" {
+		l, err := strconv.ParseInt(limit, 10, 64)
+		if err != nil || l < 1 {
+			return filter, fmt.Errorf("limit must be a positive number")
+		}
+		if l > maxLimit {
+			l = maxLimit
+		}
+		filter.Limit = l
+	}
+
+	return filter, nil
+}
+
+// nextPage trims the extra instance fetched past the limit and returns the
+// cursor for the following page, empty when this is the last one. Callers
+// ask the db for limit+1 instances so the last page doesn't need a request
+// of its own to find out it is empty.
+func nextPage(instances []db.CollectableInstance, limit int64) ([]db.CollectableInstance, string) {
+	if int64(len(instances)) <= limit {
+		return instances, ""
+	}
+	instances = instances[:limit]
+	return instances, encodeCursor(&instances[limit-1])
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/cconger/shindaggers/pkg/db"
+
+	"github.com/gorilla/mux"
+)
+
+var errMissingRole = fmt.Errorf("missing required role")
+
+type staffContextKey struct{}
+
+// staff is the user behind an /api/admin request along with their roles.
+type staff struct {
+	User  *db.User
+	Roles []string
+}
+
+// Has reports whether the user holds any of roles. Admins hold every role.
+func (st *staff) Has(roles ...string) bool {
+	for _, r := range st.Roles {
+		if r == db.RoleAdmin || slices.Contains(roles, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// staffUser returns the user that requireRole let through.
+func staffUser(ctx context.Context) *db.User {
+	st, ok := ctx.Value(staffContextKey{}).(*staff)
+	if !ok {
+		return nil
+	}
+	return st.User
+}
+
+// requireRole only lets requests through from users that hold one of roles.
+// The first use looks up the user and stores them on the request context, so
+// it can be layered on a subrouter and again on the routes within it.
+func (s *Server) requireRole(roles ...string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			st, ok := ctx.Value(staffContextKey{}).(*staff)
+			if !ok {
+				u, err := s.getAuthUser(ctx, r)
+				if err != nil {
+					serveAPIErr(w, err, http.StatusForbidden, "could not identify user")
+					return
+				}
+
+				userRoles, err := s.db.GetUserRoles(ctx, u.ID)
+				if err != nil {
+					serveAPIErr(w, err, http.StatusInternalServerError, "")
+					return
+				}
+
+				st = &staff{User: u, Roles: userRoles}
+				ctx = context.WithValue(ctx, staffContextKey{}, st)
+			}
+
+			if !st.Has(roles...) {
+				serveAPIErr(w, fmt.Errorf("%w: user %d needs one of %v", errMissingRole, st.User.ID, roles), http.StatusForbidden, "")
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// withRole wraps a single route in requireRole.
+func (s *Server) withRole(h http.HandlerFunc, roles ...string) http.Handler {
+	return s.requireRole(roles...)(h)
+}
+
+// hasRole is for handlers outside /api/admin that give staff extra abilities.
+func (s *Server) hasRole(ctx context.Context, u *db.User, roles ...string) (bool, error) {
+	userRoles, err := s.db.GetUserRoles(ctx, u.ID)
+	if err != nil {
+		return false, err
+	}
+	st := staff{User: u, Roles: userRoles}
+	return st.Has(roles...), nil
+}
+
+type RoleChange struct {
+	Role      string    `json:"role"`
+	Action    string    `json:"action"`
+	ActorID   string    `json:"actor_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (s *Server) serveUserRoles(w http.ResponseWriter, ctx context.Context, user *db.User) {
+	roles, err := s.db.GetUserRoles(ctx, user.ID)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+
+	changes, err := s.db.GetRoleChanges(ctx, user.ID)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+
+	history := make([]RoleChange, len(changes))
+	for i, c := range changes {
+		history[i] = RoleChange{
+			Role:      c.Role,
+			Action:    c.Action,
+			ActorID:   strconv.FormatInt(c.ActorID, 10),
+			CreatedAt: c.CreatedAt,
+		}
+	}
+
+	serveAPIPayload(w, struct {
+		User    User
+		Roles   []string
+		History []RoleChange
+	}{
+		User:    UserFromDBUser(user),
+		Roles:   roles,
+		History: history,
+	})
+}
+
+func (s *Server) lookupRoleUser(w http.ResponseWriter, r *http.Request) (*db.User, bool) {
+	user, err := s.getUserByUserID(r.Context(), ParseUserID(mux.Vars(r)["userid"]))
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			serveAPIErr(w, err, http.StatusNotFound, "Unknown user")
+			return nil, false
+		}
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return nil, false
+	}
+	return user, true
+}
+
+func (s *Server) adminGetUserRoles(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.lookupRoleUser(w, r)
+	if !ok {
+		return
+	}
+
+	s.serveUserRoles(w, r.Context(), user)
+}
+
+func (s *Server) adminGrantRole(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	u := staffUser(ctx)
+
+	role := mux.Vars(r)["role"]
+	if !slices.Contains(db.Roles, role) {
+		serveAPIErr(w, fmt.Errorf("unknown role %q", role), http.StatusBadRequest, "Unknown role")
+		return
+	}
+
+	user, ok := s.lookupRoleUser(w, r)
+	if !ok {
+		return
+	}
+
+	granted, err := s.db.GrantRole(ctx, user.ID, role, u.ID)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "could not grant role")
+		return
+	}
+	if granted {
+		slog.Info("granted role", "user", user.ID, "role", role, "by", u.ID)
+		s.audit(ctx, u.ID, auditGrant, auditTargetUser, user.ID, nil, struct{ Role string }{role})
+	}
+
+	s.serveUserRoles(w, ctx, user)
+}
+
+func (s *Server) adminRevokeRole(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	u := staffUser(ctx)
+
+	role := mux.Vars(r)["role"]
+	if !slices.Contains(db.Roles, role) {
+		serveAPIErr(w, fmt.Errorf("unknown role %q", role), http.StatusBadRequest, "Unknown role")
+		return
+	}
+
+	user, ok := s.lookupRoleUser(w, r)
+	if !ok {
+		return
+	}
+
+	if user.ID == u.ID && role == db.RoleAdmin {
+		// Leave at least one admin able to grant roles back
+		serveAPIErr(w, fmt.Errorf("user %d tried to revoke their own admin role", u.ID), http.StatusBadRequest, "You cannot revoke your own admin role")
+		return
+	}
+
+	revoked, err := s.db.RevokeRole(ctx, user.ID, role, u.ID)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "could not revoke role")
+		return
+	}
+	if revoked {
+		slog.Info("revoked role", "user", user.ID, "role", role, "by", u.ID)
+		s.audit(ctx, u.ID, auditRevoke, auditTargetUser, user.ID, struct{ Role string }{role}, nil)
+	}
+
+	s.serveUserRoles(w, ctx, user)
+}
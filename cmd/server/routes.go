@@ -1,19 +1,24 @@
 package main
 
 import (
+	"bytes"
 	"encoding/base64"
 	"errors"
 	"fmt"
 	"html/template"
+	"io"
 	"log/slog"
 	"net/http"
 	"net/url"
 	"path"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/cconger/shindaggers/pkg/db"
 	model "github.com/cconger/shindaggers/pkg/db/.gen/postgres/public/model"
+	"github.com/cconger/shindaggers/pkg/discord"
+	"github.com/cconger/shindaggers/pkg/images"
 	"github.com/cconger/shindaggers/pkg/twitch"
 
 	"github.com/bwmarrin/snowflake"
@@ -22,7 +27,7 @@ import (
 
 type Server struct {
 	devMode        bool
-	db             db.PostgresDB
+	db             db.Store
 	webhookSecret  string
 	twitchClientID string
 	twitchClient   twitch.TwitchClient
@@ -30,17 +35,42 @@ type Server struct {
 	minioClient    blobClient
 	bucketName     string
 	idGenerator    *snowflake.Node
-	discordWebhook string
+	discord        *discord.Webhook
+	notifyRarity   string
+	latest         *latestBroker
+	subscribers    *subscriberCache
+
+	eventSubSecret   string
+	broadcasterID    string
+	eventSubRewardID string
+
+	// Signs the oauth state cookie
+	stateKey []byte
 
 	template *template.Template
 }
 
 func (s *Server) LoginHandler(w http.ResponseWriter, r *http.Request) {
+	// The broadcaster logs in with ?broadcaster=true to let us check
+	// subscriptions to their channel
+	scope := ""
+	if r.URL.Query().Get("broadcaster") == "true" {
+		scope = twitch.SubscriptionsScope
+	}
+
+	nonce, err := s.setOAuthState(w, r.URL.Query().Get("return"))
+	if err != nil {
+		slog.Error("creating oauth state", "err", err)
+		http.Redirect(w, r, s.baseURL, http.StatusFound)
+		return
+	}
+
 	p := url.Values{
 		"response_type": []string{"code"},
 		"client_id":     []string{s.twitchClientID},
-		"redirect_uri":  []string{fmt.Sprintf("%s/oauth/handler", s.baseURL)},
-		"scope":         []string{""},
+		"redirect_uri":  []string{s.oauthRedirectURI()},
+		"scope":         []string{scope},
+		"state":         []string{nonce},
 	}
 
 	uri := "https://id.twitch.tv/oauth2/authorize?" + p.Encode()
@@ -55,6 +85,13 @@ func (s *Server) LoginHandler(w http.ResponseWriter, r *http.Request) {
 func (s *Server) LoginResponseHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
+	state, err := s.checkOAuthState(w, r)
+	if err != nil {
+		slog.Error("oAuth state mismatch", "err", err)
+		http.Redirect(w, r, s.baseURL, http.StatusFound)
+		return
+	}
+
 	params := r.URL.Query()
 	errored := params.Has("error")
 	if errored {
@@ -75,7 +112,7 @@ func (s *Server) LoginResponseHandler(w http.ResponseWriter, r *http.Request) {
 	t, err := s.twitchClient.OAuthGetToken(
 		ctx,
 		code,
-		fmt.Sprintf("%s/oauth/redirect", s.baseURL),
+		s.oauthRedirectURI(),
 	)
 	if err != nil {
 		slog.Error("getting oauthtoken", "err", err)
@@ -83,13 +120,14 @@ func (s *Server) LoginResponseHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	expiresAt := time.Now().Add(time.Duration(t.ExpiresIn) * time.Second)
-
-	// Get user from twitch
-	twitchClient := s.twitchClient.UserClient(&twitch.UserAuth{
+	session := &db.UserAuth{
 		AccessToken:  t.AccessToken,
 		RefreshToken: t.RefreshToken,
-	})
+		ExpiresAt:    time.Now().Add(time.Duration(t.ExpiresIn) * time.Second),
+	}
+
+	// Get user from twitch
+	twitchClient := s.sessionTwitchClient(session)
 
 	twitchUser, err := twitchClient.GetUser(ctx)
 	if err != nil {
@@ -123,6 +161,23 @@ func (s *Server) LoginResponseHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if s.broadcasterID != "" && twitchUser.ID == s.broadcasterID && slices.Contains(t.Scope, twitch.SubscriptionsScope) {
+		err = s.db.SaveBroadcasterAuth(ctx, model.BroadcasterTokens{
+			BroadcasterID: twitchUser.ID,
+			UserID:        user.ID,
+			AccessToken:   session.AccessToken,
+			RefreshToken:  session.RefreshToken,
+			Scopes:        strings.Join(t.Scope, " "),
+			ExpiresAt:     session.ExpiresAt,
+		})
+		if err != nil {
+			slog.Error("saving broadcaster token", "err", err)
+		} else {
+			slog.Info("stored broadcaster token", "broadcaster", twitchUser.ID)
+			s.subscribers.Clear()
+		}
+	}
+
 	if user.Name != twitchUser.DisplayName {
 		// We need to update this user
 		user.Name = twitchUser.DisplayName
@@ -141,16 +196,10 @@ func (s *Server) LoginResponseHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Store access token and refresh token
-	err = s.db.SaveAuth(
-		ctx,
-		db.UserAuth{
-			UserID:       user.ID,
-			Token:        token,
-			AccessToken:  t.AccessToken,
-			RefreshToken: t.RefreshToken,
-			ExpiresAt:    expiresAt,
-		},
-	)
+	session.UserID = user.ID
+	session.Token = hashAuthToken(token)
+	session.SessionExpiresAt = time.Now().Add(sessionLifetime)
+	err = s.db.SaveAuth(ctx, *session)
 	if err != nil {
 		slog.Error("saving auth token", "err", err)
 		http.Redirect(w, r, s.baseURL, http.StatusFound)
@@ -164,10 +213,15 @@ func (s *Server) LoginResponseHandler(w http.ResponseWriter, r *http.Request) {
 		baseURL = "http://localhost:3000"
 	}
 
+	lander := baseURL + "/login"
+	if state.ReturnTo != "" {
+		lander += "?" + url.Values{"return": []string{state.ReturnTo}}.Encode()
+	}
+
 	http.Redirect(
 		w,
 		r,
-		baseURL+"/login#token="+encodedToken,
+		lander+"#token="+encodedToken,
 		http.StatusFound,
 	)
 }
@@ -195,32 +249,54 @@ func (s *Server) ImageUpload(w http.ResponseWriter, r *http.Request) {
 	}
 	defer file.Close()
 
-	// Check the file type and size
-	if !strings.HasPrefix(handler.Header.Get("Content-Type"), "image/") {
-		serveAPIErr(w, fmt.Errorf("file not image"), http.StatusBadRequest, "file not image")
-		return
-	}
 	if handler.Size > 32<<20 {
 		serveAPIErr(w, fmt.Errorf("file too large"), http.StatusBadRequest, "file too large")
 		return
 	}
 
+	data, err := io.ReadAll(file)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusBadRequest, "could not read image")
+		return
+	}
+
+	// The content type and extension the client sent aren't trusted, the
+	// stored images are whatever the pipeline produced
+	processed, err := images.Process(data)
+	if err != nil {
+		if errors.Is(err, images.ErrNotImage) {
+			serveAPIErr(w, err, http.StatusBadRequest, "file not image")
+			return
+		}
+		if errors.Is(err, images.ErrTooLarge) {
+			serveAPIErr(w, err, http.StatusBadRequest, fmt.Sprintf("image cannot be larger than %dx%d", images.MaxDimension, images.MaxDimension))
+			return
+		}
+		serveAPIErr(w, err, http.StatusInternalServerError, "could not process image")
+		return
+	}
+
 	newImageID := s.idGenerator.Generate()
 	uploadName := path.Base(handler.Filename)
-	ext := path.Ext(handler.Filename)
 
-	basename := newImageID.String() + ext
+	basename := newImageID.String() + processed.Original.Ext
 	if s.minioClient != nil {
-		_, err = s.minioClient.PutObject(ctx, s.bucketName, path.Join("images", basename), file, handler.Size, minio.PutObjectOptions{
-			ContentType: handler.Header.Get("Content-Type"),
-		})
-		if err != nil {
-			serveAPIErr(w, err, http.StatusBadRequest, "error uploading image")
-			return
+		for name, img := range map[string]images.Image{
+			basename:                  processed.Original,
+			images.CardName(basename): processed.Card,
+			images.MiniName(basename): processed.Mini,
+		} {
+			_, err = s.minioClient.PutObject(ctx, s.bucketName, path.Join("images", name), bytes.NewReader(img.Data), int64(len(img.Data)), minio.PutObjectOptions{
+				ContentType: img.ContentType,
+			})
+			if err != nil {
+				serveAPIErr(w, err, http.StatusBadRequest, "error uploading image")
+				return
+			}
 		}
 	}
 
-	err = s.db.CreateImageUpload(ctx, newImageID.Int64(), user.ID, basename, uploadName)
+	err = s.db.CreateImageUpload(ctx, newImageID.Int64(), user.ID, basename, uploadName, int64(processed.Hash))
 	if err != nil {
 		serveAPIErr(w, err, http.StatusInternalServerError, "error saving image upload image")
 		return
@@ -230,11 +306,15 @@ func (s *Server) ImageUpload(w http.ResponseWriter, r *http.Request) {
 	serveAPIPayload(
 		w,
 		&struct {
-			ImagePath string
-			ImageURL  string
+			ImagePath    string
+			ImageURL     string
+			CardImageURL string
+			MiniImageURL string
 		}{
-			ImagePath: basename,
-			ImageURL:  "https://images.shindaggers.io/images/" + basename,
+			ImagePath:    basename,
+			ImageURL:     imageBaseURL + basename,
+			CardImageURL: imageBaseURL + images.CardName(basename),
+			MiniImageURL: imageBaseURL + images.MiniName(basename),
 		},
 	)
 }
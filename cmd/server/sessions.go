@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/cconger/shindaggers/pkg/db"
+	"github.com/cconger/shindaggers/pkg/twitch"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// Sessions end after this long without being used
+	sessionLifetime = 30 * 24 * time.Hour
+	// Only write a renewal once a day rather than on every request
+	sessionRenewInterval = 24 * time.Hour
+)
+
+// hashAuthToken is how session tokens are stored, the raw token only ever
+// lives in the browser.
+func hashAuthToken(token []byte) []byte {
+	h := sha256.Sum256(token)
+	return h[:]
+}
+
+// sessionKey reads the session token from the request and returns the hash
+// it is stored under.
+func sessionKey(r *http.Request) ([]byte, error) {
+	rawToken := r.Header.Get("Authorization")
+	if rawToken == "" {
+		return nil, fmt.Errorf("authorization token missing")
+	}
+
+	t, err := base64.URLEncoding.DecodeString(rawToken)
+	if err != nil {
+		return nil, fmt.Errorf("authorization token unreadable")
+	}
+
+	return hashAuthToken(t), nil
+}
+
+// getSession looks up the session for the request, sliding its expiry
+// forward while it is in use.
+func (s *Server) getSession(ctx context.Context, r *http.Request) (*db.UserAuth, error) {
+	key, err := sessionKey(r)
+	if err != nil {
+		return nil, err
+	}
+
+	auth, err := s.db.GetAuth(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if now.After(auth.SessionExpiresAt) {
+		return nil, errSessionEnded
+	}
+
+	if auth.SessionExpiresAt.Before(now.Add(sessionLifetime - sessionRenewInterval)) {
+		auth.SessionExpiresAt = now.Add(sessionLifetime)
+		err = s.db.RenewAuth(ctx, key, auth.SessionExpiresAt)
+		if err != nil {
+			// The session is still valid, it'll be renewed on a later request
+			slog.Error("renewing session", "user", auth.UserID, "err", err)
+		}
+	}
+
+	return auth, nil
+}
+
+// sessionTwitchClient calls twitch with the tokens of a session. Refreshed
+// tokens are copied back onto auth and, once the session has been stored,
+// saved so that later requests pick them up.
+func (s *Server) sessionTwitchClient(auth *db.UserAuth) twitch.UserClient {
+	return s.twitchClient.UserClient(&twitch.UserAuth{
+		AccessToken:  auth.AccessToken,
+		RefreshToken: auth.RefreshToken,
+		ExpiresAt:    auth.ExpiresAt,
+		OnRefresh: func(ctx context.Context, ua *twitch.UserAuth) error {
+			auth.AccessToken = ua.AccessToken
+			auth.RefreshToken = ua.RefreshToken
+			auth.ExpiresAt = ua.ExpiresAt
+			if auth.Token == nil {
+				// Still logging in, the session is saved with these tokens
+				return nil
+			}
+			return s.db.SaveAuth(ctx, *auth)
+		},
+	})
+}
+
+func (s *Server) logoutHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	key, err := sessionKey(r)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusBadRequest, "could not identify session")
+		return
+	}
+
+	err = s.db.DeleteAuth(ctx, key)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "could not log out")
+		return
+	}
+
+	serveAPIPayload(w, true)
+}
+
+func (s *Server) logoutEverywhereHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	u, err := s.getAuthUser(ctx, r)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusForbidden, "could not identify user")
+		return
+	}
+
+	n, err := s.db.DeleteAuthForUser(ctx, u.ID)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "could not log out")
+		return
+	}
+
+	serveAPIPayload(w, struct {
+		Revoked int64
+	}{
+		Revoked: n,
+	})
+}
+
+func (s *Server) adminRevokeUserTokens(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	u := staffUser(ctx)
+
+	vars := mux.Vars(r)
+	user, err := s.getUserByUserID(ctx, ParseUserID(vars["userid"]))
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			serveAPIErr(w, err, http.StatusNotFound, "Unknown user")
+			return
+		}
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+
+	n, err := s.db.DeleteAuthForUser(ctx, user.ID)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "could not revoke tokens")
+		return
+	}
+
+	slog.Info("revoked user tokens", "user", user.ID, "by", u.ID, "count", n)
+	s.audit(ctx, u.ID, auditRevoke, auditTargetUser, user.ID, nil, struct{ RevokedSessions int64 }{n})
+
+	serveAPIPayload(w, struct {
+		User    User
+		Revoked int64
+	}{
+		User:    UserFromDBUser(user),
+		Revoked: n,
+	})
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cconger/shindaggers/pkg/db"
+	model "github.com/cconger/shindaggers/pkg/db/.gen/postgres/public/model"
+
+	"github.com/gorilla/mux"
+)
+
+// getMySubmissions lists the collectables the logged in user has submitted
+// along with where each one is in review.
+func (s *Server) getMySubmissions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	u, err := s.getAuthUser(ctx, r)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusForbidden, "could not identify user")
+		return
+	}
+
+	collectables, err := s.db.GetCollectables(ctx, db.GetCollectablesOptions{
+		Creator:       u.ID,
+		GetUnapproved: true,
+		ReviewStatus:  r.URL.Query().Get("status"),
+	})
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+
+	submissions := make([]AdminCollectable, len(collectables))
+	for i, c := range collectables {
+		submissions[i] = AdminCollectableFromDBCollectable(c)
+	}
+
+	serveAPIPayload(w, struct {
+		Submissions []AdminCollectable
+	}{
+		Submissions: submissions,
+	})
+}
+
+// resubmitCollectable lets a creator edit a submission that hasn't been
+// approved yet, which puts it back in the approval queue.
+func (s *Server) resubmitCollectable(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	u, err := s.getAuthUser(ctx, r)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusForbidden, "could not identify user")
+		return
+	}
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusBadRequest, "id is not numeric")
+		return
+	}
+
+	var payload CollectablePayload
+	err = json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusBadRequest, "could not parse body")
+		return
+	}
+	r.Body.Close()
+
+	if payload.Collectable.Name == "" {
+		serveAPIErr(w, errMissingField, http.StatusBadRequest, "Name cannot be empty")
+		return
+	}
+
+	if !slices.Contains(rarities, payload.Collectable.Rarity) {
+		serveAPIErr(w, errMissingField, http.StatusBadRequest, "Rarity is unknown")
+		return
+	}
+
+	if payload.Collectable.ImagePath == "" {
+		serveAPIErr(w, errMissingField, http.StatusBadRequest, "ImagePath cannot be empty")
+		return
+	}
+
+	existing, err := s.db.GetCollectable(ctx, id, db.GetCollectableOptions{GetUnapproved: true})
+	if err != nil || existing.CreatorID != u.ID {
+		if err == nil || errors.Is(err, db.ErrNotFound) {
+			serveAPIErr(w, db.ErrNotFound, http.StatusNotFound, "Unknown submission")
+			return
+		}
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+
+	if existing.ApprovedAt != nil || existing.DeletedAt != nil {
+		serveAPIErr(w, fmt.Errorf("collectable %d is %s", id, existing.ReviewStatus), http.StatusConflict, "Only submissions awaiting approval can be edited")
+		return
+	}
+
+	collectionID := existing.CollectionID
+	if payload.Collectable.CollectionID != "" {
+		collection, err := s.collectionForPayload(ctx, payload.Collectable.CollectionID)
+		if err != nil {
+			if errors.Is(err, db.ErrNotFound) {
+				serveAPIErr(w, err, http.StatusBadRequest, "Unknown collection")
+				return
+			}
+			serveAPIErr(w, err, http.StatusInternalServerError, "")
+			return
+		}
+
+		if !collection.IsActive(time.Now()) {
+			serveAPIErr(w, fmt.Errorf("collection %d is not active", collection.ID), http.StatusBadRequest, "Collection is not accepting submissions")
+			return
+		}
+		collectionID = &collection.ID
+	}
+
+	updated, err := s.db.ResubmitCollectable(ctx, model.Collectables{
+		ID:           id,
+		CollectionID: collectionID,
+		CreatorID:    u.ID,
+		Name:         payload.Collectable.Name,
+		Rarity:       payload.Collectable.Rarity,
+		Imagepath:    payload.Collectable.ImagePath,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			serveAPIErr(w, err, http.StatusConflict, "Only submissions awaiting approval can be edited")
+			return
+		}
+		serveAPIErr(w, err, http.StatusInternalServerError, "could not update submission")
+		return
+	}
+
+	s.notifySubmission(updated)
+
+	serveAPIPayload(w, struct {
+		Collectable AdminCollectable
+	}{
+		Collectable: AdminCollectableFromDBCollectable(updated),
+	})
+}
+
+// ReviewPayload is the body for rejecting a submission or asking for changes.
+type ReviewPayload struct {
+	Feedback string
+}
+
+func (s *Server) adminRejectCollectable(w http.ResponseWriter, r *http.Request) {
+	s.reviewCollectable(w, r, db.ReviewRejected, auditReject)
+}
+
+func (s *Server) adminRequestChanges(w http.ResponseWriter, r *http.Request) {
+	s.reviewCollectable(w, r, db.ReviewChangesRequested, auditRequest)
+}
+
+func (s *Server) reviewCollectable(w http.ResponseWriter, r *http.Request, status, action string) {
+	ctx := r.Context()
+
+	u := staffUser(ctx)
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusBadRequest, "id is non numeric")
+		return
+	}
+
+	var payload ReviewPayload
+	err = json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusBadRequest, "could not parse body")
+		return
+	}
+	r.Body.Close()
+
+	payload.Feedback = strings.TrimSpace(payload.Feedback)
+	if payload.Feedback == "" {
+		serveAPIErr(w, errMissingField, http.StatusBadRequest, "Feedback cannot be empty")
+		return
+	}
+
+	before, err := s.db.GetCollectable(ctx, id, db.GetCollectableOptions{GetUnapproved: true})
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			serveAPIErr(w, err, http.StatusNotFound, "Unknown collectable")
+			return
+		}
+		serveAPIErr(w, err, http.StatusInternalServerError, "unable to get collectable")
+		return
+	}
+
+	c, err := s.db.ReviewCollectable(ctx, id, u.ID, status, payload.Feedback)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			serveAPIErr(w, err, http.StatusConflict, "Collectable is not awaiting approval")
+			return
+		}
+		serveAPIErr(w, err, http.StatusInternalServerError, "unable to review collectable")
+		return
+	}
+
+	s.audit(ctx, u.ID, action, auditTargetCollectable, id, before.Collectables, c.Collectables)
+
+	serveAPIPayload(w, struct {
+		Collectable AdminCollectable
+	}{
+		Collectable: AdminCollectableFromDBCollectable(c),
+	})
+}
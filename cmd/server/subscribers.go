@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cconger/shindaggers/pkg/twitch"
+)
+
+const (
+	// Long enough to absorb a burst of pulls without hitting helix for each
+	subscriberCacheTTL  = 5 * time.Minute
+	subscriberCacheSize = 5000
+)
+
+type subscriberEntry struct {
+	subscribed bool
+	expiresAt  time.Time
+}
+
+// subscriberCache remembers recent subscription lookups by twitch id.
+type subscriberCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]subscriberEntry
+}
+
+func newSubscriberCache(ttl time.Duration) *subscriberCache {
+	return &subscriberCache{
+		ttl:     ttl,
+		entries: map[string]subscriberEntry{},
+	}
+}
+
+func (c *subscriberCache) Get(twitchID string, now time.Time) (subscribed bool, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[twitchID]
+	if !ok || now.After(e.expiresAt) {
+		return false, false
+	}
+	return e.subscribed, true
+}
+
+func (c *subscriberCache) Set(twitchID string, subscribed bool, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[twitchID]; !ok && len(c.entries) >= subscriberCacheSize {
+		for id, e := range c.entries {
+			if now.After(e.expiresAt) {
+				delete(c.entries, id)
+			}
+		}
+
+		// Everything is still fresh during a busy stream, make room by
+		// dropping whoever would have expired first
+		for len(c.entries) >= subscriberCacheSize {
+			var oldest string
+			var oldestExpiry time.Time
+			for id, e := range c.entries {
+				if oldest == "" || e.expiresAt.Before(oldestExpiry) {
+					oldest, oldestExpiry = id, e.expiresAt
+				}
+			}
+			delete(c.entries, oldest)
+		}
+	}
+
+	c.entries[twitchID] = subscriberEntry{
+		subscribed: subscribed,
+		expiresAt:  now.Add(c.ttl),
+	}
+}
+
+// Clear drops every entry, used when the broadcaster token changes.
+func (c *subscriberCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = map[string]subscriberEntry{}
+}
+
+// isSubscriber asks twitch whether twitchID subscribes to the broadcaster,
+// using the token the broadcaster stored by logging in with the
+// subscriptions scope.
+func (s *Server) isSubscriber(ctx context.Context, twitchID string) (bool, error) {
+	now := time.Now()
+	if subscribed, ok := s.subscribers.Get(twitchID, now); ok {
+		return subscribed, nil
+	}
+
+	auth, err := s.db.GetBroadcasterAuth(ctx, s.broadcasterID)
+	if err != nil {
+		return false, fmt.Errorf("getting broadcaster token: %w", err)
+	}
+	if !strings.Contains(auth.Scopes, twitch.SubscriptionsScope) {
+		return false, fmt.Errorf("broadcaster token missing %s scope", twitch.SubscriptionsScope)
+	}
+
+	ua := &twitch.UserAuth{
+		AccessToken:  auth.AccessToken,
+		RefreshToken: auth.RefreshToken,
+		ExpiresAt:    auth.ExpiresAt,
+		OnRefresh: func(ctx context.Context, ua *twitch.UserAuth) error {
+			auth.AccessToken = ua.AccessToken
+			auth.RefreshToken = ua.RefreshToken
+			auth.ExpiresAt = ua.ExpiresAt
+			return s.db.SaveBroadcasterAuth(ctx, *auth)
+		},
+	}
+
+	subs, err := s.twitchClient.GetBroadcasterSubscriptions(ctx, ua, s.broadcasterID, twitchID)
+	if err != nil {
+		return false, fmt.Errorf("getting subscriptions: %w", err)
+	}
+
+	subscribed := false
+	for _, sub := range subs {
+		if sub.UserID == twitchID {
+			subscribed = true
+		}
+	}
+
+	s.subscribers.Set(twitchID, subscribed, now)
+	return subscribed, nil
+}
@@ -0,0 +1,380 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cconger/shindaggers/pkg/db"
+	model "github.com/cconger/shindaggers/pkg/db/.gen/postgres/public/model"
+
+	"github.com/gorilla/mux"
+)
+
+type TradeOffer struct {
+	ID         string              `json:"id"`
+	Proposer   User                `json:"proposer"`
+	Recipient  User                `json:"recipient"`
+	Status     string              `json:"status"`
+	Offered    []IssuedCollectable `json:"offered"`
+	Requested  []IssuedCollectable `json:"requested"`
+	CreatedAt  time.Time           `json:"created_at"`
+	ResolvedAt *time.Time          `json:"resolved_at"`
+}
+
+func TradeOfferFromDBTradeOffer(o *db.TradeOffer) TradeOffer {
+	res := TradeOffer{
+		ID: strconv.FormatInt(o.ID, 10),
+		Proposer: User{
+			ID: strconv.FormatInt(o.ProposerID, 10),
+		},
+		Recipient: User{
+			ID: strconv.FormatInt(o.RecipientID, 10),
+		},
+		Status:     o.Status,
+		Offered:    []IssuedCollectable{},
+		Requested:  []IssuedCollectable{},
+		CreatedAt:  o.CreatedAt,
+		ResolvedAt: o.ResolvedAt,
+	}
+	if o.Proposer != nil {
+		res.Proposer.Name = o.Proposer.Name
+	}
+	if o.Recipient != nil {
+		res.Recipient.Name = o.Recipient.Name
+	}
+	for _, item := range o.Items {
+		if item.Instance == nil {
+			continue
+		}
+		c := IssuedCollectableFromCollectableInstance(item.Instance)
+		if item.FromUserID == o.ProposerID {
+			res.Offered = append(res.Offered, c)
+		} else {
+			res.Requested = append(res.Requested, c)
+		}
+	}
+	return res
+}
+
+type OwnershipTransfer struct {
+	From    *User     `json:"from,omitempty"`
+	To      User      `json:"to"`
+	Type    string    `json:"type"`
+	TradeID string    `json:"trade_id,omitempty"`
+	At      time.Time `json:"at"`
+}
+
+func OwnershipTransferFromDBOwnershipEntry(e *db.OwnershipEntry) OwnershipTransfer {
+	res := OwnershipTransfer{
+		To: User{
+			ID: strconv.FormatInt(e.ToUserID, 10),
+		},
+		Type: e.TransType,
+		At:   e.CreatedAt,
+	}
+	if e.To != nil {
+		res.To.Name = e.To.Name
+	}
+	if e.FromUserID != nil {
+		res.From = &User{
+			ID: strconv.FormatInt(*e.FromUserID, 10),
+		}
+		if e.From != nil {
+			res.From.Name = e.From.Name
+		}
+	}
+	if e.TradeOfferID != nil {
+		res.TradeID = strconv.FormatInt(*e.TradeOfferID, 10)
+	}
+	return res
+}
+
+type TradeOfferPayload struct {
+	RecipientID string
+	// Offered are instance ids owned by the proposer
+	Offered []string
+	// Requested are instance ids owned by the recipient
+	Requested []string
+}
+
+// errBadTradeItem marks tradeItems errors that are the client's fault
+var errBadTradeItem = errors.New("invalid trade item")
+
+// tradeItems resolves the instance ids on one side of an offer, checking that
+// each is a live instance owned by ownerID.
+func (s *Server) tradeItems(r *http.Request, offerID int64, ownerID int64, ids []string, seen map[int64]bool) ([]model.TradeOfferItems, error) {
+	items := make([]model.TradeOfferItems, len(ids))
+	for i, idstr := range ids {
+		id, err := strconv.ParseInt(idstr, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: instance id %q is not numeric", errBadTradeItem, idstr)
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("%w: instance %d listed more than once", errBadTradeItem, id)
+		}
+		seen[id] = true
+
+		instances, err := s.db.GetCollectableInstances(r.Context(), db.GetCollectableInstancesOptions{
+			ByID: id,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(instances) == 0 || instances[0].OwnerID != ownerID {
+			return nil, fmt.Errorf("%w: instance %d is not owned by user %d", errBadTradeItem, id, ownerID)
+		}
+
+		items[i] = model.TradeOfferItems{
+			OfferID:    offerID,
+			InstanceID: id,
+			FromUserID: ownerID,
+		}
+	}
+	return items, nil
+}
+
+func (s *Server) createTradeOffer(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	u, err := s.getAuthUser(ctx, r)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusForbidden, "could not identify user")
+		return
+	}
+
+	var payload TradeOfferPayload
+	err = json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusBadRequest, "could not parse body")
+		return
+	}
+	r.Body.Close()
+
+	if len(payload.Offered) == 0 || len(payload.Requested) == 0 {
+		serveAPIErr(w, errMissingField, http.StatusBadRequest, "A trade needs knives on both sides")
+		return
+	}
+
+	recipientID, err := strconv.ParseInt(payload.RecipientID, 10, 64)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusBadRequest, "RecipientID is not numeric")
+		return
+	}
+	if recipientID == u.ID {
+		serveAPIErr(w, fmt.Errorf("user %d trading with themselves", u.ID), http.StatusBadRequest, "Cannot trade with yourself")
+		return
+	}
+
+	recipient, err := s.db.GetUser(ctx, db.GetUserOptions{
+		ID: recipientID,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			serveAPIErr(w, err, http.StatusBadRequest, "Unknown recipient")
+			return
+		}
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+
+	offer := model.TradeOffers{
+		ID:          s.idGenerator.Generate().Int64(),
+		ProposerID:  u.ID,
+		RecipientID: recipient.ID,
+		Status:      db.TradeStatusPending,
+	}
+
+	seen := map[int64]bool{}
+	offered, err := s.tradeItems(r, offer.ID, u.ID, payload.Offered, seen)
+	if err != nil {
+		if errors.Is(err, errBadTradeItem) {
+			serveAPIErr(w, err, http.StatusBadRequest, err.Error())
+			return
+		}
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+	requested, err := s.tradeItems(r, offer.ID, recipient.ID, payload.Requested, seen)
+	if err != nil {
+		if errors.Is(err, errBadTradeItem) {
+			serveAPIErr(w, err, http.StatusBadRequest, err.Error())
+			return
+		}
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+
+	created, err := s.db.CreateTradeOffer(ctx, offer, append(offered, requested...))
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "could not create trade offer")
+		return
+	}
+
+	serveAPIPayload(w, struct {
+		TradeOffer TradeOffer
+	}{
+		TradeOffer: TradeOfferFromDBTradeOffer(created),
+	})
+}
+
+func (s *Server) getMyTradeOffers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	u, err := s.getAuthUser(ctx, r)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusForbidden, "could not identify user")
+		return
+	}
+
+	offers, err := s.db.GetTradeOffers(ctx, db.GetTradeOffersOptions{
+		ByUser: u.ID,
+		Status: r.URL.Query().Get("status"),
+	})
+	if err != nil {
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return
+	}
+
+	res := make([]TradeOffer, len(offers))
+	for i, o := range offers {
+		res[i] = TradeOfferFromDBTradeOffer(&o)
+	}
+
+	serveAPIPayload(
+		w,
+		&struct {
+			TradeOffers []TradeOffer
+		}{
+			TradeOffers: res,
+		},
+	)
+}
+
+// tradeOfferForUser loads the offer named in the request if u is one of its
+// parties. Admins can see every offer.
+func (s *Server) tradeOfferForUser(w http.ResponseWriter, r *http.Request, u *db.User) (*db.TradeOffer, bool) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusBadRequest, "id is not numeric")
+		return nil, false
+	}
+
+	offer, err := s.db.GetTradeOffer(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			serveAPIErr(w, err, http.StatusNotFound, "Unknown trade offer")
+			return nil, false
+		}
+		serveAPIErr(w, err, http.StatusInternalServerError, "")
+		return nil, false
+	}
+
+	if offer.ProposerID != u.ID && offer.RecipientID != u.ID {
+		isAdmin, err := s.hasRole(r.Context(), u, db.RoleAdmin)
+		if err != nil {
+			serveAPIErr(w, err, http.StatusInternalServerError, "")
+			return nil, false
+		}
+		if !isAdmin {
+			// Don't reveal offers between other users
+			serveAPIErr(w, fmt.Errorf("user %d not party to offer %d", u.ID, id), http.StatusNotFound, "Unknown trade offer")
+			return nil, false
+		}
+	}
+
+	return offer, true
+}
+
+func (s *Server) getTradeOffer(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	u, err := s.getAuthUser(ctx, r)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusForbidden, "could not identify user")
+		return
+	}
+
+	offer, ok := s.tradeOfferForUser(w, r, u)
+	if !ok {
+		return
+	}
+
+	serveAPIPayload(w, struct {
+		TradeOffer TradeOffer
+	}{
+		TradeOffer: TradeOfferFromDBTradeOffer(offer),
+	})
+}
+
+func (s *Server) acceptTradeOffer(w http.ResponseWriter, r *http.Request) {
+	s.resolveTradeOffer(w, r, db.TradeStatusAccepted)
+}
+
+func (s *Server) declineTradeOffer(w http.ResponseWriter, r *http.Request) {
+	s.resolveTradeOffer(w, r, db.TradeStatusDeclined)
+}
+
+func (s *Server) cancelTradeOffer(w http.ResponseWriter, r *http.Request) {
+	s.resolveTradeOffer(w, r, db.TradeStatusCancelled)
+}
+
+// resolveTradeOffer moves a pending offer to status. Only the recipient can
+// accept or decline, and only the proposer can cancel.
+func (s *Server) resolveTradeOffer(w http.ResponseWriter, r *http.Request, status string) {
+	ctx := r.Context()
+
+	u, err := s.getAuthUser(ctx, r)
+	if err != nil {
+		serveAPIErr(w, err, http.StatusForbidden, "could not identify user")
+		return
+	}
+
+	offer, ok := s.tradeOfferForUser(w, r, u)
+	if !ok {
+		return
+	}
+
+	actor := offer.RecipientID
+	if status == db.TradeStatusCancelled {
+		actor = offer.ProposerID
+	}
+	if u.ID != actor {
+		serveAPIErr(w, fmt.Errorf("user %d cannot mark offer %d %s", u.ID, offer.ID, status), http.StatusForbidden, fmt.Sprintf("Only the other party can mark this trade offer %s", status))
+		return
+	}
+
+	var updated *db.TradeOffer
+	if status == db.TradeStatusAccepted {
+		updated, err = s.db.AcceptTradeOffer(ctx, offer.ID)
+	} else {
+		updated, err = s.db.ResolveTradeOffer(ctx, offer.ID, status)
+	}
+	if err != nil {
+		if errors.Is(err, db.ErrNotPending) {
+			serveAPIErr(w, err, http.StatusConflict, "Trade offer is no longer pending")
+			return
+		}
+		if errors.Is(err, db.ErrOwnershipChanged) {
+			serveAPIErr(w, err, http.StatusConflict, "A knife in this trade has changed hands")
+			return
+		}
+		serveAPIErr(w, err, http.StatusInternalServerError, "could not update trade offer")
+		return
+	}
+
+	if status == db.TradeStatusAccepted {
+		// Accepting moves knives between users, declines and cancels don't
+		s.audit(ctx, u.ID, auditTrade, auditTargetTrade, offer.ID, offer.TradeOffers, updated.TradeOffers)
+	}
+
+	serveAPIPayload(w, struct {
+		TradeOffer TradeOffer
+	}{
+		TradeOffer: TradeOfferFromDBTradeOffer(updated),
+	})
+}
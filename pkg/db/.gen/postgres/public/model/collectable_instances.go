@@ -19,4 +19,9 @@ type CollectableInstances struct {
 	CreatedAt     time.Time
 	DeletedAt     *time.Time
 	Tags          *string
+	IssuedBy      *int64
+	IssueReason   *string
+	RevokedBy     *int64
+	RevokeReason  *string
+	SalvagedAt    *time.Time
 }
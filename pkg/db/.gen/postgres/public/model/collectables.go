@@ -12,14 +12,18 @@ import (
 )
 
 type Collectables struct {
-	ID           int64 `sql:"primary_key"`
-	Name         string
-	CollectionID *int64
-	CreatorID    int64
-	Rarity       string
-	Imagepath    string
-	ApprovedAt   *time.Time
-	ApprovedBy   *int64
-	CreatedAt    time.Time
-	DeletedAt    *time.Time
+	ID             int64 `sql:"primary_key"`
+	Name           string
+	CollectionID   *int64
+	CreatorID      int64
+	Rarity         string
+	Imagepath      string
+	ApprovedAt     *time.Time
+	ApprovedBy     *int64
+	CreatedAt      time.Time
+	DeletedAt      *time.Time
+	ReviewStatus   string
+	ReviewFeedback *string
+	ReviewedBy     *int64
+	ReviewedAt     *time.Time
 }
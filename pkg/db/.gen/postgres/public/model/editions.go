@@ -12,7 +12,11 @@ import (
 )
 
 type Editions struct {
-	ID        int64 `sql:"primary_key"`
-	Name      string
-	CreatedAt time.Time
+	ID           int64 `sql:"primary_key"`
+	Name         string
+	CreatedAt    time.Time
+	CollectionID *int64
+	FirstN       *int64
+	ActiveAt     *time.Time
+	RetiredAt    *time.Time
 }
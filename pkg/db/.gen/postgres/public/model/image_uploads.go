@@ -17,4 +17,5 @@ type ImageUploads struct {
 	UserID     int64
 	Imagepath  string
 	UploadedAt time.Time
+	Phash      *int64
 }
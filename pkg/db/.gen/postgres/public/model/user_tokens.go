@@ -12,11 +12,12 @@ import (
 )
 
 type UserTokens struct {
-	UserID       int64
-	Token        []byte `sql:"primary_key"`
-	AccessToken  *string
-	RefreshToken *string
-	ExpiresAt    *time.Time
-	CreatedAt    time.Time
-	UpdatedAt    time.Time
+	UserID           int64
+	Token            []byte `sql:"primary_key"`
+	AccessToken      *string
+	RefreshToken     *string
+	ExpiresAt        *time.Time
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	SessionExpiresAt time.Time
 }
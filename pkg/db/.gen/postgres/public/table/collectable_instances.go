@@ -24,6 +24,11 @@ type collectableInstancesTable struct {
 	CreatedAt     postgres.ColumnTimestamp
 	DeletedAt     postgres.ColumnTimestamp
 	Tags          postgres.ColumnString
+	IssuedBy      postgres.ColumnInteger
+	IssueReason   postgres.ColumnString
+	RevokedBy     postgres.ColumnInteger
+	RevokeReason  postgres.ColumnString
+	SalvagedAt    postgres.ColumnTimestamp
 
 	AllColumns     postgres.ColumnList
 	MutableColumns postgres.ColumnList
@@ -71,8 +76,13 @@ func newCollectableInstancesTableImpl(schemaName, tableName, alias string) colle
 		CreatedAtColumn     = postgres.TimestampColumn("created_at")
 		DeletedAtColumn     = postgres.TimestampColumn("deleted_at")
 		TagsColumn          = postgres.StringColumn("tags")
-		allColumns          = postgres.ColumnList{IDColumn, CollectableIDColumn, OwnerIDColumn, EditionIDColumn, CreatedAtColumn, DeletedAtColumn, TagsColumn}
-		mutableColumns      = postgres.ColumnList{CollectableIDColumn, OwnerIDColumn, EditionIDColumn, CreatedAtColumn, DeletedAtColumn, TagsColumn}
+		IssuedByColumn      = postgres.IntegerColumn("issued_by")
+		IssueReasonColumn   = postgres.StringColumn("issue_reason")
+		RevokedByColumn     = postgres.IntegerColumn("revoked_by")
+		RevokeReasonColumn  = postgres.StringColumn("revoke_reason")
+		SalvagedAtColumn    = postgres.TimestampColumn("salvaged_at")
+		allColumns          = postgres.ColumnList{IDColumn, CollectableIDColumn, OwnerIDColumn, EditionIDColumn, CreatedAtColumn, DeletedAtColumn, TagsColumn, IssuedByColumn, IssueReasonColumn, RevokedByColumn, RevokeReasonColumn, SalvagedAtColumn}
+		mutableColumns      = postgres.ColumnList{CollectableIDColumn, OwnerIDColumn, EditionIDColumn, CreatedAtColumn, DeletedAtColumn, TagsColumn, IssuedByColumn, IssueReasonColumn, RevokedByColumn, RevokeReasonColumn, SalvagedAtColumn}
 	)
 
 	return collectableInstancesTable{
@@ -86,6 +96,11 @@ func newCollectableInstancesTableImpl(schemaName, tableName, alias string) colle
 		CreatedAt:     CreatedAtColumn,
 		DeletedAt:     DeletedAtColumn,
 		Tags:          TagsColumn,
+		IssuedBy:      IssuedByColumn,
+		IssueReason:   IssueReasonColumn,
+		RevokedBy:     RevokedByColumn,
+		RevokeReason:  RevokeReasonColumn,
+		SalvagedAt:    SalvagedAtColumn,
 
 		AllColumns:     allColumns,
 		MutableColumns: mutableColumns,
@@ -17,16 +17,20 @@ type collectablesTable struct {
 	postgres.Table
 
 	// Columns
-	ID           postgres.ColumnInteger
-	Name         postgres.ColumnString
-	CollectionID postgres.ColumnInteger
-	CreatorID    postgres.ColumnInteger
-	Rarity       postgres.ColumnString
-	Imagepath    postgres.ColumnString
-	ApprovedAt   postgres.ColumnTimestamp
-	ApprovedBy   postgres.ColumnInteger
-	CreatedAt    postgres.ColumnTimestamp
-	DeletedAt    postgres.ColumnTimestamp
+	ID             postgres.ColumnInteger
+	Name           postgres.ColumnString
+	CollectionID   postgres.ColumnInteger
+	CreatorID      postgres.ColumnInteger
+	Rarity         postgres.ColumnString
+	Imagepath      postgres.ColumnString
+	ApprovedAt     postgres.ColumnTimestamp
+	ApprovedBy     postgres.ColumnInteger
+	CreatedAt      postgres.ColumnTimestamp
+	DeletedAt      postgres.ColumnTimestamp
+	ReviewStatus   postgres.ColumnString
+	ReviewFeedback postgres.ColumnString
+	ReviewedBy     postgres.ColumnInteger
+	ReviewedAt     postgres.ColumnTimestamp
 
 	AllColumns     postgres.ColumnList
 	MutableColumns postgres.ColumnList
@@ -67,34 +71,42 @@ func newCollectablesTable(schemaName, tableName, alias string) *CollectablesTabl
 
 func newCollectablesTableImpl(schemaName, tableName, alias string) collectablesTable {
 	var (
-		IDColumn           = postgres.IntegerColumn("id")
-		NameColumn         = postgres.StringColumn("name")
-		CollectionIDColumn = postgres.IntegerColumn("collection_id")
-		CreatorIDColumn    = postgres.IntegerColumn("creator_id")
-		RarityColumn       = postgres.StringColumn("rarity")
-		ImagepathColumn    = postgres.StringColumn("imagepath")
-		ApprovedAtColumn   = postgres.TimestampColumn("approved_at")
-		ApprovedByColumn   = postgres.IntegerColumn("approved_by")
-		CreatedAtColumn    = postgres.TimestampColumn("created_at")
-		DeletedAtColumn    = postgres.TimestampColumn("deleted_at")
-		allColumns         = postgres.ColumnList{IDColumn, NameColumn, CollectionIDColumn, CreatorIDColumn, RarityColumn, ImagepathColumn, ApprovedAtColumn, ApprovedByColumn, CreatedAtColumn, DeletedAtColumn}
-		mutableColumns     = postgres.ColumnList{NameColumn, CollectionIDColumn, CreatorIDColumn, RarityColumn, ImagepathColumn, ApprovedAtColumn, ApprovedByColumn, CreatedAtColumn, DeletedAtColumn}
+		IDColumn             = postgres.IntegerColumn("id")
+		NameColumn           = postgres.StringColumn("name")
+		CollectionIDColumn   = postgres.IntegerColumn("collection_id")
+		CreatorIDColumn      = postgres.IntegerColumn("creator_id")
+		RarityColumn         = postgres.StringColumn("rarity")
+		ImagepathColumn      = postgres.StringColumn("imagepath")
+		ApprovedAtColumn     = postgres.TimestampColumn("approved_at")
+		ApprovedByColumn     = postgres.IntegerColumn("approved_by")
+		CreatedAtColumn      = postgres.TimestampColumn("created_at")
+		DeletedAtColumn      = postgres.TimestampColumn("deleted_at")
+		ReviewStatusColumn   = postgres.StringColumn("review_status")
+		ReviewFeedbackColumn = postgres.StringColumn("review_feedback")
+		ReviewedByColumn     = postgres.IntegerColumn("reviewed_by")
+		ReviewedAtColumn     = postgres.TimestampColumn("reviewed_at")
+		allColumns           = postgres.ColumnList{IDColumn, NameColumn, CollectionIDColumn, CreatorIDColumn, RarityColumn, ImagepathColumn, ApprovedAtColumn, ApprovedByColumn, CreatedAtColumn, DeletedAtColumn, ReviewStatusColumn, ReviewFeedbackColumn, ReviewedByColumn, ReviewedAtColumn}
+		mutableColumns       = postgres.ColumnList{NameColumn, CollectionIDColumn, CreatorIDColumn, RarityColumn, ImagepathColumn, ApprovedAtColumn, ApprovedByColumn, CreatedAtColumn, DeletedAtColumn, ReviewStatusColumn, ReviewFeedbackColumn, ReviewedByColumn, ReviewedAtColumn}
 	)
 
 	return collectablesTable{
 		Table: postgres.NewTable(schemaName, tableName, alias, allColumns...),
 
 		//Columns
-		ID:           IDColumn,
-		Name:         NameColumn,
-		CollectionID: CollectionIDColumn,
-		CreatorID:    CreatorIDColumn,
-		Rarity:       RarityColumn,
-		Imagepath:    ImagepathColumn,
-		ApprovedAt:   ApprovedAtColumn,
-		ApprovedBy:   ApprovedByColumn,
-		CreatedAt:    CreatedAtColumn,
-		DeletedAt:    DeletedAtColumn,
+		ID:             IDColumn,
+		Name:           NameColumn,
+		CollectionID:   CollectionIDColumn,
+		CreatorID:      CreatorIDColumn,
+		Rarity:         RarityColumn,
+		Imagepath:      ImagepathColumn,
+		ApprovedAt:     ApprovedAtColumn,
+		ApprovedBy:     ApprovedByColumn,
+		CreatedAt:      CreatedAtColumn,
+		DeletedAt:      DeletedAtColumn,
+		ReviewStatus:   ReviewStatusColumn,
+		ReviewFeedback: ReviewFeedbackColumn,
+		ReviewedBy:     ReviewedByColumn,
+		ReviewedAt:     ReviewedAtColumn,
 
 		AllColumns:     allColumns,
 		MutableColumns: mutableColumns,
@@ -17,9 +17,13 @@ type editionsTable struct {
 	postgres.Table
 
 	// Columns
-	ID        postgres.ColumnInteger
-	Name      postgres.ColumnString
-	CreatedAt postgres.ColumnTimestamp
+	ID           postgres.ColumnInteger
+	Name         postgres.ColumnString
+	CreatedAt    postgres.ColumnTimestamp
+	CollectionID postgres.ColumnInteger
+	FirstN       postgres.ColumnInteger
+	ActiveAt     postgres.ColumnTimestamp
+	RetiredAt    postgres.ColumnTimestamp
 
 	AllColumns     postgres.ColumnList
 	MutableColumns postgres.ColumnList
@@ -60,20 +64,28 @@ func newEditionsTable(schemaName, tableName, alias string) *EditionsTable {
 
 func newEditionsTableImpl(schemaName, tableName, alias string) editionsTable {
 	var (
-		IDColumn        = postgres.IntegerColumn("id")
-		NameColumn      = postgres.StringColumn("name")
-		CreatedAtColumn = postgres.TimestampColumn("created_at")
-		allColumns      = postgres.ColumnList{IDColumn, NameColumn, CreatedAtColumn}
-		mutableColumns  = postgres.ColumnList{NameColumn, CreatedAtColumn}
+		IDColumn           = postgres.IntegerColumn("id")
+		NameColumn         = postgres.StringColumn("name")
+		CreatedAtColumn    = postgres.TimestampColumn("created_at")
+		CollectionIDColumn = postgres.IntegerColumn("collection_id")
+		FirstNColumn       = postgres.IntegerColumn("first_n")
+		ActiveAtColumn     = postgres.TimestampColumn("active_at")
+		RetiredAtColumn    = postgres.TimestampColumn("retired_at")
+		allColumns         = postgres.ColumnList{IDColumn, NameColumn, CreatedAtColumn, CollectionIDColumn, FirstNColumn, ActiveAtColumn, RetiredAtColumn}
+		mutableColumns     = postgres.ColumnList{NameColumn, CreatedAtColumn, CollectionIDColumn, FirstNColumn, ActiveAtColumn, RetiredAtColumn}
 	)
 
 	return editionsTable{
 		Table: postgres.NewTable(schemaName, tableName, alias, allColumns...),
 
 		//Columns
-		ID:        IDColumn,
-		Name:      NameColumn,
-		CreatedAt: CreatedAtColumn,
+		ID:           IDColumn,
+		Name:         NameColumn,
+		CreatedAt:    CreatedAtColumn,
+		CollectionID: CollectionIDColumn,
+		FirstN:       FirstNColumn,
+		ActiveAt:     ActiveAtColumn,
+		RetiredAt:    RetiredAtColumn,
 
 		AllColumns:     allColumns,
 		MutableColumns: mutableColumns,
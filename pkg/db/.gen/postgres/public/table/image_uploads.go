@@ -22,6 +22,7 @@ type imageUploadsTable struct {
 	UserID     postgres.ColumnInteger
 	Imagepath  postgres.ColumnString
 	UploadedAt postgres.ColumnTimestamp
+	Phash      postgres.ColumnInteger
 
 	AllColumns     postgres.ColumnList
 	MutableColumns postgres.ColumnList
@@ -67,8 +68,9 @@ func newImageUploadsTableImpl(schemaName, tableName, alias string) imageUploadsT
 		UserIDColumn     = postgres.IntegerColumn("user_id")
 		ImagepathColumn  = postgres.StringColumn("imagepath")
 		UploadedAtColumn = postgres.TimestampColumn("uploaded_at")
-		allColumns       = postgres.ColumnList{IDColumn, UploadNameColumn, UserIDColumn, ImagepathColumn, UploadedAtColumn}
-		mutableColumns   = postgres.ColumnList{UploadNameColumn, UserIDColumn, ImagepathColumn, UploadedAtColumn}
+		PhashColumn      = postgres.IntegerColumn("phash")
+		allColumns       = postgres.ColumnList{IDColumn, UploadNameColumn, UserIDColumn, ImagepathColumn, UploadedAtColumn, PhashColumn}
+		mutableColumns   = postgres.ColumnList{UploadNameColumn, UserIDColumn, ImagepathColumn, UploadedAtColumn, PhashColumn}
 	)
 
 	return imageUploadsTable{
@@ -80,6 +82,7 @@ func newImageUploadsTableImpl(schemaName, tableName, alias string) imageUploadsT
 		UserID:     UserIDColumn,
 		Imagepath:  ImagepathColumn,
 		UploadedAt: UploadedAtColumn,
+		Phash:      PhashColumn,
 
 		AllColumns:     allColumns,
 		MutableColumns: mutableColumns,
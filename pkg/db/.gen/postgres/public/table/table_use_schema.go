@@ -10,12 +10,27 @@ package table
 // UseSchema sets a new schema name for all generated table SQL builder types. It is recommended to invoke
 // this method only once at the beginning of the program.
 func UseSchema(schema string) {
+	AuditLog = AuditLog.FromSchema(schema)
+	BroadcasterTokens = BroadcasterTokens.FromSchema(schema)
 	CollectableInstances = CollectableInstances.FromSchema(schema)
 	Collectables = Collectables.FromSchema(schema)
+	CollectionWeightHistory = CollectionWeightHistory.FromSchema(schema)
 	Collections = Collections.FromSchema(schema)
+	CraftRecipes = CraftRecipes.FromSchema(schema)
 	Editions = Editions.FromSchema(schema)
+	EventMatches = EventMatches.FromSchema(schema)
+	Events = Events.FromSchema(schema)
+	EventsubMessages = EventsubMessages.FromSchema(schema)
+	FightOutcomes = FightOutcomes.FromSchema(schema)
+	Fights = Fights.FromSchema(schema)
 	ImageUploads = ImageUploads.FromSchema(schema)
+	MaterialBalances = MaterialBalances.FromSchema(schema)
+	OwnershipLedger = OwnershipLedger.FromSchema(schema)
+	TradeOfferItems = TradeOfferItems.FromSchema(schema)
+	TradeOffers = TradeOffers.FromSchema(schema)
 	UserEquipCollectableInstance = UserEquipCollectableInstance.FromSchema(schema)
+	UserRoleChanges = UserRoleChanges.FromSchema(schema)
+	UserRoles = UserRoles.FromSchema(schema)
 	UserTokens = UserTokens.FromSchema(schema)
 	Users = Users.FromSchema(schema)
 }
@@ -17,13 +17,14 @@ type userTokensTable struct {
 	postgres.Table
 
 	// Columns
-	UserID       postgres.ColumnInteger
-	Token        postgres.ColumnString
-	AccessToken  postgres.ColumnString
-	RefreshToken postgres.ColumnString
-	ExpiresAt    postgres.ColumnTimestamp
-	CreatedAt    postgres.ColumnTimestamp
-	UpdatedAt    postgres.ColumnTimestamp
+	UserID           postgres.ColumnInteger
+	Token            postgres.ColumnString
+	AccessToken      postgres.ColumnString
+	RefreshToken     postgres.ColumnString
+	ExpiresAt        postgres.ColumnTimestamp
+	CreatedAt        postgres.ColumnTimestamp
+	UpdatedAt        postgres.ColumnTimestamp
+	SessionExpiresAt postgres.ColumnTimestamp
 
 	AllColumns     postgres.ColumnList
 	MutableColumns postgres.ColumnList
@@ -64,28 +65,30 @@ func newUserTokensTable(schemaName, tableName, alias string) *UserTokensTable {
 
 func newUserTokensTableImpl(schemaName, tableName, alias string) userTokensTable {
 	var (
-		UserIDColumn       = postgres.IntegerColumn("user_id")
-		TokenColumn        = postgres.StringColumn("token")
-		AccessTokenColumn  = postgres.StringColumn("access_token")
-		RefreshTokenColumn = postgres.StringColumn("refresh_token")
-		ExpiresAtColumn    = postgres.TimestampColumn("expires_at")
-		CreatedAtColumn    = postgres.TimestampColumn("created_at")
-		UpdatedAtColumn    = postgres.TimestampColumn("updated_at")
-		allColumns         = postgres.ColumnList{UserIDColumn, TokenColumn, AccessTokenColumn, RefreshTokenColumn, ExpiresAtColumn, CreatedAtColumn, UpdatedAtColumn}
-		mutableColumns     = postgres.ColumnList{UserIDColumn, AccessTokenColumn, RefreshTokenColumn, ExpiresAtColumn, CreatedAtColumn, UpdatedAtColumn}
+		UserIDColumn           = postgres.IntegerColumn("user_id")
+		TokenColumn            = postgres.StringColumn("token")
+		AccessTokenColumn      = postgres.StringColumn("access_token")
+		RefreshTokenColumn     = postgres.StringColumn("refresh_token")
+		ExpiresAtColumn        = postgres.TimestampColumn("expires_at")
+		CreatedAtColumn        = postgres.TimestampColumn("created_at")
+		UpdatedAtColumn        = postgres.TimestampColumn("updated_at")
+		SessionExpiresAtColumn = postgres.TimestampColumn("session_expires_at")
+		allColumns             = postgres.ColumnList{UserIDColumn, TokenColumn, AccessTokenColumn, RefreshTokenColumn, ExpiresAtColumn, CreatedAtColumn, UpdatedAtColumn, SessionExpiresAtColumn}
+		mutableColumns         = postgres.ColumnList{UserIDColumn, AccessTokenColumn, RefreshTokenColumn, ExpiresAtColumn, CreatedAtColumn, UpdatedAtColumn, SessionExpiresAtColumn}
 	)
 
 	return userTokensTable{
 		Table: postgres.NewTable(schemaName, tableName, alias, allColumns...),
 
 		//Columns
-		UserID:       UserIDColumn,
-		Token:        TokenColumn,
-		AccessToken:  AccessTokenColumn,
-		RefreshToken: RefreshTokenColumn,
-		ExpiresAt:    ExpiresAtColumn,
-		CreatedAt:    CreatedAtColumn,
-		UpdatedAt:    UpdatedAtColumn,
+		UserID:           UserIDColumn,
+		Token:            TokenColumn,
+		AccessToken:      AccessTokenColumn,
+		RefreshToken:     RefreshTokenColumn,
+		ExpiresAt:        ExpiresAtColumn,
+		CreatedAt:        CreatedAtColumn,
+		UpdatedAt:        UpdatedAtColumn,
+		SessionExpiresAt: SessionExpiresAtColumn,
 
 		AllColumns:     allColumns,
 		MutableColumns: mutableColumns,
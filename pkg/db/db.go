@@ -0,0 +1,110 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	model "github.com/cconger/shindaggers/pkg/db/.gen/postgres/public/model"
+)
+
+// Store is the storage used by the server. PostgresDB is the production
+// implementation and MemoryDB backs the -nodb development mode.
+type Store interface {
+	GetLatestIssues(ctx context.Context, options GetLatestIssuesOptions) ([]CollectableInstance, error)
+	CreateCollectableInstance(ctx context.Context, instance model.CollectableInstances) (*CollectableInstance, error)
+	GetCollectableInstances(ctx context.Context, options GetCollectableInstancesOptions) ([]CollectableInstance, error)
+	RevokeCollectableInstance(ctx context.Context, instanceID int64, revokerID int64, reason string) error
+	RestoreCollectableInstance(ctx context.Context, instanceID int64) (*CollectableInstance, error)
+	GetEquippedForUser(ctx context.Context, userID int64) (*CollectableInstance, error)
+	SetEquipped(ctx context.Context, collectableInstanceID int64, userID int64) error
+
+	GetCollectable(ctx context.Context, id int64, options GetCollectableOptions) (*Collectable, error)
+	GetCollectables(ctx context.Context, options GetCollectablesOptions) ([]*Collectable, error)
+	CreateCollectable(ctx context.Context, collectable model.Collectables) (*Collectable, error)
+	UpdateCollectable(ctx context.Context, collectable model.Collectables) (*Collectable, error)
+	ApproveCollectable(ctx context.Context, collectableID int64, approverID int64) (*Collectable, error)
+	ReviewCollectable(ctx context.Context, collectableID int64, reviewerID int64, status string, feedback string) (*Collectable, error)
+	ResubmitCollectable(ctx context.Context, collectable model.Collectables) (*Collectable, error)
+	DeleteCollectable(ctx context.Context, collectableID int64) error
+
+	SearchUsers(ctx context.Context, search string) ([]User, error)
+	GetUser(ctx context.Context, options GetUserOptions) (*User, error)
+	CreateUser(ctx context.Context, user User) (*User, error)
+	UpdateUser(ctx context.Context, user User) (*User, error)
+	GetAuth(ctx context.Context, token []byte) (*UserAuth, error)
+	SaveAuth(ctx context.Context, auth UserAuth) error
+	RenewAuth(ctx context.Context, token []byte, sessionExpiresAt time.Time) error
+	DeleteAuth(ctx context.Context, token []byte) error
+	DeleteAuthForUser(ctx context.Context, userID int64) (int64, error)
+
+	CreateImageUpload(ctx context.Context, imageID int64, user int64, name, uploadname string, phash int64) error
+	GetImageHashes(ctx context.Context, imagepaths []string) (map[string]int64, error)
+	GetOrphanedImageUploads(ctx context.Context, uploadedBefore time.Time) ([]model.ImageUploads, error)
+	DeleteImageUpload(ctx context.Context, imageID int64) error
+	GetUnhashedImageUploads(ctx context.Context) ([]model.ImageUploads, error)
+	GetCollectablesWithoutImageUpload(ctx context.Context) ([]model.Collectables, error)
+	SetImageUploadHash(ctx context.Context, imageID int64, phash int64) error
+
+	GetEditions(ctx context.Context, options GetEditionsOptions) ([]*Edition, error)
+	GetEdition(ctx context.Context, id int64) (*Edition, error)
+	CreateEdition(ctx context.Context, edition model.Editions) (*Edition, error)
+	UpdateEdition(ctx context.Context, edition model.Editions) (*Edition, error)
+	GetEditionCounts(ctx context.Context, options GetEditionCountsOptions) ([]EditionCount, error)
+	CountCollectableInstances(ctx context.Context, collectableID int64) (int64, error)
+
+	GetCollections(ctx context.Context, options GetCollectionsOptions) ([]*Collection, error)
+	GetCollection(ctx context.Context, id int64) (*Collection, error)
+	CreateCollection(ctx context.Context, collection model.Collections) (*Collection, error)
+	UpdateCollection(ctx context.Context, collection model.Collections) (*Collection, error)
+
+	GetWeights(ctx context.Context, collectionID int64) ([]*PullWeight, error)
+	UpdateWeights(ctx context.Context, collectionID int64, weights map[string]int, changedBy int64) error
+	GetWeightHistory(ctx context.Context, collectionID int64) ([]WeightHistory, error)
+
+	GetFight(ctx context.Context, id int64) (*Fight, error)
+	GetFightByClientID(ctx context.Context, clientID string) (*Fight, error)
+	GetFights(ctx context.Context, options GetFightsOptions) ([]Fight, error)
+	CreateFight(ctx context.Context, fight model.Fights, outcomes []model.FightOutcomes) (*Fight, error)
+	GetFightStats(ctx context.Context, userID int64) (*FightStats, error)
+
+	GetEvents(ctx context.Context, options GetEventsOptions) ([]*Event, error)
+	GetEvent(ctx context.Context, options GetEventOptions) (*Event, error)
+	CreateEvent(ctx context.Context, event model.Events) (*Event, error)
+	UpdateEvent(ctx context.Context, event model.Events) (*Event, error)
+	GetEventLeaderboard(ctx context.Context, eventID int64) ([]LeaderboardEntry, error)
+	GetEventMatches(ctx context.Context, eventID int64) ([]EventMatch, error)
+	CreateEventMatches(ctx context.Context, matches []model.EventMatches) error
+	DecideEventMatch(ctx context.Context, match model.EventMatches) error
+
+	GetTradeOffer(ctx context.Context, id int64) (*TradeOffer, error)
+	GetTradeOffers(ctx context.Context, options GetTradeOffersOptions) ([]TradeOffer, error)
+	CreateTradeOffer(ctx context.Context, offer model.TradeOffers, items []model.TradeOfferItems) (*TradeOffer, error)
+	ResolveTradeOffer(ctx context.Context, offerID int64, status string) (*TradeOffer, error)
+	AcceptTradeOffer(ctx context.Context, offerID int64) (*TradeOffer, error)
+	GetOwnershipHistory(ctx context.Context, instanceID int64) ([]OwnershipEntry, error)
+
+	GetCraftRecipe(ctx context.Context, collectionID int64) (*CraftRecipe, error)
+	SaveCraftRecipe(ctx context.Context, recipe model.CraftRecipes) (*CraftRecipe, error)
+	GetMaterialBalances(ctx context.Context, userID int64) ([]model.MaterialBalances, error)
+	SalvageCollectableInstances(ctx context.Context, userID int64, instanceIDs []int64, credits map[int64]int64) error
+	ForgeCollectableInstance(ctx context.Context, instance model.CollectableInstances, collectionID int64, cost int64) (*CollectableInstance, error)
+
+	ClaimEventSubMessage(ctx context.Context, msg model.EventsubMessages) (bool, error)
+	ReleaseEventSubMessage(ctx context.Context, messageID string) error
+
+	GetBroadcasterAuth(ctx context.Context, broadcasterID string) (*model.BroadcasterTokens, error)
+	SaveBroadcasterAuth(ctx context.Context, auth model.BroadcasterTokens) error
+
+	GetUserRoles(ctx context.Context, userID int64) ([]string, error)
+	GetRoleChanges(ctx context.Context, userID int64) ([]model.UserRoleChanges, error)
+	GrantRole(ctx context.Context, userID int64, role string, actorID int64) (bool, error)
+	RevokeRole(ctx context.Context, userID int64, role string, actorID int64) (bool, error)
+
+	AddAuditEntry(ctx context.Context, entry model.AuditLog) error
+	GetAuditLog(ctx context.Context, options GetAuditLogOptions) ([]model.AuditLog, error)
+}
+
+var (
+	_ Store = &PostgresDB{}
+	_ Store = &MemoryDB{}
+)
@@ -0,0 +1,2020 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	model "github.com/cconger/shindaggers/pkg/db/.gen/postgres/public/model"
+)
+
+// MemoryDB is an in-memory Store. It mirrors the behavior of PostgresDB closely
+// enough to run the site locally and to exercise handlers without a database.
+type MemoryDB struct {
+	mu sync.Mutex
+
+	nextID int64
+
+	users         map[int64]model.Users
+	tokens        []model.UserTokens
+	collections   map[int64]model.Collections
+	collectables  map[int64]model.Collectables
+	editions      map[int64]model.Editions
+	instances     map[int64]model.CollectableInstances
+	equipped      map[int64]model.UserEquipCollectableInstance
+	imageUploads  map[int64]model.ImageUploads
+	weightHistory []model.CollectionWeightHistory
+	fights        map[int64]model.Fights
+	fightOutcomes []model.FightOutcomes
+	events        map[int64]model.Events
+	eventMatches  []model.EventMatches
+	tradeOffers   map[int64]model.TradeOffers
+	tradeItems    []model.TradeOfferItems
+	ledger        []model.OwnershipLedger
+	craftRecipes  map[int64]model.CraftRecipes
+	materials     map[[2]int64]model.MaterialBalances
+
+	eventSubMessages  map[string]model.EventsubMessages
+	broadcasterTokens map[string]model.BroadcasterTokens
+	roles             map[int64][]model.UserRoles
+	roleChanges       []model.UserRoleChanges
+	auditLog          []model.AuditLog
+}
+
+func NewMemoryDB() *MemoryDB {
+	return &MemoryDB{
+		nextID:       1,
+		users:        map[int64]model.Users{},
+		collections:  map[int64]model.Collections{},
+		collectables: map[int64]model.Collectables{},
+		editions:     map[int64]model.Editions{},
+		instances:    map[int64]model.CollectableInstances{},
+		equipped:     map[int64]model.UserEquipCollectableInstance{},
+		imageUploads: map[int64]model.ImageUploads{},
+		fights:       map[int64]model.Fights{},
+		events:       map[int64]model.Events{},
+		tradeOffers:  map[int64]model.TradeOffers{},
+		craftRecipes: map[int64]model.CraftRecipes{},
+		materials:    map[[2]int64]model.MaterialBalances{},
+
+		eventSubMessages:  map[string]model.EventsubMessages{},
+		broadcasterTokens: map[string]model.BroadcasterTokens{},
+		roles:             map[int64][]model.UserRoles{},
+	}
+}
+
+// genID returns a new id for rows that postgres would assign a serial id to.
+// Must be called with mu held.
+func (m *MemoryDB) genID() int64 {
+	id := m.nextID
+	m.nextID++
+	return id
+}
+
+func (m *MemoryDB) collectable(id int64) (*Collectable, bool) {
+	c, ok := m.collectables[id]
+	if !ok {
+		return nil, false
+	}
+	creator, ok := m.users[c.CreatorID]
+	if !ok {
+		return nil, false
+	}
+	res := &Collectable{
+		Collectables: c,
+		Creator:      &creator,
+	}
+	if c.ApprovedBy != nil {
+		if approver, ok := m.users[*c.ApprovedBy]; ok {
+			res.ApprovedBy = &approver
+		}
+	}
+	return res, true
+}
+
+func (m *MemoryDB) instance(id int64) (*CollectableInstance, bool) {
+	i, ok := m.instances[id]
+	if !ok {
+		return nil, false
+	}
+	c, ok := m.collectable(i.CollectableID)
+	if !ok {
+		return nil, false
+	}
+	owner, ok := m.users[i.OwnerID]
+	if !ok {
+		return nil, false
+	}
+	edition, ok := m.editions[i.EditionID]
+	if !ok {
+		return nil, false
+	}
+	return &CollectableInstance{
+		CollectableInstances: i,
+		Collectable:          c,
+		Owner:                &owner,
+		Edition:              &edition,
+	}, true
+}
+
+func sortInstancesDesc(instances []CollectableInstance) {
+	sort.SliceStable(instances, func(i, j int) bool {
+		if !instances[i].CreatedAt.Equal(instances[j].CreatedAt) {
+			return instances[i].CreatedAt.After(instances[j].CreatedAt)
+		}
+		return instances[i].ID > instances[j].ID
+	})
+}
+
+func (f InstanceFilter) matches(i *CollectableInstance) bool {
+	if f.Rarity != "" && i.Collectable.Rarity != f.Rarity {
+		return false
+	}
+	if f.ByEdition != 0 && i.EditionID != f.ByEdition {
+		return false
+	}
+	if f.Verified != nil || f.Subscriber != nil {
+		var tags struct {
+			Verified   bool `json:"verified"`
+			Subscriber bool `json:"subscriber"`
+		}
+		if i.Tags != nil {
+			json.Unmarshal([]byte(*i.Tags), &tags)
+		}
+		if f.Verified != nil && tags.Verified != *f.Verified {
+			return false
+		}
+		if f.Subscriber != nil && tags.Subscriber != *f.Subscriber {
+			return false
+		}
+	}
+	if f.Cursor != nil {
+		if i.CreatedAt.After(f.Cursor.CreatedAt) {
+			return false
+		}
+		if i.CreatedAt.Equal(f.Cursor.CreatedAt) && i.ID >= f.Cursor.ID {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *MemoryDB) GetLatestIssues(ctx context.Context, options GetLatestIssuesOptions) ([]CollectableInstance, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	dest := []CollectableInstance{}
+	for id := range m.instances {
+		i, ok := m.instance(id)
+		if !ok {
+			continue
+		}
+		if i.DeletedAt != nil || i.Collectable.DeletedAt != nil {
+			continue
+		}
+		if !options.After.IsZero() && !i.CreatedAt.After(options.After) {
+			continue
+		}
+		if options.ByCollection != 0 && (i.Collectable.CollectionID == nil || *i.Collectable.CollectionID != options.ByCollection) {
+			continue
+		}
+		if options.ByCollectable != 0 && i.CollectableID != options.ByCollectable {
+			continue
+		}
+		if !options.InstanceFilter.matches(i) {
+			continue
+		}
+		dest = append(dest, *i)
+	}
+
+	limit := int(options.Limit)
+	if limit <= 0 {
+		limit = 15
+	}
+
+	sortInstancesDesc(dest)
+	if len(dest) > limit {
+		dest = dest[:limit]
+	}
+
+	return dest, nil
+}
+
+func (m *MemoryDB) CreateCollectableInstance(ctx context.Context, instance model.CollectableInstances) (*CollectableInstance, error) {
+	m.mu.Lock()
+	if _, ok := m.instances[instance.ID]; ok {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("collectable instance %d already exists", instance.ID)
+	}
+	if m.firstEditionFull(instance) {
+		m.mu.Unlock()
+		return nil, ErrEditionFull
+	}
+	m.instances[instance.ID] = instance
+	m.recordOwnership(instance)
+	m.mu.Unlock()
+
+	instances, err := m.GetCollectableInstances(ctx, GetCollectableInstancesOptions{ByID: instance.ID})
+	if err != nil {
+		return nil, err
+	}
+	if len(instances) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return &instances[0], nil
+}
+
+func (m *MemoryDB) GetCollectableInstances(ctx context.Context, options GetCollectableInstancesOptions) ([]CollectableInstance, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	dest := []CollectableInstance{}
+	for id := range m.instances {
+		i, ok := m.instance(id)
+		if !ok {
+			continue
+		}
+		if options.ByOwner != 0 && i.OwnerID != options.ByOwner {
+			continue
+		}
+		if options.ByCollectable != 0 && i.CollectableID != options.ByCollectable {
+			continue
+		}
+		if options.ByID != 0 && i.ID != options.ByID {
+			continue
+		}
+		if !options.GetDeleted && i.DeletedAt != nil {
+			continue
+		}
+		if !options.InstanceFilter.matches(i) {
+			continue
+		}
+		dest = append(dest, *i)
+	}
+
+	sortInstancesDesc(dest)
+	if options.Limit > 0 && len(dest) > int(options.Limit) {
+		dest = dest[:options.Limit]
+	}
+
+	return dest, nil
+}
+
+func (m *MemoryDB) RevokeCollectableInstance(ctx context.Context, instanceID int64, revokerID int64, reason string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i, ok := m.instances[instanceID]
+	if !ok || i.DeletedAt != nil {
+		return ErrNotFound
+	}
+
+	now := time.Now()
+	i.DeletedAt = &now
+	i.RevokedBy = &revokerID
+	i.RevokeReason = &reason
+	m.instances[instanceID] = i
+
+	for userID, e := range m.equipped {
+		if e.InstanceID != nil && *e.InstanceID == instanceID {
+			delete(m.equipped, userID)
+		}
+	}
+
+	return nil
+}
+
+func (m *MemoryDB) RestoreCollectableInstance(ctx context.Context, instanceID int64) (*CollectableInstance, error) {
+	m.mu.Lock()
+	i, ok := m.instances[instanceID]
+	if !ok || i.DeletedAt == nil || i.SalvagedAt != nil {
+		m.mu.Unlock()
+		return nil, ErrNotFound
+	}
+
+	i.DeletedAt = nil
+	i.RevokedBy = nil
+	i.RevokeReason = nil
+	m.instances[instanceID] = i
+	m.mu.Unlock()
+
+	instances, err := m.GetCollectableInstances(ctx, GetCollectableInstancesOptions{ByID: instanceID})
+	if err != nil {
+		return nil, err
+	}
+	if len(instances) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return &instances[0], nil
+}
+
+func (m *MemoryDB) GetEquippedForUser(ctx context.Context, userID int64) (*CollectableInstance, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.equipped[userID]
+	if !ok || e.InstanceID == nil {
+		return nil, nil
+	}
+
+	i, ok := m.instance(*e.InstanceID)
+	if !ok {
+		return nil, nil
+	}
+
+	return i, nil
+}
+
+func (m *MemoryDB) SetEquipped(ctx context.Context, collectableInstanceID int64, userID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.equipped[userID] = model.UserEquipCollectableInstance{
+		UserID:     userID,
+		InstanceID: &collectableInstanceID,
+		EquippedAt: &now,
+	}
+
+	return nil
+}
+
+func (m *MemoryDB) GetCollectable(ctx context.Context, id int64, options GetCollectableOptions) (*Collectable, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.collectable(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if !options.GetDeleted && c.DeletedAt != nil {
+		return nil, ErrNotFound
+	}
+	if !options.GetUnapproved && c.ApprovedAt == nil {
+		return nil, ErrNotFound
+	}
+	if options.Name != "" && c.Name != options.Name {
+		return nil, ErrNotFound
+	}
+
+	return c, nil
+}
+
+func (m *MemoryDB) GetCollectables(ctx context.Context, options GetCollectablesOptions) ([]*Collectable, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	dest := []*Collectable{}
+	for id := range m.collectables {
+		c, ok := m.collectable(id)
+		if !ok {
+			continue
+		}
+		if options.Collection != 0 && (c.CollectionID == nil || *c.CollectionID != options.Collection) {
+			continue
+		}
+		if options.Creator != 0 && c.CreatorID != options.Creator {
+			continue
+		}
+		if options.Rarity != "" && c.Rarity != options.Rarity {
+			continue
+		}
+		if options.ReviewStatus != "" && c.ReviewStatus != options.ReviewStatus {
+			continue
+		}
+		if !options.GetDeleted && c.DeletedAt != nil {
+			continue
+		}
+		if !options.GetUnapproved && c.ApprovedAt == nil {
+			continue
+		}
+		if options.OnlyUnapproved && c.ApprovedAt != nil {
+			continue
+		}
+		dest = append(dest, c)
+	}
+
+	sort.SliceStable(dest, func(i, j int) bool {
+		return dest[i].CreatedAt.Before(dest[j].CreatedAt)
+	})
+
+	return dest, nil
+}
+
+func (m *MemoryDB) CreateCollectable(ctx context.Context, collectable model.Collectables) (*Collectable, error) {
+	m.mu.Lock()
+	if _, ok := m.collectables[collectable.ID]; ok {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("collectable %d already exists", collectable.ID)
+	}
+	collectable.CreatedAt = time.Now()
+	collectable.ReviewStatus = initialReviewStatus(collectable)
+	m.collectables[collectable.ID] = collectable
+	m.mu.Unlock()
+
+	return m.GetCollectable(ctx, collectable.ID, GetCollectableOptions{
+		GetUnapproved: true,
+	})
+}
+
+func (m *MemoryDB) UpdateCollectable(ctx context.Context, collectable model.Collectables) (*Collectable, error) {
+	m.mu.Lock()
+	c, ok := m.collectables[collectable.ID]
+	if !ok {
+		m.mu.Unlock()
+		return nil, ErrNotFound
+	}
+	c.Name = collectable.Name
+	c.CreatorID = collectable.CreatorID
+	c.Rarity = collectable.Rarity
+	c.Imagepath = collectable.Imagepath
+	m.collectables[c.ID] = c
+	m.mu.Unlock()
+
+	return m.GetCollectable(ctx, collectable.ID, GetCollectableOptions{
+		GetUnapproved: true,
+	})
+}
+
+func (m *MemoryDB) ApproveCollectable(ctx context.Context, collectableID int64, approverID int64) (*Collectable, error) {
+	m.mu.Lock()
+	c, ok := m.collectables[collectableID]
+	if !ok {
+		m.mu.Unlock()
+		return nil, ErrNotFound
+	}
+	now := time.Now()
+	c.ApprovedAt = &now
+	c.ApprovedBy = &approverID
+	c.ReviewStatus = ReviewApproved
+	c.ReviewFeedback = nil
+	c.ReviewedAt = &now
+	c.ReviewedBy = &approverID
+	m.collectables[c.ID] = c
+	m.mu.Unlock()
+
+	return m.GetCollectable(ctx, collectableID, GetCollectableOptions{
+		GetUnapproved: true,
+	})
+}
+
+func (m *MemoryDB) ReviewCollectable(ctx context.Context, collectableID int64, reviewerID int64, status string, feedback string) (*Collectable, error) {
+	m.mu.Lock()
+	c, ok := m.collectables[collectableID]
+	if !ok || c.ApprovedAt != nil || c.DeletedAt != nil {
+		m.mu.Unlock()
+		return nil, ErrNotFound
+	}
+	now := time.Now()
+	c.ReviewStatus = status
+	c.ReviewFeedback = &feedback
+	c.ReviewedBy = &reviewerID
+	c.ReviewedAt = &now
+	m.collectables[c.ID] = c
+	m.mu.Unlock()
+
+	return m.GetCollectable(ctx, collectableID, GetCollectableOptions{
+		GetUnapproved: true,
+	})
+}
+
+func (m *MemoryDB) ResubmitCollectable(ctx context.Context, collectable model.Collectables) (*Collectable, error) {
+	m.mu.Lock()
+	c, ok := m.collectables[collectable.ID]
+	if !ok || c.CreatorID != collectable.CreatorID || c.ApprovedAt != nil || c.DeletedAt != nil {
+		m.mu.Unlock()
+		return nil, ErrNotFound
+	}
+	c.Name = collectable.Name
+	c.CollectionID = collectable.CollectionID
+	c.Rarity = collectable.Rarity
+	c.Imagepath = collectable.Imagepath
+	c.ReviewStatus = ReviewPending
+	m.collectables[c.ID] = c
+	m.mu.Unlock()
+
+	return m.GetCollectable(ctx, collectable.ID, GetCollectableOptions{
+		GetUnapproved: true,
+	})
+}
+
+func (m *MemoryDB) DeleteCollectable(ctx context.Context, collectableID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.collectables[collectableID]
+	if !ok {
+		return nil
+	}
+	now := time.Now()
+	c.DeletedAt = &now
+	m.collectables[c.ID] = c
+
+	return nil
+}
+
+func (m *MemoryDB) SearchUsers(ctx context.Context, search string) ([]User, error) {
+	re, err := regexp.Compile("(?i)" + search)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	dest := []User{}
+	for _, u := range m.users {
+		if re.MatchString(u.Name) {
+			dest = append(dest, User{Users: u})
+		}
+	}
+
+	sort.Slice(dest, func(i, j int) bool {
+		return dest[i].ID < dest[j].ID
+	})
+
+	return dest, nil
+}
+
+func (m *MemoryDB) GetUser(ctx context.Context, options GetUserOptions) (*User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ids := make([]int64, 0, len(m.users))
+	for id := range m.users {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	for _, id := range ids {
+		u := m.users[id]
+		if options.ID != 0 && u.ID != options.ID {
+			continue
+		}
+		if options.TwitchID != "" && (u.TwitchID == nil || *u.TwitchID != options.TwitchID) {
+			continue
+		}
+		if options.Username != "" && u.Name != options.Username {
+			continue
+		}
+		return &User{Users: u}, nil
+	}
+
+	return nil, ErrNotFound
+}
+
+func (m *MemoryDB) CreateUser(ctx context.Context, user User) (*User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[user.ID]; ok {
+		return nil, fmt.Errorf("user %d already exists", user.ID)
+	}
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = time.Now()
+	}
+	m.users[user.ID] = user.Users
+
+	return &user, nil
+}
+
+func (m *MemoryDB) UpdateUser(ctx context.Context, user User) (*User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[user.ID]; !ok {
+		return nil, ErrNotFound
+	}
+	m.users[user.ID] = user.Users
+
+	return &user, nil
+}
+
+func (m *MemoryDB) GetAuth(ctx context.Context, token []byte) (*UserAuth, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, t := range m.tokens {
+		if bytes.Equal(t.Token, token) {
+			return userAuthFromUserTokens(t), nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+func (m *MemoryDB) SaveAuth(ctx context.Context, auth UserAuth) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	t := model.UserTokens{
+		UserID:           auth.UserID,
+		Token:            auth.Token,
+		AccessToken:      &auth.AccessToken,
+		RefreshToken:     &auth.RefreshToken,
+		ExpiresAt:        &auth.ExpiresAt,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+		SessionExpiresAt: auth.SessionExpiresAt,
+	}
+
+	for i, existing := range m.tokens {
+		if bytes.Equal(existing.Token, auth.Token) {
+			t.CreatedAt = existing.CreatedAt
+			m.tokens[i] = t
+			return nil
+		}
+	}
+	m.tokens = append(m.tokens, t)
+
+	return nil
+}
+
+func (m *MemoryDB) RenewAuth(ctx context.Context, token []byte, sessionExpiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, t := range m.tokens {
+		if bytes.Equal(t.Token, token) {
+			m.tokens[i].SessionExpiresAt = sessionExpiresAt
+		}
+	}
+
+	return nil
+}
+
+func (m *MemoryDB) DeleteAuth(ctx context.Context, token []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	kept := m.tokens[:0]
+	for _, t := range m.tokens {
+		if !bytes.Equal(t.Token, token) {
+			kept = append(kept, t)
+		}
+	}
+	m.tokens = kept
+
+	return nil
+}
+
+func (m *MemoryDB) DeleteAuthForUser(ctx context.Context, userID int64) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var n int64
+	kept := m.tokens[:0]
+	for _, t := range m.tokens {
+		if t.UserID == userID {
+			n++
+			continue
+		}
+		kept = append(kept, t)
+	}
+	m.tokens = kept
+
+	return n, nil
+}
+
+func (m *MemoryDB) CreateImageUpload(ctx context.Context, imageID int64, user int64, name, uploadname string, phash int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.imageUploads[imageID]; ok {
+		return fmt.Errorf("image upload %d already exists", imageID)
+	}
+	m.imageUploads[imageID] = model.ImageUploads{
+		ID:         imageID,
+		UserID:     user,
+		Imagepath:  name,
+		UploadName: &uploadname,
+		UploadedAt: time.Now(),
+		Phash:      &phash,
+	}
+
+	return nil
+}
+
+func (m *MemoryDB) GetOrphanedImageUploads(ctx context.Context, uploadedBefore time.Time) ([]model.ImageUploads, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	used := map[string]bool{}
+	for _, c := range m.collectables {
+		used[c.Imagepath] = true
+	}
+
+	var uploads []model.ImageUploads
+	for _, u := range m.imageUploads {
+		if u.UploadedAt.Before(uploadedBefore) && !used[u.Imagepath] {
+			uploads = append(uploads, u)
+		}
+	}
+	sort.Slice(uploads, func(i, j int) bool {
+		return uploads[i].UploadedAt.Before(uploads[j].UploadedAt)
+	})
+	return uploads, nil
+}
+
+func (m *MemoryDB) DeleteImageUpload(ctx context.Context, imageID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.imageUploads, imageID)
+	return nil
+}
+
+func (m *MemoryDB) GetUnhashedImageUploads(ctx context.Context) ([]model.ImageUploads, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var uploads []model.ImageUploads
+	for _, u := range m.imageUploads {
+		if u.Phash == nil {
+			uploads = append(uploads, u)
+		}
+	}
+	sort.Slice(uploads, func(i, j int) bool {
+		return uploads[i].UploadedAt.Before(uploads[j].UploadedAt)
+	})
+	return uploads, nil
+}
+
+func (m *MemoryDB) GetCollectablesWithoutImageUpload(ctx context.Context) ([]model.Collectables, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	uploaded := map[string]bool{}
+	for _, u := range m.imageUploads {
+		uploaded[u.Imagepath] = true
+	}
+
+	var collectables []model.Collectables
+	for _, c := range m.collectables {
+		if !uploaded[c.Imagepath] {
+			collectables = append(collectables, c)
+		}
+	}
+	sort.Slice(collectables, func(i, j int) bool {
+		return collectables[i].ID < collectables[j].ID
+	})
+	return collectables, nil
+}
+
+func (m *MemoryDB) SetImageUploadHash(ctx context.Context, imageID int64, phash int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	u, ok := m.imageUploads[imageID]
+	if !ok {
+		return ErrNotFound
+	}
+	u.Phash = &phash
+	m.imageUploads[imageID] = u
+	return nil
+}
+
+func (m *MemoryDB) GetImageHashes(ctx context.Context, imagepaths []string) (map[string]int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	hashes := map[string]int64{}
+	for _, u := range m.imageUploads {
+		if u.Phash != nil && slices.Contains(imagepaths, u.Imagepath) {
+			hashes[u.Imagepath] = *u.Phash
+		}
+	}
+	return hashes, nil
+}
+
+func (m *MemoryDB) GetWeights(ctx context.Context, collectionID int64) ([]*PullWeight, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.collections[collectionID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	out := []*PullWeight{}
+	if c.Weights == nil {
+		return out, nil
+	}
+
+	w := make(map[string]int)
+	err := json.Unmarshal([]byte(*c.Weights), &w)
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range w {
+		out = append(out, &PullWeight{
+			Rarity: k,
+			Weight: v,
+		})
+	}
+
+	return out, nil
+}
+
+func (m *MemoryDB) UpdateWeights(ctx context.Context, collectionID int64, weights map[string]int, changedBy int64) error {
+	encoded, err := json.Marshal(weights)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.collections[collectionID]
+	if !ok {
+		return ErrNotFound
+	}
+
+	var version int64
+	for _, h := range m.weightHistory {
+		if h.CollectionID == collectionID && h.Version > version {
+			version = h.Version
+		}
+	}
+
+	now := time.Now()
+	if version == 0 && c.Weights != nil {
+		version++
+		m.weightHistory = append(m.weightHistory, model.CollectionWeightHistory{
+			ID:           m.genID(),
+			CollectionID: collectionID,
+			Version:      version,
+			Weights:      *c.Weights,
+			CreatedAt:    now,
+		})
+	}
+
+	version++
+	m.weightHistory = append(m.weightHistory, model.CollectionWeightHistory{
+		ID:           m.genID(),
+		CollectionID: collectionID,
+		Version:      version,
+		Weights:      string(encoded),
+		ChangedBy:    &changedBy,
+		CreatedAt:    now,
+	})
+
+	w := string(encoded)
+	c.Weights = &w
+	c.UpdatedAt = now
+	m.collections[collectionID] = c
+
+	return nil
+}
+
+func (m *MemoryDB) GetWeightHistory(ctx context.Context, collectionID int64) ([]WeightHistory, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	dest := []WeightHistory{}
+	for _, h := range m.weightHistory {
+		if h.CollectionID != collectionID {
+			continue
+		}
+		wh := WeightHistory{CollectionWeightHistory: h}
+		if h.ChangedBy != nil {
+			if u, ok := m.users[*h.ChangedBy]; ok {
+				wh.ChangedBy = &u
+			}
+		}
+		dest = append(dest, wh)
+	}
+
+	sort.Slice(dest, func(i, j int) bool {
+		return dest[i].Version > dest[j].Version
+	})
+
+	return dest, nil
+}
+
+func (m *MemoryDB) collection(id int64) (*Collection, bool) {
+	c, ok := m.collections[id]
+	if !ok {
+		return nil, false
+	}
+	creator, ok := m.users[c.CreatorID]
+	if !ok {
+		return nil, false
+	}
+	return &Collection{
+		Collections: c,
+		Creator:     &creator,
+	}, true
+}
+
+func (m *MemoryDB) GetCollections(ctx context.Context, options GetCollectionsOptions) ([]*Collection, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	dest := []*Collection{}
+	for id := range m.collections {
+		c, ok := m.collection(id)
+		if !ok {
+			continue
+		}
+		released := c.ActiveAt != nil && !c.ActiveAt.After(now)
+		if (!options.GetUnreleased || options.OnlyActive) && !released {
+			continue
+		}
+		if options.OnlyActive && !c.IsActive(now) {
+			continue
+		}
+		dest = append(dest, c)
+	}
+
+	sort.SliceStable(dest, func(i, j int) bool {
+		a, b := dest[i], dest[j]
+		if a.ActiveAt == nil || b.ActiveAt == nil {
+			if a.ActiveAt != nil || b.ActiveAt != nil {
+				return a.ActiveAt != nil
+			}
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		if !a.ActiveAt.Equal(*b.ActiveAt) {
+			return a.ActiveAt.After(*b.ActiveAt)
+		}
+		return a.CreatedAt.After(b.CreatedAt)
+	})
+
+	return dest, nil
+}
+
+func (m *MemoryDB) GetCollection(ctx context.Context, id int64) (*Collection, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.collection(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return c, nil
+}
+
+func (m *MemoryDB) CreateCollection(ctx context.Context, collection model.Collections) (*Collection, error) {
+	m.mu.Lock()
+	if _, ok := m.collections[collection.ID]; ok {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("collection %d already exists", collection.ID)
+	}
+	now := time.Now()
+	collection.CreatedAt = now
+	collection.UpdatedAt = now
+	m.collections[collection.ID] = collection
+	m.mu.Unlock()
+
+	return m.GetCollection(ctx, collection.ID)
+}
+
+func (m *MemoryDB) UpdateCollection(ctx context.Context, collection model.Collections) (*Collection, error) {
+	m.mu.Lock()
+	c, ok := m.collections[collection.ID]
+	if !ok {
+		m.mu.Unlock()
+		return nil, ErrNotFound
+	}
+	c.Name = collection.Name
+	c.ActiveAt = collection.ActiveAt
+	c.RetiredAt = collection.RetiredAt
+	c.UpdatedAt = time.Now()
+	m.collections[c.ID] = c
+	m.mu.Unlock()
+
+	return m.GetCollection(ctx, collection.ID)
+}
+
+func (m *MemoryDB) GetEditions(ctx context.Context, options GetEditionsOptions) ([]*Edition, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	dest := []*Edition{}
+	for _, e := range m.editions {
+		edition := &Edition{Editions: e}
+		if options.OnlyActive && !edition.IsActive(now) {
+			continue
+		}
+		dest = append(dest, edition)
+	}
+
+	sort.SliceStable(dest, func(i, j int) bool {
+		a, b := dest[i], dest[j]
+		if a.ActiveAt == nil || b.ActiveAt == nil {
+			if a.ActiveAt != nil || b.ActiveAt != nil {
+				return a.ActiveAt != nil
+			}
+			return a.ID < b.ID
+		}
+		if !a.ActiveAt.Equal(*b.ActiveAt) {
+			return a.ActiveAt.After(*b.ActiveAt)
+		}
+		return a.ID < b.ID
+	})
+
+	return dest, nil
+}
+
+func (m *MemoryDB) GetEdition(ctx context.Context, id int64) (*Edition, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.editions[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return &Edition{Editions: e}, nil
+}
+
+func (m *MemoryDB) CreateEdition(ctx context.Context, edition model.Editions) (*Edition, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.editions[edition.ID]; ok {
+		return nil, fmt.Errorf("edition %d already exists", edition.ID)
+	}
+	edition.CreatedAt = time.Now()
+	m.editions[edition.ID] = edition
+
+	return &Edition{Editions: edition}, nil
+}
+
+func (m *MemoryDB) UpdateEdition(ctx context.Context, edition model.Editions) (*Edition, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.editions[edition.ID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	e.Name = edition.Name
+	e.CollectionID = edition.CollectionID
+	e.FirstN = edition.FirstN
+	e.ActiveAt = edition.ActiveAt
+	e.RetiredAt = edition.RetiredAt
+	m.editions[e.ID] = e
+
+	return &Edition{Editions: e}, nil
+}
+
+func (m *MemoryDB) GetEditionCounts(ctx context.Context, options GetEditionCountsOptions) ([]EditionCount, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	type key struct {
+		collectableID int64
+		editionID     int64
+	}
+	counts := map[key]int64{}
+	for _, i := range m.instances {
+		if i.DeletedAt != nil {
+			continue
+		}
+		c, ok := m.collectables[i.CollectableID]
+		if !ok {
+			continue
+		}
+		if _, ok := m.editions[i.EditionID]; !ok {
+			continue
+		}
+		if options.ByCollection != 0 && (c.CollectionID == nil || *c.CollectionID != options.ByCollection) {
+			continue
+		}
+		if options.ByCollectable != 0 && i.CollectableID != options.ByCollectable {
+			continue
+		}
+		if options.ByOwner != 0 && i.OwnerID != options.ByOwner {
+			continue
+		}
+		counts[key{i.CollectableID, i.EditionID}]++
+	}
+
+	dest := []EditionCount{}
+	for k, count := range counts {
+		dest = append(dest, EditionCount{
+			CollectableID: k.collectableID,
+			EditionID:     k.editionID,
+			EditionName:   m.editions[k.editionID].Name,
+			Count:         count,
+		})
+	}
+
+	sort.Slice(dest, func(i, j int) bool {
+		if dest[i].CollectableID != dest[j].CollectableID {
+			return dest[i].CollectableID < dest[j].CollectableID
+		}
+		return dest[i].EditionID < dest[j].EditionID
+	})
+
+	return dest, nil
+}
+
+func (m *MemoryDB) CountCollectableInstances(ctx context.Context, collectableID int64) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.countInstances(collectableID), nil
+}
+
+// countInstances counts every instance of a collectable, deleted or not. Must
+// be called with mu held.
+func (m *MemoryDB) countInstances(collectableID int64) int64 {
+	var count int64
+	for _, i := range m.instances {
+		if i.CollectableID == collectableID {
+			count++
+		}
+	}
+	return count
+}
+
+// firstEditionFull reports whether instance is in a first edition with no
+// room left. Must be called with mu held.
+func (m *MemoryDB) firstEditionFull(instance model.CollectableInstances) bool {
+	e, ok := m.editions[instance.EditionID]
+	if !ok || e.FirstN == nil {
+		return false
+	}
+	return m.countInstances(instance.CollectableID) >= *e.FirstN
+}
+
+// fight assembles a fight with its outcomes. Must be called with mu held.
+func (m *MemoryDB) fight(id int64) (*Fight, bool) {
+	f, ok := m.fights[id]
+	if !ok {
+		return nil, false
+	}
+
+	res := &Fight{
+		Fights:   f,
+		Outcomes: []FightOutcome{},
+	}
+	for _, o := range m.fightOutcomes {
+		if o.FightID != id {
+			continue
+		}
+		outcome := FightOutcome{
+			FightOutcomes: o,
+		}
+		if u, ok := m.users[o.UserID]; ok {
+			outcome.User = &u
+		}
+		if o.CollectableID != nil {
+			if c, ok := m.collectable(*o.CollectableID); ok {
+				outcome.Collectable = c
+			}
+		}
+		res.Outcomes = append(res.Outcomes, outcome)
+	}
+	sort.Slice(res.Outcomes, func(i, j int) bool {
+		return res.Outcomes[i].UserID < res.Outcomes[j].UserID
+	})
+
+	return res, true
+}
+
+func (m *MemoryDB) GetFight(ctx context.Context, id int64) (*Fight, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, ok := m.fight(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return f, nil
+}
+
+func (m *MemoryDB) GetFightByClientID(ctx context.Context, clientID string) (*Fight, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, f := range m.fights {
+		if f.ClientID != nil && *f.ClientID == clientID {
+			fight, _ := m.fight(id)
+			return fight, nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+func (m *MemoryDB) GetFights(ctx context.Context, options GetFightsOptions) ([]Fight, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	limit := options.Limit
+	if limit <= 0 {
+		limit = 25
+	}
+
+	fought := map[int64]bool{}
+	for _, o := range m.fightOutcomes {
+		if options.ByUser != 0 && o.UserID != options.ByUser {
+			continue
+		}
+		f := m.fights[o.FightID]
+		if options.ByEvent != 0 && (f.EventID == nil || *f.EventID != options.ByEvent) {
+			continue
+		}
+		fought[o.FightID] = true
+	}
+
+	dest := []Fight{}
+	for id := range fought {
+		if f, ok := m.fight(id); ok {
+			dest = append(dest, *f)
+		}
+	}
+
+	sort.Slice(dest, func(i, j int) bool {
+		if !dest[i].CreatedAt.Equal(dest[j].CreatedAt) {
+			return dest[i].CreatedAt.After(dest[j].CreatedAt)
+		}
+		return dest[i].ID > dest[j].ID
+	})
+	if int64(len(dest)) > limit {
+		dest = dest[:limit]
+	}
+
+	return dest, nil
+}
+
+func (m *MemoryDB) CreateFight(ctx context.Context, fight model.Fights, outcomes []model.FightOutcomes) (*Fight, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.fights[fight.ID]; ok {
+		return nil, fmt.Errorf("fight %d already exists", fight.ID)
+	}
+	if fight.ClientID != nil {
+		for _, f := range m.fights {
+			if f.ClientID != nil && *f.ClientID == *fight.ClientID {
+				return nil, ErrFightExists
+			}
+		}
+	}
+	if fight.CreatedAt.IsZero() {
+		fight.CreatedAt = time.Now()
+	}
+	m.fights[fight.ID] = fight
+	m.fightOutcomes = append(m.fightOutcomes, outcomes...)
+
+	f, _ := m.fight(fight.ID)
+	return f, nil
+}
+
+func (m *MemoryDB) GetFightStats(ctx context.Context, userID int64) (*FightStats, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := FightStats{}
+	for _, o := range m.fightOutcomes {
+		if o.UserID != userID {
+			continue
+		}
+		switch o.Outcome {
+		case FightOutcomeWin:
+			stats.Wins++
+		case FightOutcomeLoss:
+			stats.Losses++
+		case FightOutcomeDraw:
+			stats.Draws++
+		}
+	}
+
+	return &stats, nil
+}
+
+func (m *MemoryDB) event(id int64) (*Event, bool) {
+	e, ok := m.events[id]
+	if !ok {
+		return nil, false
+	}
+	res := &Event{
+		Events: e,
+	}
+	if creator, ok := m.users[e.CreatorID]; ok {
+		res.Creator = &creator
+	}
+	return res, true
+}
+
+func (m *MemoryDB) GetEvents(ctx context.Context, options GetEventsOptions) ([]*Event, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	dest := []*Event{}
+	for id := range m.events {
+		e, ok := m.event(id)
+		if !ok {
+			continue
+		}
+		if options.OnlyActive && !e.IsActive(now) {
+			continue
+		}
+		dest = append(dest, e)
+	}
+
+	sort.Slice(dest, func(i, j int) bool {
+		if !dest[i].CreatedAt.Equal(dest[j].CreatedAt) {
+			return dest[i].CreatedAt.After(dest[j].CreatedAt)
+		}
+		return dest[i].ID > dest[j].ID
+	})
+
+	return dest, nil
+}
+
+func (m *MemoryDB) GetEvent(ctx context.Context, options GetEventOptions) (*Event, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if options.ID == 0 && options.Slug == "" {
+		return nil, fmt.Errorf("must specify ID or Slug")
+	}
+
+	for id, e := range m.events {
+		if options.ID != 0 && e.ID != options.ID {
+			continue
+		}
+		if options.ID == 0 && e.Slug != options.Slug {
+			continue
+		}
+		if res, ok := m.event(id); ok {
+			return res, nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+func (m *MemoryDB) CreateEvent(ctx context.Context, event model.Events) (*Event, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, e := range m.events {
+		if e.Slug == event.Slug {
+			return nil, fmt.Errorf("event slug %s already exists", event.Slug)
+		}
+	}
+
+	event.CreatedAt = time.Now()
+	m.events[event.ID] = event
+
+	e, _ := m.event(event.ID)
+	return e, nil
+}
+
+func (m *MemoryDB) UpdateEvent(ctx context.Context, event model.Events) (*Event, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, ok := m.events[event.ID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	existing.Name = event.Name
+	existing.Description = event.Description
+	existing.ClosedAt = event.ClosedAt
+	m.events[event.ID] = existing
+
+	e, _ := m.event(event.ID)
+	return e, nil
+}
+
+func (m *MemoryDB) GetEventLeaderboard(ctx context.Context, eventID int64) ([]LeaderboardEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entries := map[int64]*LeaderboardEntry{}
+	for _, o := range m.fightOutcomes {
+		f := m.fights[o.FightID]
+		if f.EventID == nil || *f.EventID != eventID {
+			continue
+		}
+
+		e, ok := entries[o.UserID]
+		if !ok {
+			e = &LeaderboardEntry{
+				UserID:   o.UserID,
+				UserName: m.users[o.UserID].Name,
+			}
+			entries[o.UserID] = e
+		}
+
+		switch o.Outcome {
+		case FightOutcomeWin:
+			e.Wins++
+		case FightOutcomeLoss:
+			e.Losses++
+		case FightOutcomeDraw:
+			e.Draws++
+		}
+	}
+
+	dest := make([]LeaderboardEntry, 0, len(entries))
+	for _, e := range entries {
+		dest = append(dest, *e)
+	}
+
+	sort.Slice(dest, func(i, j int) bool {
+		if dest[i].Wins != dest[j].Wins {
+			return dest[i].Wins > dest[j].Wins
+		}
+		if dest[i].Losses != dest[j].Losses {
+			return dest[i].Losses < dest[j].Losses
+		}
+		return dest[i].UserName < dest[j].UserName
+	})
+
+	return dest, nil
+}
+
+func (m *MemoryDB) GetEventMatches(ctx context.Context, eventID int64) ([]EventMatch, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	dest := []EventMatch{}
+	for _, match := range m.eventMatches {
+		if match.EventID != eventID {
+			continue
+		}
+		em := EventMatch{EventMatches: match}
+		if match.FirstUserID != nil {
+			u := m.users[*match.FirstUserID]
+			em.FirstUser = &u
+		}
+		if match.SecondUserID != nil {
+			u := m.users[*match.SecondUserID]
+			em.SecondUser = &u
+		}
+		dest = append(dest, em)
+	}
+
+	sort.Slice(dest, func(i, j int) bool {
+		if dest[i].Round != dest[j].Round {
+			return dest[i].Round < dest[j].Round
+		}
+		return dest[i].Slot < dest[j].Slot
+	})
+
+	return dest, nil
+}
+
+func (m *MemoryDB) CreateEventMatches(ctx context.Context, matches []model.EventMatches) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, match := range matches {
+		for _, existing := range m.eventMatches {
+			if existing.EventID == match.EventID && existing.Round == match.Round && existing.Slot == match.Slot {
+				return fmt.Errorf("event %d already has match %d/%d", match.EventID, match.Round, match.Slot)
+			}
+		}
+	}
+	m.eventMatches = append(m.eventMatches, matches...)
+
+	return nil
+}
+
+func (m *MemoryDB) DecideEventMatch(ctx context.Context, match model.EventMatches) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, existing := range m.eventMatches {
+		if existing.EventID != match.EventID || existing.Round != match.Round || existing.Slot != match.Slot {
+			continue
+		}
+		if existing.WinnerID != nil {
+			return ErrNotFound
+		}
+		m.eventMatches[i].WinnerID = match.WinnerID
+		m.eventMatches[i].FightID = match.FightID
+		return nil
+	}
+
+	return ErrNotFound
+}
+
+// recordOwnership appends the ledger entry for a newly created instance. Must
+// be called with mu held.
+func (m *MemoryDB) recordOwnership(instance model.CollectableInstances) {
+	transType := TransTypePull
+	if instance.IssuedBy != nil {
+		transType = TransTypeIssue
+	}
+	m.recordOwnershipAs(instance, transType)
+}
+
+func (m *MemoryDB) recordOwnershipAs(instance model.CollectableInstances, transType string) {
+	m.ledger = append(m.ledger, model.OwnershipLedger{
+		ID:         m.genID(),
+		InstanceID: instance.ID,
+		ToUserID:   instance.OwnerID,
+		TransType:  transType,
+		CreatedAt:  instance.CreatedAt,
+	})
+}
+
+func (m *MemoryDB) tradeOffer(id int64) (*TradeOffer, bool) {
+	o, ok := m.tradeOffers[id]
+	if !ok {
+		return nil, false
+	}
+
+	res := &TradeOffer{
+		TradeOffers: o,
+		Items:       []TradeOfferItem{},
+	}
+	if u, ok := m.users[o.ProposerID]; ok {
+		res.Proposer = &u
+	}
+	if u, ok := m.users[o.RecipientID]; ok {
+		res.Recipient = &u
+	}
+	for _, item := range m.tradeItems {
+		if item.OfferID != id {
+			continue
+		}
+		i, ok := m.instance(item.InstanceID)
+		if !ok {
+			continue
+		}
+		res.Items = append(res.Items, TradeOfferItem{
+			TradeOfferItems: item,
+			Instance:        i,
+		})
+	}
+	sort.Slice(res.Items, func(i, j int) bool {
+		return res.Items[i].InstanceID < res.Items[j].InstanceID
+	})
+
+	return res, true
+}
+
+func (m *MemoryDB) GetTradeOffer(ctx context.Context, id int64) (*TradeOffer, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	o, ok := m.tradeOffer(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return o, nil
+}
+
+func (m *MemoryDB) GetTradeOffers(ctx context.Context, options GetTradeOffersOptions) ([]TradeOffer, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	dest := []TradeOffer{}
+	for id, o := range m.tradeOffers {
+		if options.ByUser != 0 && o.ProposerID != options.ByUser && o.RecipientID != options.ByUser {
+			continue
+		}
+		if options.Status != "" && o.Status != options.Status {
+			continue
+		}
+		if offer, ok := m.tradeOffer(id); ok {
+			dest = append(dest, *offer)
+		}
+	}
+
+	sort.Slice(dest, func(i, j int) bool {
+		if !dest[i].CreatedAt.Equal(dest[j].CreatedAt) {
+			return dest[i].CreatedAt.After(dest[j].CreatedAt)
+		}
+		return dest[i].ID > dest[j].ID
+	})
+
+	return dest, nil
+}
+
+func (m *MemoryDB) CreateTradeOffer(ctx context.Context, offer model.TradeOffers, items []model.TradeOfferItems) (*TradeOffer, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.tradeOffers[offer.ID]; ok {
+		return nil, fmt.Errorf("trade offer %d already exists", offer.ID)
+	}
+
+	offer.CreatedAt = time.Now()
+	m.tradeOffers[offer.ID] = offer
+	m.tradeItems = append(m.tradeItems, items...)
+
+	o, _ := m.tradeOffer(offer.ID)
+	return o, nil
+}
+
+func (m *MemoryDB) ResolveTradeOffer(ctx context.Context, offerID int64, status string) (*TradeOffer, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	o, ok := m.tradeOffers[offerID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if o.Status != TradeStatusPending {
+		return nil, ErrNotPending
+	}
+
+	now := time.Now()
+	o.Status = status
+	o.ResolvedAt = &now
+	m.tradeOffers[offerID] = o
+
+	res, _ := m.tradeOffer(offerID)
+	return res, nil
+}
+
+func (m *MemoryDB) AcceptTradeOffer(ctx context.Context, offerID int64) (*TradeOffer, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	o, ok := m.tradeOffers[offerID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if o.Status != TradeStatusPending {
+		return nil, ErrNotPending
+	}
+
+	items := []model.TradeOfferItems{}
+	for _, item := range m.tradeItems {
+		if item.OfferID != offerID {
+			continue
+		}
+		i, ok := m.instances[item.InstanceID]
+		if !ok || i.DeletedAt != nil || i.OwnerID != item.FromUserID {
+			return nil, ErrOwnershipChanged
+		}
+		items = append(items, item)
+	}
+
+	now := time.Now()
+	for _, item := range items {
+		to := o.RecipientID
+		if item.FromUserID == o.RecipientID {
+			to = o.ProposerID
+		}
+
+		i := m.instances[item.InstanceID]
+		i.OwnerID = to
+		m.instances[item.InstanceID] = i
+
+		for userID, e := range m.equipped {
+			if e.InstanceID != nil && *e.InstanceID == item.InstanceID {
+				delete(m.equipped, userID)
+			}
+		}
+
+		from := item.FromUserID
+		m.ledger = append(m.ledger, model.OwnershipLedger{
+			ID:           m.genID(),
+			InstanceID:   item.InstanceID,
+			FromUserID:   &from,
+			ToUserID:     to,
+			TransType:    TransTypeTrade,
+			TradeOfferID: &o.ID,
+			CreatedAt:    now,
+		})
+	}
+
+	o.Status = TradeStatusAccepted
+	o.ResolvedAt = &now
+	m.tradeOffers[offerID] = o
+
+	res, _ := m.tradeOffer(offerID)
+	return res, nil
+}
+
+func (m *MemoryDB) GetOwnershipHistory(ctx context.Context, instanceID int64) ([]OwnershipEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	dest := []OwnershipEntry{}
+	for _, l := range m.ledger {
+		if l.InstanceID != instanceID {
+			continue
+		}
+		entry := OwnershipEntry{
+			OwnershipLedger: l,
+		}
+		if l.FromUserID != nil {
+			if u, ok := m.users[*l.FromUserID]; ok {
+				entry.From = &u
+			}
+		}
+		if u, ok := m.users[l.ToUserID]; ok {
+			entry.To = &u
+		}
+		dest = append(dest, entry)
+	}
+
+	sort.SliceStable(dest, func(i, j int) bool {
+		return dest[i].CreatedAt.Before(dest[j].CreatedAt)
+	})
+
+	return dest, nil
+}
+
+func (m *MemoryDB) GetCraftRecipe(ctx context.Context, collectionID int64) (*CraftRecipe, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r, ok := m.craftRecipes[collectionID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return &CraftRecipe{CraftRecipes: r}, nil
+}
+
+func (m *MemoryDB) SaveCraftRecipe(ctx context.Context, recipe model.CraftRecipes) (*CraftRecipe, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	recipe.UpdatedAt = time.Now()
+	m.craftRecipes[recipe.CollectionID] = recipe
+
+	return &CraftRecipe{CraftRecipes: recipe}, nil
+}
+
+func (m *MemoryDB) GetMaterialBalances(ctx context.Context, userID int64) ([]model.MaterialBalances, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	dest := []model.MaterialBalances{}
+	for _, b := range m.materials {
+		if b.UserID == userID {
+			dest = append(dest, b)
+		}
+	}
+
+	sort.Slice(dest, func(i, j int) bool {
+		return dest[i].CollectionID < dest[j].CollectionID
+	})
+
+	return dest, nil
+}
+
+func (m *MemoryDB) SalvageCollectableInstances(ctx context.Context, userID int64, instanceIDs []int64, credits map[int64]int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, id := range instanceIDs {
+		i, ok := m.instances[id]
+		if !ok || i.DeletedAt != nil || i.OwnerID != userID {
+			return ErrOwnershipChanged
+		}
+	}
+
+	now := time.Now()
+	for _, id := range instanceIDs {
+		i := m.instances[id]
+		i.DeletedAt = &now
+		i.SalvagedAt = &now
+		m.instances[id] = i
+
+		for user, e := range m.equipped {
+			if e.InstanceID != nil && *e.InstanceID == id {
+				delete(m.equipped, user)
+			}
+		}
+	}
+
+	for collectionID, amount := range credits {
+		key := [2]int64{userID, collectionID}
+		b := m.materials[key]
+		b.UserID = userID
+		b.CollectionID = collectionID
+		b.Balance += amount
+		b.UpdatedAt = now
+		m.materials[key] = b
+	}
+
+	return nil
+}
+
+func (m *MemoryDB) ForgeCollectableInstance(ctx context.Context, instance model.CollectableInstances, collectionID int64, cost int64) (*CollectableInstance, error) {
+	m.mu.Lock()
+
+	key := [2]int64{instance.OwnerID, collectionID}
+	b, ok := m.materials[key]
+	if !ok || b.Balance < cost {
+		m.mu.Unlock()
+		return nil, ErrInsufficientMaterials
+	}
+	if _, ok := m.instances[instance.ID]; ok {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("collectable instance %d already exists", instance.ID)
+	}
+	if m.firstEditionFull(instance) {
+		m.mu.Unlock()
+		return nil, ErrEditionFull
+	}
+
+	b.Balance -= cost
+	b.UpdatedAt = time.Now()
+	m.materials[key] = b
+
+	m.instances[instance.ID] = instance
+	m.recordOwnershipAs(instance, TransTypeForge)
+	m.mu.Unlock()
+
+	instances, err := m.GetCollectableInstances(ctx, GetCollectableInstancesOptions{ByID: instance.ID})
+	if err != nil {
+		return nil, err
+	}
+	if len(instances) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return &instances[0], nil
+}
+
+func (m *MemoryDB) ClaimEventSubMessage(ctx context.Context, msg model.EventsubMessages) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.eventSubMessages[msg.MessageID]; ok {
+		return false, nil
+	}
+
+	msg.ReceivedAt = time.Now()
+	m.eventSubMessages[msg.MessageID] = msg
+
+	return true, nil
+}
+
+func (m *MemoryDB) ReleaseEventSubMessage(ctx context.Context, messageID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.eventSubMessages, messageID)
+
+	return nil
+}
+
+func (m *MemoryDB) GetBroadcasterAuth(ctx context.Context, broadcasterID string) (*model.BroadcasterTokens, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	auth, ok := m.broadcasterTokens[broadcasterID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return &auth, nil
+}
+
+func (m *MemoryDB) SaveBroadcasterAuth(ctx context.Context, auth model.BroadcasterTokens) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	auth.UpdatedAt = time.Now()
+	m.broadcasterTokens[auth.BroadcasterID] = auth
+
+	return nil
+}
+
+func (m *MemoryDB) GetUserRoles(ctx context.Context, userID int64) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	roles := []string{}
+	for _, r := range m.roles[userID] {
+		roles = append(roles, r.Role)
+	}
+	sort.Strings(roles)
+
+	return roles, nil
+}
+
+func (m *MemoryDB) GetRoleChanges(ctx context.Context, userID int64) ([]model.UserRoleChanges, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	changes := []model.UserRoleChanges{}
+	for i := len(m.roleChanges) - 1; i >= 0; i-- {
+		if m.roleChanges[i].UserID == userID {
+			changes = append(changes, m.roleChanges[i])
+		}
+	}
+
+	return changes, nil
+}
+
+func (m *MemoryDB) GrantRole(ctx context.Context, userID int64, role string, actorID int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, r := range m.roles[userID] {
+		if r.Role == role {
+			return false, nil
+		}
+	}
+
+	now := time.Now()
+	m.roles[userID] = append(m.roles[userID], model.UserRoles{
+		UserID:    userID,
+		Role:      role,
+		GrantedBy: &actorID,
+		CreatedAt: now,
+	})
+	m.recordRoleChange(userID, role, RoleChangeGrant, actorID, now)
+
+	return true, nil
+}
+
+func (m *MemoryDB) RevokeRole(ctx context.Context, userID int64, role string, actorID int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	roles := m.roles[userID]
+	for i, r := range roles {
+		if r.Role == role {
+			m.roles[userID] = append(roles[:i:i], roles[i+1:]...)
+			m.recordRoleChange(userID, role, RoleChangeRevoke, actorID, time.Now())
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// recordRoleChange must be called with mu held.
+func (m *MemoryDB) recordRoleChange(userID int64, role, action string, actorID int64, at time.Time) {
+	m.roleChanges = append(m.roleChanges, model.UserRoleChanges{
+		ID:        m.genID(),
+		UserID:    userID,
+		Role:      role,
+		Action:    action,
+		ActorID:   actorID,
+		CreatedAt: at,
+	})
+}
+
+func (m *MemoryDB) AddAuditEntry(ctx context.Context, entry model.AuditLog) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry.ID = m.genID()
+	entry.CreatedAt = time.Now()
+	m.auditLog = append(m.auditLog, entry)
+
+	return nil
+}
+
+func (m *MemoryDB) GetAuditLog(ctx context.Context, options GetAuditLogOptions) ([]model.AuditLog, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	limit := int(options.Limit)
+	if limit <= 0 {
+		limit = 100
+	}
+
+	entries := []model.AuditLog{}
+	for i := len(m.auditLog) - 1; i >= 0 && len(entries) < limit; i-- {
+		e := m.auditLog[i]
+		if options.ActorID != 0 && e.ActorID != options.ActorID {
+			continue
+		}
+		if options.TargetType != "" && e.TargetType != options.TargetType {
+			continue
+		}
+		if options.TargetID != 0 && e.TargetID != options.TargetID {
+			continue
+		}
+		if !options.Since.IsZero() && e.CreatedAt.Before(options.Since) {
+			continue
+		}
+		if !options.Until.IsZero() && !e.CreatedAt.Before(options.Until) {
+			continue
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
@@ -13,21 +13,58 @@ import (
 	"github.com/go-jet/jet/v2/qrm"
 )
 
-var ErrNotFound = errors.New("not found")
+var (
+	ErrNotFound = errors.New("not found")
+	// ErrNotPending is returned when acting on a trade offer that has already
+	// been resolved.
+	ErrNotPending = errors.New("trade offer is not pending")
+	// ErrOwnershipChanged is returned when an instance in a trade offer is no
+	// longer held by the user that offered it.
+	ErrOwnershipChanged = errors.New("ownership changed")
+	// ErrInsufficientMaterials is returned when a forge costs more than the
+	// user's material balance.
+	ErrInsufficientMaterials = errors.New("insufficient materials")
+	// ErrEditionFull is returned when creating an instance in a first edition
+	// that already has all of its instances.
+	ErrEditionFull = errors.New("edition is full")
+	// ErrFightExists is returned when creating a fight with a client id that
+	// has already been recorded.
+	ErrFightExists = errors.New("fight already recorded")
+)
 
 type UserAuth struct {
-	UserID       int64
+	UserID int64
+	// Token is the hash of the session token handed to the browser
 	Token        []byte
 	AccessToken  string
 	RefreshToken string
 	ExpiresAt    time.Time
 	UpdatedAt    time.Time
+	// SessionExpiresAt is when the session token stops being accepted
+	SessionExpiresAt time.Time
 }
 
+// DefaultEditionID is the edition given to pulls when no other edition applies.
+const DefaultEditionID = 1
+
 type Edition struct {
-	ID        int
-	Name      string
-	UpdatedAt time.Time
+	model.Editions
+}
+
+// IsActive reports whether new pulls can be given this edition.
+func (e *Edition) IsActive(at time.Time) bool {
+	if e.ActiveAt == nil || e.ActiveAt.After(at) {
+		return false
+	}
+	return e.RetiredAt == nil || e.RetiredAt.After(at)
+}
+
+// EditionCount is the number of live instances of a collectable in an edition.
+type EditionCount struct {
+	CollectableID int64
+	EditionID     int64
+	EditionName   string
+	Count         int64
 }
 
 type PullWeight struct {
@@ -36,14 +73,24 @@ type PullWeight struct {
 	Weight      int
 	UpdatedAt   time.Time
 }
+type WeightHistory struct {
+	model.CollectionWeightHistory
+
+	ChangedBy *model.Users `alias:"changer"`
+}
+
 type Collection struct {
-	ID      int64
-	Creator *User
-	Name    string
+	model.Collections `alias:"collection"`
 
-	CreatedAt time.Time
-	EnabledAt time.Time
-	RetiredAt time.Time
+	Creator *model.Users `alias:"creator"`
+}
+
+// IsActive reports whether pulls should currently draw from the collection.
+func (c *Collection) IsActive(at time.Time) bool {
+	if c.ActiveAt == nil || c.ActiveAt.After(at) {
+		return false
+	}
+	return c.RetiredAt == nil || c.RetiredAt.After(at)
 }
 
 type Collectable struct {
@@ -66,9 +113,64 @@ type PostgresDB struct {
 }
 
 type GetLatestIssuesOptions struct {
-	ByCreator    int64
-	ByCollection int64
-	After        time.Time
+	InstanceFilter
+
+	ByCreator     int64
+	ByCollection  int64
+	ByCollectable int64
+	After         time.Time
+}
+
+// InstanceFilter narrows and pages lists of collectable instances, which are
+// ordered newest first. Cursor continues after the last instance of the
+// previous page.
+type InstanceFilter struct {
+	Rarity     string
+	ByEdition  int64
+	Verified   *bool
+	Subscriber *bool
+
+	Cursor *InstanceCursor
+	Limit  int64
+}
+
+// InstanceCursor is the position of an instance in newest first order.
+type InstanceCursor struct {
+	CreatedAt time.Time
+	ID        int64
+}
+
+func (f InstanceFilter) apply(c *ConstraintBuilder, collectable *table.CollectablesTable) {
+	if f.Rarity != "" {
+		c.Add(collectable.Rarity.EQ(postgres.String(f.Rarity)))
+	}
+	if f.ByEdition != 0 {
+		c.Add(table.CollectableInstances.EditionID.EQ(postgres.Int64(f.ByEdition)))
+	}
+	if f.Verified != nil {
+		c.Add(instanceTagIs("verified", *f.Verified))
+	}
+	if f.Subscriber != nil {
+		c.Add(instanceTagIs("subscriber", *f.Subscriber))
+	}
+	if f.Cursor != nil {
+		createdAt := postgres.TimestampT(f.Cursor.CreatedAt)
+		c.Add(
+			table.CollectableInstances.CreatedAt.LT(createdAt).OR(
+				table.CollectableInstances.CreatedAt.EQ(createdAt).
+					AND(table.CollectableInstances.ID.LT(postgres.Int64(f.Cursor.ID))),
+			),
+		)
+	}
+}
+
+// instanceTagIs matches instances by one of their boolean tags, a missing tag
+// counts as false.
+func instanceTagIs(tag string, value bool) postgres.BoolExpression {
+	return postgres.RawBool(
+		"COALESCE((collectable_instances.tags ->> #tag)::boolean, false) = #value",
+		postgres.RawArgs{"#tag": tag, "#value": value},
+	)
 }
 
 type User struct {
@@ -99,19 +201,24 @@ func (db *PostgresDB) GetLatestIssues(ctx context.Context, options GetLatestIssu
 	creator := table.Users.AS("creator")
 	collectable := table.Collectables.AS("collectable")
 
-	where := []postgres.BoolExpression{
-		collectable.DeletedAt.IS_NULL(),
+	limit := options.Limit
+	if limit <= 0 {
+		limit = 15
 	}
+
+	c := ConstraintBuilder{}
+	c.Add(collectable.DeletedAt.IS_NULL())
+	c.Add(table.CollectableInstances.DeletedAt.IS_NULL())
 	if !options.After.IsZero() {
-		where = append(where, table.CollectableInstances.CreatedAt.GT(postgres.TimestampT(options.After)))
+		c.Add(table.CollectableInstances.CreatedAt.GT(postgres.TimestampT(options.After)))
 	}
 	if options.ByCollection != 0 {
-		where = append(where, collectable.CollectionID.EQ(postgres.Int64(options.ByCollection)))
+		c.Add(collectable.CollectionID.EQ(postgres.Int64(options.ByCollection)))
 	}
-
-	if len(where) == 0 {
-		where = append(where, postgres.Bool(true))
+	if options.ByCollectable != 0 {
+		c.Add(collectable.ID.EQ(postgres.Int64(options.ByCollectable)))
 	}
+	options.InstanceFilter.apply(&c, collectable)
 
 	stmt := postgres.SELECT(
 		table.CollectableInstances.AllColumns,
@@ -125,11 +232,12 @@ func (db *PostgresDB) GetLatestIssues(ctx context.Context, options GetLatestIssu
 			INNER_JOIN(creator, collectable.CreatorID.EQ(creator.ID)).
 			INNER_JOIN(owner, table.CollectableInstances.OwnerID.EQ(owner.ID)).
 			INNER_JOIN(table.Editions, table.CollectableInstances.EditionID.EQ(table.Editions.ID)),
-	).WHERE(
-		postgres.AND(where...),
-	).ORDER_BY(
+	)
+
+	stmt = c.Apply(stmt).ORDER_BY(
 		table.CollectableInstances.CreatedAt.DESC(),
-	).LIMIT(15)
+		table.CollectableInstances.ID.DESC(),
+	).LIMIT(limit)
 
 	dest := []CollectableInstance{}
 
@@ -141,23 +249,61 @@ func (db *PostgresDB) GetLatestIssues(ctx context.Context, options GetLatestIssu
 	return dest, nil
 }
 
-func (db *PostgresDB) CreateCollectableInstance(ctx context.Context, instance model.CollectableInstances) (*CollectableInstance, error) {
-	stmt := table.CollectableInstances.
+// insertCollectableInstance creates an instance along with the ledger entry
+// recording how its first owner got it.
+func insertCollectableInstance(ctx context.Context, tx *sql.Tx, instance model.CollectableInstances, transType string) error {
+	err := checkFirstEdition(ctx, tx, instance)
+	if err != nil {
+		return err
+	}
+
+	_, err = table.CollectableInstances.
 		INSERT(table.CollectableInstances.AllColumns).
 		MODEL(instance).
-		RETURNING(table.CollectableInstances.ID)
+		ExecContext(ctx, tx)
+	if err != nil {
+		return err
+	}
 
-	id := []int64{}
-	err := stmt.QueryContext(ctx, db.DB, &id)
+	_, err = table.OwnershipLedger.
+		INSERT(
+			table.OwnershipLedger.InstanceID,
+			table.OwnershipLedger.ToUserID,
+			table.OwnershipLedger.TransType,
+			table.OwnershipLedger.CreatedAt,
+		).
+		MODEL(model.OwnershipLedger{
+			InstanceID: instance.ID,
+			ToUserID:   instance.OwnerID,
+			TransType:  transType,
+			CreatedAt:  instance.CreatedAt,
+		}).
+		ExecContext(ctx, tx)
+	return err
+}
+
+func (db *PostgresDB) CreateCollectableInstance(ctx context.Context, instance model.CollectableInstances) (*CollectableInstance, error) {
+	tx, err := db.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback()
 
-	if len(id) != 1 {
-		return nil, errors.New("expected one id to be returned")
+	transType := TransTypePull
+	if instance.IssuedBy != nil {
+		transType = TransTypeIssue
+	}
+	err = insertCollectableInstance(ctx, tx, instance, transType)
+	if err != nil {
+		return nil, err
 	}
 
-	instances, err := db.GetCollectableInstances(ctx, GetCollectableInstancesOptions{ByID: id[0]})
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	instances, err := db.GetCollectableInstances(ctx, GetCollectableInstancesOptions{ByID: instance.ID})
 	if err != nil {
 		return nil, err
 	}
@@ -169,6 +315,8 @@ func (db *PostgresDB) CreateCollectableInstance(ctx context.Context, instance mo
 }
 
 type GetCollectableInstancesOptions struct {
+	InstanceFilter
+
 	ByOwner       int64
 	ByID          int64
 	ByCollectable int64
@@ -208,10 +356,17 @@ func (db *PostgresDB) GetCollectableInstances(ctx context.Context, options GetCo
 	if !options.GetDeleted {
 		c.Add(table.CollectableInstances.DeletedAt.IS_NULL())
 	}
+	options.InstanceFilter.apply(&c, collectable)
 
 	stmt = c.Apply(stmt)
 
-	stmt.ORDER_BY(table.CollectableInstances.CreatedAt.DESC())
+	stmt = stmt.ORDER_BY(
+		table.CollectableInstances.CreatedAt.DESC(),
+		table.CollectableInstances.ID.DESC(),
+	)
+	if options.Limit > 0 {
+		stmt = stmt.LIMIT(options.Limit)
+	}
 
 	dest := []CollectableInstance{}
 	err := stmt.QueryContext(ctx, db.DB, &dest)
@@ -222,6 +377,99 @@ func (db *PostgresDB) GetCollectableInstances(ctx context.Context, options GetCo
 	return dest, nil
 }
 
+// RevokeCollectableInstance soft deletes an issued collectable, recording who
+// revoked it and why. If the instance is currently equipped it is unequipped.
+func (db *PostgresDB) RevokeCollectableInstance(ctx context.Context, instanceID int64, revokerID int64, reason string) error {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt := table.CollectableInstances.
+		UPDATE(
+			table.CollectableInstances.DeletedAt,
+			table.CollectableInstances.RevokedBy,
+			table.CollectableInstances.RevokeReason,
+		).
+		SET(
+			table.CollectableInstances.DeletedAt.SET(postgres.TimestampT(time.Now())),
+			table.CollectableInstances.RevokedBy.SET(postgres.Int64(revokerID)),
+			table.CollectableInstances.RevokeReason.SET(postgres.String(reason)),
+		).
+		WHERE(
+			table.CollectableInstances.ID.EQ(postgres.Int64(instanceID)).
+				AND(table.CollectableInstances.DeletedAt.IS_NULL()),
+		)
+
+	res, err := stmt.ExecContext(ctx, tx)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+
+	unequip := table.UserEquipCollectableInstance.
+		DELETE().
+		WHERE(table.UserEquipCollectableInstance.InstanceID.EQ(postgres.Int64(instanceID)))
+
+	_, err = unequip.ExecContext(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RestoreCollectableInstance undoes a RevokeCollectableInstance. The instance
+// is not re-equipped.
+func (db *PostgresDB) RestoreCollectableInstance(ctx context.Context, instanceID int64) (*CollectableInstance, error) {
+	stmt := table.CollectableInstances.
+		UPDATE(
+			table.CollectableInstances.DeletedAt,
+			table.CollectableInstances.RevokedBy,
+			table.CollectableInstances.RevokeReason,
+		).
+		SET(
+			table.CollectableInstances.DeletedAt.SET(postgres.TimestampExp(postgres.NULL)),
+			table.CollectableInstances.RevokedBy.SET(postgres.IntExp(postgres.NULL)),
+			table.CollectableInstances.RevokeReason.SET(postgres.StringExp(postgres.NULL)),
+		).
+		WHERE(
+			table.CollectableInstances.ID.EQ(postgres.Int64(instanceID)).
+				AND(table.CollectableInstances.DeletedAt.IS_NOT_NULL()).
+				// Salvaged instances were already paid out as materials
+				AND(table.CollectableInstances.SalvagedAt.IS_NULL()),
+		)
+
+	res, err := stmt.ExecContext(ctx, db.DB)
+	if err != nil {
+		return nil, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, ErrNotFound
+	}
+
+	instances, err := db.GetCollectableInstances(ctx, GetCollectableInstancesOptions{ByID: instanceID})
+	if err != nil {
+		return nil, err
+	}
+	if len(instances) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return &instances[0], nil
+}
+
 func (db *PostgresDB) GetEquippedForUser(ctx context.Context, userID int64) (*CollectableInstance, error) {
 	collectable := table.Collectables.AS("collectable")
 	creator := table.Users.AS("creator")
@@ -247,6 +495,9 @@ func (db *PostgresDB) GetEquippedForUser(ctx context.Context, userID int64) (*Co
 	dest := CollectableInstance{}
 	err := stmt.QueryContext(ctx, db.DB, &dest)
 	if err != nil {
+		if errors.Is(err, qrm.ErrNoRows) {
+			return nil, nil
+		}
 		return nil, err
 	}
 
@@ -289,6 +540,9 @@ func (db *PostgresDB) GetCollectable(ctx context.Context, id int64, options GetC
 
 	err := stmt.QueryContext(ctx, db.DB, &dest)
 	if err != nil {
+		if errors.Is(err, qrm.ErrNoRows) {
+			return nil, ErrNotFound
+		}
 		return nil, err
 	}
 
@@ -299,6 +553,7 @@ type GetCollectablesOptions struct {
 	Collection     int64
 	Creator        int64
 	Rarity         string
+	ReviewStatus   string
 	GetDeleted     bool
 	GetUnapproved  bool
 	OnlyUnapproved bool
@@ -325,6 +580,9 @@ func (db *PostgresDB) GetCollectables(ctx context.Context, options GetCollectabl
 	if options.Rarity != "" {
 		c.Add(collectable.Rarity.EQ(postgres.String(options.Rarity)))
 	}
+	if options.ReviewStatus != "" {
+		c.Add(collectable.ReviewStatus.EQ(postgres.String(options.ReviewStatus)))
+	}
 	if !options.GetDeleted {
 		c.Add(collectable.DeletedAt.IS_NULL())
 	}
@@ -368,39 +626,28 @@ func (db *PostgresDB) SearchUsers(ctx context.Context, search string) ([]User, e
 }
 
 type GetUserOptions struct {
-	ID        int64
-	TwitchID  string
-	Username  string
-	AuthToken []byte
+	ID       int64
+	TwitchID string
+	Username string
 }
 
 func (db *PostgresDB) GetUser(ctx context.Context, options GetUserOptions) (*User, error) {
 	stmt := postgres.SELECT(
 		table.Users.AllColumns,
-	)
+	).FROM(table.Users)
 
-	if options.AuthToken != nil {
-		stmt = stmt.FROM(
-			table.UserTokens.INNER_JOIN(table.Users, table.UserTokens.UserID.EQ(table.Users.ID)),
-		).WHERE(
-			table.UserTokens.Token.EQ(postgres.Bytea(options.AuthToken)),
-		).LIMIT(1)
-	} else {
-		stmt = stmt.FROM(table.Users)
-
-		c := ConstraintBuilder{}
+	c := ConstraintBuilder{}
 
-		if options.ID != 0 {
-			c.Add(table.Users.ID.EQ(postgres.Int64(options.ID)))
-		}
-		if options.TwitchID != "" {
-			c.Add(table.Users.TwitchID.EQ(postgres.String(options.TwitchID)))
-		}
-		if options.Username != "" {
-			c.Add(table.Users.Name.EQ(postgres.String(options.Username)))
-		}
-		stmt = c.Apply(stmt).LIMIT(1)
+	if options.ID != 0 {
+		c.Add(table.Users.ID.EQ(postgres.Int64(options.ID)))
+	}
+	if options.TwitchID != "" {
+		c.Add(table.Users.TwitchID.EQ(postgres.String(options.TwitchID)))
+	}
+	if options.Username != "" {
+		c.Add(table.Users.Name.EQ(postgres.String(options.Username)))
 	}
+	stmt = c.Apply(stmt).LIMIT(1)
 
 	dest := User{}
 	err := stmt.QueryContext(ctx, db.DB, &dest)
@@ -470,6 +717,8 @@ func (db *PostgresDB) SetEquipped(ctx context.Context, collectableInstanceID int
 }
 
 func (db *PostgresDB) CreateCollectable(ctx context.Context, collectable model.Collectables) (*Collectable, error) {
+	collectable.ReviewStatus = initialReviewStatus(collectable)
+
 	stmt := table.Collectables.INSERT(
 		table.Collectables.AllColumns.Except(table.Collectables.CreatedAt),
 	).
@@ -511,14 +760,23 @@ func (db *PostgresDB) UpdateCollectable(ctx context.Context, collectable model.C
 }
 
 func (db *PostgresDB) ApproveCollectable(ctx context.Context, collectableID int64, approverID int64) (*Collectable, error) {
+	now := time.Now()
 	stmt := table.Collectables.
 		UPDATE(
 			table.Collectables.ApprovedAt,
 			table.Collectables.ApprovedBy,
+			table.Collectables.ReviewStatus,
+			table.Collectables.ReviewFeedback,
+			table.Collectables.ReviewedBy,
+			table.Collectables.ReviewedAt,
 		).
 		SET(
-			table.Collectables.ApprovedAt.SET(postgres.TimestampT(time.Now())),
+			table.Collectables.ApprovedAt.SET(postgres.TimestampT(now)),
 			table.Collectables.ApprovedBy.SET(postgres.Int64(approverID)),
+			table.Collectables.ReviewStatus.SET(postgres.String(ReviewApproved)),
+			table.Collectables.ReviewFeedback.SET(postgres.StringExp(postgres.NULL)),
+			table.Collectables.ReviewedBy.SET(postgres.Int64(approverID)),
+			table.Collectables.ReviewedAt.SET(postgres.TimestampT(now)),
 		).
 		WHERE(table.Collectables.ID.EQ(postgres.Int64(collectableID))).
 		RETURNING(table.Collectables.AllColumns)
@@ -534,6 +792,80 @@ func (db *PostgresDB) ApproveCollectable(ctx context.Context, collectableID int6
 	})
 }
 
+// ReviewCollectable sends a submission back to its creator with feedback,
+// either rejected or with changes requested.
+func (db *PostgresDB) ReviewCollectable(ctx context.Context, collectableID int64, reviewerID int64, status string, feedback string) (*Collectable, error) {
+	stmt := table.Collectables.
+		UPDATE(
+			table.Collectables.ReviewStatus,
+			table.Collectables.ReviewFeedback,
+			table.Collectables.ReviewedBy,
+			table.Collectables.ReviewedAt,
+		).
+		SET(
+			table.Collectables.ReviewStatus.SET(postgres.String(status)),
+			table.Collectables.ReviewFeedback.SET(postgres.String(feedback)),
+			table.Collectables.ReviewedBy.SET(postgres.Int64(reviewerID)),
+			table.Collectables.ReviewedAt.SET(postgres.TimestampT(time.Now())),
+		).
+		WHERE(
+			table.Collectables.ID.EQ(postgres.Int64(collectableID)).
+				AND(table.Collectables.ApprovedAt.IS_NULL()).
+				AND(table.Collectables.DeletedAt.IS_NULL()),
+		).
+		RETURNING(table.Collectables.AllColumns)
+
+	dest := model.Collectables{}
+	err := stmt.QueryContext(ctx, db.DB, &dest)
+	if err != nil {
+		if errors.Is(err, qrm.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return db.GetCollectable(ctx, dest.ID, GetCollectableOptions{
+		GetUnapproved: true,
+	})
+}
+
+// ResubmitCollectable replaces the details of an unapproved submission and
+// puts it back in the approval queue. The last feedback is kept so reviewers
+// can see what was asked for.
+func (db *PostgresDB) ResubmitCollectable(ctx context.Context, collectable model.Collectables) (*Collectable, error) {
+	collectable.ReviewStatus = ReviewPending
+
+	stmt := table.Collectables.
+		UPDATE(
+			table.Collectables.Name,
+			table.Collectables.CollectionID,
+			table.Collectables.Rarity,
+			table.Collectables.Imagepath,
+			table.Collectables.ReviewStatus,
+		).
+		MODEL(collectable).
+		WHERE(
+			table.Collectables.ID.EQ(postgres.Int64(collectable.ID)).
+				AND(table.Collectables.CreatorID.EQ(postgres.Int64(collectable.CreatorID))).
+				AND(table.Collectables.ApprovedAt.IS_NULL()).
+				AND(table.Collectables.DeletedAt.IS_NULL()),
+		).
+		RETURNING(table.Collectables.AllColumns)
+
+	dest := model.Collectables{}
+	err := stmt.QueryContext(ctx, db.DB, &dest)
+	if err != nil {
+		if errors.Is(err, qrm.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return db.GetCollectable(ctx, dest.ID, GetCollectableOptions{
+		GetUnapproved: true,
+	})
+}
+
 func (db *PostgresDB) DeleteCollectable(ctx context.Context, collectableID int64) error {
 	stmt := table.Collectables.
 		UPDATE(
@@ -553,7 +885,7 @@ func (db *PostgresDB) DeleteCollectable(ctx context.Context, collectableID int64
 	return nil
 }
 
-func (db *PostgresDB) CreateImageUpload(ctx context.Context, imageID int64, user int64, name, uploadname string) error {
+func (db *PostgresDB) CreateImageUpload(ctx context.Context, imageID int64, user int64, name, uploadname string, phash int64) error {
 	stmt := table.ImageUploads.INSERT(
 		table.ImageUploads.AllColumns,
 	).MODEL(model.ImageUploads{
@@ -561,6 +893,8 @@ func (db *PostgresDB) CreateImageUpload(ctx context.Context, imageID int64, user
 		UserID:     user,
 		Imagepath:  name,
 		UploadName: &uploadname,
+		UploadedAt: time.Now(),
+		Phash:      &phash,
 	})
 
 	_, err := stmt.ExecContext(ctx, db.DB)
@@ -570,13 +904,164 @@ func (db *PostgresDB) CreateImageUpload(ctx context.Context, imageID int64, user
 	return nil
 }
 
+// GetOrphanedImageUploads finds uploads from before uploadedBefore that no
+// collectable uses, deleted collectables included so they can be restored.
+func (db *PostgresDB) GetOrphanedImageUploads(ctx context.Context, uploadedBefore time.Time) ([]model.ImageUploads, error) {
+	stmt := table.ImageUploads.
+		SELECT(table.ImageUploads.AllColumns).
+		WHERE(
+			table.ImageUploads.UploadedAt.LT(postgres.TimestampT(uploadedBefore)).
+				AND(postgres.NOT(postgres.EXISTS(
+					postgres.SELECT(postgres.Int(1)).
+						FROM(table.Collectables).
+						WHERE(table.Collectables.Imagepath.EQ(table.ImageUploads.Imagepath)),
+				))),
+		).
+		ORDER_BY(table.ImageUploads.UploadedAt.ASC())
+
+	var uploads []model.ImageUploads
+	err := stmt.QueryContext(ctx, db.DB, &uploads)
+	if err != nil && !errors.Is(err, qrm.ErrNoRows) {
+		return nil, err
+	}
+	return uploads, nil
+}
+
+func (db *PostgresDB) DeleteImageUpload(ctx context.Context, imageID int64) error {
+	stmt := table.ImageUploads.
+		DELETE().
+		WHERE(table.ImageUploads.ID.EQ(postgres.Int64(imageID)))
+
+	_, err := stmt.ExecContext(ctx, db.DB)
+	return err
+}
+
+// GetUnhashedImageUploads finds uploads from before perceptual hashing.
+func (db *PostgresDB) GetUnhashedImageUploads(ctx context.Context) ([]model.ImageUploads, error) {
+	stmt := table.ImageUploads.
+		SELECT(table.ImageUploads.AllColumns).
+		WHERE(table.ImageUploads.Phash.IS_NULL()).
+		ORDER_BY(table.ImageUploads.UploadedAt.ASC())
+
+	var uploads []model.ImageUploads
+	err := stmt.QueryContext(ctx, db.DB, &uploads)
+	if err != nil && !errors.Is(err, qrm.ErrNoRows) {
+		return nil, err
+	}
+	return uploads, nil
+}
+
+// GetCollectablesWithoutImageUpload finds collectables, deleted ones
+// included, whose image was imported rather than uploaded and so has no
+// image_uploads row to hold its hash.
+func (db *PostgresDB) GetCollectablesWithoutImageUpload(ctx context.Context) ([]model.Collectables, error) {
+	stmt := table.Collectables.
+		SELECT(table.Collectables.AllColumns).
+		WHERE(
+			postgres.NOT(postgres.EXISTS(
+				postgres.SELECT(postgres.Int(1)).
+					FROM(table.ImageUploads).
+					WHERE(table.ImageUploads.Imagepath.EQ(table.Collectables.Imagepath)),
+			)),
+		).
+		ORDER_BY(table.Collectables.ID.ASC())
+
+	var collectables []model.Collectables
+	err := stmt.QueryContext(ctx, db.DB, &collectables)
+	if err != nil && !errors.Is(err, qrm.ErrNoRows) {
+		return nil, err
+	}
+	return collectables, nil
+}
+
+func (db *PostgresDB) SetImageUploadHash(ctx context.Context, imageID int64, phash int64) error {
+	stmt := table.ImageUploads.
+		UPDATE(table.ImageUploads.Phash).
+		SET(postgres.Int64(phash)).
+		WHERE(table.ImageUploads.ID.EQ(postgres.Int64(imageID)))
+
+	_, err := stmt.ExecContext(ctx, db.DB)
+	return err
+}
+
+// GetImageHashes returns the perceptual hash of each of imagepaths that has
+// one, keyed by imagepath.
+func (db *PostgresDB) GetImageHashes(ctx context.Context, imagepaths []string) (map[string]int64, error) {
+	hashes := map[string]int64{}
+	if len(imagepaths) == 0 {
+		return hashes, nil
+	}
+
+	paths := make([]postgres.Expression, len(imagepaths))
+	for i, p := range imagepaths {
+		paths[i] = postgres.String(p)
+	}
+
+	stmt := table.ImageUploads.
+		SELECT(table.ImageUploads.Imagepath, table.ImageUploads.Phash).
+		WHERE(
+			table.ImageUploads.Imagepath.IN(paths...).
+				AND(table.ImageUploads.Phash.IS_NOT_NULL()),
+		)
+
+	var uploads []model.ImageUploads
+	err := stmt.QueryContext(ctx, db.DB, &uploads)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, u := range uploads {
+		hashes[u.Imagepath] = *u.Phash
+	}
+	return hashes, nil
+}
+
+// GetAuth returns the twitch tokens stored for a session token.
+func (db *PostgresDB) GetAuth(ctx context.Context, token []byte) (*UserAuth, error) {
+	stmt := table.UserTokens.
+		SELECT(table.UserTokens.AllColumns).
+		FROM(table.UserTokens).
+		WHERE(table.UserTokens.Token.EQ(postgres.Bytea(token)))
+
+	dest := model.UserTokens{}
+	err := stmt.QueryContext(ctx, db.DB, &dest)
+	if err != nil {
+		if errors.Is(err, qrm.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return userAuthFromUserTokens(dest), nil
+}
+
+func userAuthFromUserTokens(t model.UserTokens) *UserAuth {
+	auth := &UserAuth{
+		UserID:           t.UserID,
+		Token:            t.Token,
+		UpdatedAt:        t.UpdatedAt,
+		SessionExpiresAt: t.SessionExpiresAt,
+	}
+	if t.AccessToken != nil {
+		auth.AccessToken = *t.AccessToken
+	}
+	if t.RefreshToken != nil {
+		auth.RefreshToken = *t.RefreshToken
+	}
+	if t.ExpiresAt != nil {
+		auth.ExpiresAt = *t.ExpiresAt
+	}
+	return auth
+}
+
 func (db *PostgresDB) SaveAuth(ctx context.Context, auth UserAuth) error {
 	m := model.UserTokens{
-		UserID:       auth.UserID,
-		Token:        auth.Token,
-		AccessToken:  &auth.AccessToken,
-		RefreshToken: &auth.RefreshToken,
-		ExpiresAt:    &auth.ExpiresAt,
+		UserID:           auth.UserID,
+		Token:            auth.Token,
+		AccessToken:      &auth.AccessToken,
+		RefreshToken:     &auth.RefreshToken,
+		ExpiresAt:        &auth.ExpiresAt,
+		SessionExpiresAt: auth.SessionExpiresAt,
 	}
 
 	stmt := table.UserTokens.INSERT(table.UserTokens.AllColumns).MODEL(m).ON_CONFLICT(table.UserTokens.Token).DO_UPDATE(postgres.SET(
@@ -585,6 +1070,7 @@ func (db *PostgresDB) SaveAuth(ctx context.Context, auth UserAuth) error {
 		table.UserTokens.AccessToken.SET(table.UserTokens.EXCLUDED.AccessToken),
 		table.UserTokens.RefreshToken.SET(table.UserTokens.EXCLUDED.RefreshToken),
 		table.UserTokens.ExpiresAt.SET(table.UserTokens.EXCLUDED.ExpiresAt),
+		table.UserTokens.SessionExpiresAt.SET(table.UserTokens.EXCLUDED.SessionExpiresAt),
 	))
 
 	_, err := stmt.ExecContext(ctx, db.DB)
@@ -595,6 +1081,38 @@ func (db *PostgresDB) SaveAuth(ctx context.Context, auth UserAuth) error {
 	return nil
 }
 
+// RenewAuth pushes back when a session expires.
+func (db *PostgresDB) RenewAuth(ctx context.Context, token []byte, sessionExpiresAt time.Time) error {
+	_, err := table.UserTokens.
+		UPDATE(table.UserTokens.SessionExpiresAt).
+		SET(table.UserTokens.SessionExpiresAt.SET(postgres.TimestampT(sessionExpiresAt))).
+		WHERE(table.UserTokens.Token.EQ(postgres.Bytea(token))).
+		ExecContext(ctx, db.DB)
+	return err
+}
+
+// DeleteAuth ends a single session.
+func (db *PostgresDB) DeleteAuth(ctx context.Context, token []byte) error {
+	_, err := table.UserTokens.
+		DELETE().
+		WHERE(table.UserTokens.Token.EQ(postgres.Bytea(token))).
+		ExecContext(ctx, db.DB)
+	return err
+}
+
+// DeleteAuthForUser ends every session belonging to userID and returns how
+// many were ended.
+func (db *PostgresDB) DeleteAuthForUser(ctx context.Context, userID int64) (int64, error) {
+	res, err := table.UserTokens.
+		DELETE().
+		WHERE(table.UserTokens.UserID.EQ(postgres.Int64(userID))).
+		ExecContext(ctx, db.DB)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
 func (db *PostgresDB) GetWeights(ctx context.Context, collectionID int64) ([]*PullWeight, error) {
 	stmt := table.Collections.
 		SELECT(table.Collections.Weights).
@@ -628,3 +1146,1726 @@ func (db *PostgresDB) GetWeights(ctx context.Context, collectionID int64) ([]*Pu
 
 	return out, nil
 }
+
+// UpdateWeights replaces the pull weights for a collection and appends the new
+// configuration to the collection's weight history.
+func (db *PostgresDB) UpdateWeights(ctx context.Context, collectionID int64, weights map[string]int, changedBy int64) error {
+	encoded, err := json.Marshal(weights)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current := model.Collections{}
+	err = table.Collections.
+		SELECT(table.Collections.ID, table.Collections.Weights).
+		FROM(table.Collections).
+		WHERE(table.Collections.ID.EQ(postgres.Int64(collectionID))).
+		FOR(postgres.UPDATE()).
+		QueryContext(ctx, tx, &current)
+	if err != nil {
+		if errors.Is(err, qrm.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	versions := []int64{}
+	err = table.CollectionWeightHistory.
+		SELECT(table.CollectionWeightHistory.Version).
+		FROM(table.CollectionWeightHistory).
+		WHERE(table.CollectionWeightHistory.CollectionID.EQ(postgres.Int64(collectionID))).
+		ORDER_BY(table.CollectionWeightHistory.Version.DESC()).
+		LIMIT(1).
+		QueryContext(ctx, tx, &versions)
+	if err != nil {
+		return err
+	}
+
+	history := []model.CollectionWeightHistory{}
+	var version int64
+	if len(versions) == 0 {
+		// Preserve the config that predates the history table as the first version
+		if current.Weights != nil {
+			version++
+			history = append(history, model.CollectionWeightHistory{
+				CollectionID: collectionID,
+				Version:      version,
+				Weights:      *current.Weights,
+			})
+		}
+	} else {
+		version = versions[0]
+	}
+
+	version++
+	history = append(history, model.CollectionWeightHistory{
+		CollectionID: collectionID,
+		Version:      version,
+		Weights:      string(encoded),
+		ChangedBy:    &changedBy,
+	})
+
+	update := table.Collections.
+		UPDATE(
+			table.Collections.Weights,
+			table.Collections.UpdatedAt,
+		).
+		SET(
+			table.Collections.Weights.SET(postgres.String(string(encoded))),
+			table.Collections.UpdatedAt.SET(postgres.TimestampT(time.Now())),
+		).
+		WHERE(table.Collections.ID.EQ(postgres.Int64(collectionID)))
+
+	_, err = update.ExecContext(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	insert := table.CollectionWeightHistory.
+		INSERT(
+			table.CollectionWeightHistory.CollectionID,
+			table.CollectionWeightHistory.Version,
+			table.CollectionWeightHistory.Weights,
+			table.CollectionWeightHistory.ChangedBy,
+		).
+		MODELS(history)
+
+	_, err = insert.ExecContext(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (db *PostgresDB) GetWeightHistory(ctx context.Context, collectionID int64) ([]WeightHistory, error) {
+	changer := table.Users.AS("changer")
+
+	stmt := postgres.SELECT(
+		table.CollectionWeightHistory.AllColumns,
+		changer.AllColumns.Except(changer.Admin, changer.CreatedAt),
+	).FROM(
+		table.CollectionWeightHistory.
+			LEFT_JOIN(changer, table.CollectionWeightHistory.ChangedBy.EQ(changer.ID)),
+	).WHERE(
+		table.CollectionWeightHistory.CollectionID.EQ(postgres.Int64(collectionID)),
+	).ORDER_BY(
+		table.CollectionWeightHistory.Version.DESC(),
+	)
+
+	dest := []WeightHistory{}
+	err := stmt.QueryContext(ctx, db.DB, &dest)
+	if err != nil {
+		return nil, err
+	}
+
+	return dest, nil
+}
+
+type GetCollectionsOptions struct {
+	OnlyActive    bool
+	GetUnreleased bool
+}
+
+func (db *PostgresDB) GetCollections(ctx context.Context, options GetCollectionsOptions) ([]*Collection, error) {
+	collection := table.Collections.AS("collection")
+	creator := table.Users.AS("creator")
+
+	stmt := postgres.SELECT(
+		collection.AllColumns,
+		creator.AllColumns.Except(creator.Admin, creator.CreatedAt),
+	).FROM(
+		collection.INNER_JOIN(creator, collection.CreatorID.EQ(creator.ID)),
+	)
+
+	now := postgres.TimestampT(time.Now())
+
+	c := ConstraintBuilder{}
+	if !options.GetUnreleased || options.OnlyActive {
+		c.Add(collection.ActiveAt.LT_EQ(now))
+	}
+	if options.OnlyActive {
+		c.Add(collection.RetiredAt.IS_NULL().OR(collection.RetiredAt.GT(now)))
+	}
+	stmt = c.Apply(stmt)
+
+	stmt = stmt.ORDER_BY(collection.ActiveAt.DESC().NULLS_LAST(), collection.CreatedAt.DESC())
+
+	dest := []*Collection{}
+	err := stmt.QueryContext(ctx, db.DB, &dest)
+	if err != nil {
+		return nil, err
+	}
+
+	return dest, nil
+}
+
+func (db *PostgresDB) GetCollection(ctx context.Context, id int64) (*Collection, error) {
+	collection := table.Collections.AS("collection")
+	creator := table.Users.AS("creator")
+
+	stmt := postgres.SELECT(
+		collection.AllColumns,
+		creator.AllColumns.Except(creator.Admin, creator.CreatedAt),
+	).FROM(
+		collection.INNER_JOIN(creator, collection.CreatorID.EQ(creator.ID)),
+	).WHERE(
+		collection.ID.EQ(postgres.Int64(id)),
+	).LIMIT(1)
+
+	dest := Collection{}
+	err := stmt.QueryContext(ctx, db.DB, &dest)
+	if err != nil {
+		if errors.Is(err, qrm.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &dest, nil
+}
+
+func (db *PostgresDB) CreateCollection(ctx context.Context, collection model.Collections) (*Collection, error) {
+	stmt := table.Collections.INSERT(
+		table.Collections.AllColumns.Except(table.Collections.CreatedAt, table.Collections.UpdatedAt),
+	).
+		MODEL(collection).
+		RETURNING(table.Collections.ID)
+
+	id := []int64{}
+	err := stmt.QueryContext(ctx, db.DB, &id)
+	if err != nil {
+		return nil, err
+	}
+	if len(id) != 1 {
+		return nil, errors.New("expected one id to be returned")
+	}
+
+	return db.GetCollection(ctx, id[0])
+}
+
+// UpdateCollection changes the name and schedule of a collection. Weights are
+// managed through UpdateWeights so that their history is kept.
+func (db *PostgresDB) UpdateCollection(ctx context.Context, collection model.Collections) (*Collection, error) {
+	collection.UpdatedAt = time.Now()
+
+	stmt := table.Collections.
+		UPDATE(
+			table.Collections.Name,
+			table.Collections.ActiveAt,
+			table.Collections.RetiredAt,
+			table.Collections.UpdatedAt,
+		).
+		MODEL(collection).
+		WHERE(table.Collections.ID.EQ(postgres.Int64(collection.ID)))
+
+	res, err := stmt.ExecContext(ctx, db.DB)
+	if err != nil {
+		return nil, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, ErrNotFound
+	}
+
+	return db.GetCollection(ctx, collection.ID)
+}
+
+type GetEditionsOptions struct {
+	OnlyActive bool
+}
+
+func (db *PostgresDB) GetEditions(ctx context.Context, options GetEditionsOptions) ([]*Edition, error) {
+	stmt := postgres.SELECT(
+		table.Editions.AllColumns,
+	).FROM(
+		table.Editions,
+	)
+
+	if options.OnlyActive {
+		now := postgres.TimestampT(time.Now())
+		stmt = stmt.WHERE(
+			table.Editions.ActiveAt.LT_EQ(now).
+				AND(table.Editions.RetiredAt.IS_NULL().OR(table.Editions.RetiredAt.GT(now))),
+		)
+	}
+
+	stmt = stmt.ORDER_BY(table.Editions.ActiveAt.DESC().NULLS_LAST(), table.Editions.ID.ASC())
+
+	dest := []*Edition{}
+	err := stmt.QueryContext(ctx, db.DB, &dest)
+	if err != nil {
+		return nil, err
+	}
+
+	return dest, nil
+}
+
+func (db *PostgresDB) GetEdition(ctx context.Context, id int64) (*Edition, error) {
+	stmt := postgres.SELECT(
+		table.Editions.AllColumns,
+	).FROM(
+		table.Editions,
+	).WHERE(
+		table.Editions.ID.EQ(postgres.Int64(id)),
+	).LIMIT(1)
+
+	dest := Edition{}
+	err := stmt.QueryContext(ctx, db.DB, &dest)
+	if err != nil {
+		if errors.Is(err, qrm.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &dest, nil
+}
+
+func (db *PostgresDB) CreateEdition(ctx context.Context, edition model.Editions) (*Edition, error) {
+	stmt := table.Editions.INSERT(
+		table.Editions.AllColumns.Except(table.Editions.CreatedAt),
+	).
+		MODEL(edition).
+		RETURNING(table.Editions.AllColumns)
+
+	dest := Edition{}
+	err := stmt.QueryContext(ctx, db.DB, &dest)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dest, nil
+}
+
+func (db *PostgresDB) UpdateEdition(ctx context.Context, edition model.Editions) (*Edition, error) {
+	stmt := table.Editions.
+		UPDATE(
+			table.Editions.Name,
+			table.Editions.CollectionID,
+			table.Editions.FirstN,
+			table.Editions.ActiveAt,
+			table.Editions.RetiredAt,
+		).
+		MODEL(edition).
+		WHERE(table.Editions.ID.EQ(postgres.Int64(edition.ID))).
+		RETURNING(table.Editions.AllColumns)
+
+	dest := Edition{}
+	err := stmt.QueryContext(ctx, db.DB, &dest)
+	if err != nil {
+		if errors.Is(err, qrm.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &dest, nil
+}
+
+type GetEditionCountsOptions struct {
+	ByCollection  int64
+	ByCollectable int64
+	ByOwner       int64
+}
+
+func (db *PostgresDB) GetEditionCounts(ctx context.Context, options GetEditionCountsOptions) ([]EditionCount, error) {
+	stmt := postgres.SELECT(
+		table.CollectableInstances.CollectableID.AS("edition_count.collectable_id"),
+		table.CollectableInstances.EditionID.AS("edition_count.edition_id"),
+		table.Editions.Name.AS("edition_count.edition_name"),
+		postgres.COUNT(table.CollectableInstances.ID).AS("edition_count.count"),
+	).FROM(
+		table.CollectableInstances.
+			INNER_JOIN(table.Collectables, table.CollectableInstances.CollectableID.EQ(table.Collectables.ID)).
+			INNER_JOIN(table.Editions, table.CollectableInstances.EditionID.EQ(table.Editions.ID)),
+	)
+
+	c := ConstraintBuilder{}
+	c.Add(table.CollectableInstances.DeletedAt.IS_NULL())
+	if options.ByCollection != 0 {
+		c.Add(table.Collectables.CollectionID.EQ(postgres.Int64(options.ByCollection)))
+	}
+	if options.ByCollectable != 0 {
+		c.Add(table.CollectableInstances.CollectableID.EQ(postgres.Int64(options.ByCollectable)))
+	}
+	if options.ByOwner != 0 {
+		c.Add(table.CollectableInstances.OwnerID.EQ(postgres.Int64(options.ByOwner)))
+	}
+	stmt = c.Apply(stmt)
+
+	stmt = stmt.GROUP_BY(
+		table.CollectableInstances.CollectableID,
+		table.CollectableInstances.EditionID,
+		table.Editions.Name,
+	).ORDER_BY(
+		table.CollectableInstances.CollectableID.ASC(),
+		table.CollectableInstances.EditionID.ASC(),
+	)
+
+	dest := []EditionCount{}
+	err := stmt.QueryContext(ctx, db.DB, &dest)
+	if err != nil {
+		return nil, err
+	}
+
+	return dest, nil
+}
+
+// CountCollectableInstances counts every instance of a collectable ever made
+// across all editions, including revoked and salvaged ones, so that first
+// editions can't be handed out again.
+func (db *PostgresDB) CountCollectableInstances(ctx context.Context, collectableID int64) (int64, error) {
+	return countCollectableInstances(ctx, db.DB, collectableID)
+}
+
+// checkFirstEdition returns ErrEditionFull if instance is in a first edition
+// that has no room left. The collectable is locked until the transaction ends
+// so concurrent pulls of it can't both take the last one.
+func checkFirstEdition(ctx context.Context, tx *sql.Tx, instance model.CollectableInstances) error {
+	editions := []model.Editions{}
+	err := postgres.SELECT(
+		table.Editions.ID,
+		table.Editions.FirstN,
+	).FROM(
+		table.Editions,
+	).WHERE(
+		table.Editions.ID.EQ(postgres.Int64(instance.EditionID)),
+	).QueryContext(ctx, tx, &editions)
+	if err != nil {
+		return err
+	}
+	if len(editions) == 0 || editions[0].FirstN == nil {
+		return nil
+	}
+
+	locked := []model.Collectables{}
+	err = postgres.SELECT(
+		table.Collectables.ID,
+	).FROM(
+		table.Collectables,
+	).WHERE(
+		table.Collectables.ID.EQ(postgres.Int64(instance.CollectableID)),
+	).FOR(postgres.UPDATE()).QueryContext(ctx, tx, &locked)
+	if err != nil {
+		return err
+	}
+
+	count, err := countCollectableInstances(ctx, tx, instance.CollectableID)
+	if err != nil {
+		return err
+	}
+	if count >= *editions[0].FirstN {
+		return ErrEditionFull
+	}
+	return nil
+}
+
+func countCollectableInstances(ctx context.Context, q qrm.Queryable, collectableID int64) (int64, error) {
+	stmt := postgres.SELECT(
+		postgres.COUNT(table.CollectableInstances.ID),
+	).FROM(
+		table.CollectableInstances,
+	).WHERE(
+		table.CollectableInstances.CollectableID.EQ(postgres.Int64(collectableID)),
+	)
+
+	dest := []int64{}
+	err := stmt.QueryContext(ctx, q, &dest)
+	if err != nil {
+		return 0, err
+	}
+	if len(dest) != 1 {
+		return 0, errors.New("expected one count to be returned")
+	}
+
+	return dest[0], nil
+}
+
+const (
+	FightOutcomeWin  = "win"
+	FightOutcomeLoss = "loss"
+	FightOutcomeDraw = "draw"
+)
+
+type FightOutcome struct {
+	model.FightOutcomes
+
+	User        *model.Users `alias:"fighter"`
+	Collectable *Collectable `alias:"collectable"`
+}
+
+type Fight struct {
+	model.Fights
+
+	Outcomes []FightOutcome
+}
+
+type FightStats struct {
+	Wins   int64
+	Losses int64
+	Draws  int64
+}
+
+type OutcomeCount struct {
+	Outcome string
+	Count   int64
+}
+
+type GetFightsOptions struct {
+	ByUser  int64
+	ByEvent int64
+	Limit   int64
+}
+
+func fightsQuery(where postgres.BoolExpression) postgres.SelectStatement {
+	fighter := table.Users.AS("fighter")
+	collectable := table.Collectables.AS("collectable")
+	creator := table.Users.AS("creator")
+
+	return postgres.SELECT(
+		table.Fights.AllColumns,
+		table.FightOutcomes.AllColumns,
+		fighter.AllColumns.Except(fighter.Admin, fighter.CreatedAt),
+		collectable.AllColumns,
+		creator.AllColumns.Except(creator.Admin, creator.CreatedAt),
+	).FROM(
+		table.Fights.
+			INNER_JOIN(table.FightOutcomes, table.FightOutcomes.FightID.EQ(table.Fights.ID)).
+			INNER_JOIN(fighter, table.FightOutcomes.UserID.EQ(fighter.ID)).
+			LEFT_JOIN(collectable, table.FightOutcomes.CollectableID.EQ(collectable.ID)).
+			LEFT_JOIN(creator, collectable.CreatorID.EQ(creator.ID)),
+	).WHERE(
+		where,
+	).ORDER_BY(
+		table.Fights.CreatedAt.DESC(),
+		table.Fights.ID.DESC(),
+		table.FightOutcomes.UserID.ASC(),
+	)
+}
+
+func (db *PostgresDB) GetFight(ctx context.Context, id int64) (*Fight, error) {
+	stmt := fightsQuery(table.Fights.ID.EQ(postgres.Int64(id)))
+
+	dest := Fight{}
+	err := stmt.QueryContext(ctx, db.DB, &dest)
+	if err != nil {
+		if errors.Is(err, qrm.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &dest, nil
+}
+
+// GetFightByClientID finds the fight a client reported under clientID.
+func (db *PostgresDB) GetFightByClientID(ctx context.Context, clientID string) (*Fight, error) {
+	stmt := fightsQuery(table.Fights.ClientID.EQ(postgres.String(clientID)))
+
+	dest := Fight{}
+	err := stmt.QueryContext(ctx, db.DB, &dest)
+	if err != nil {
+		if errors.Is(err, qrm.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &dest, nil
+}
+
+func (db *PostgresDB) GetFights(ctx context.Context, options GetFightsOptions) ([]Fight, error) {
+	limit := options.Limit
+	if limit <= 0 {
+		limit = 25
+	}
+
+	// Limit on fights rather than on the joined rows
+	ids := postgres.SELECT(table.Fights.ID).FROM(table.Fights)
+
+	c := ConstraintBuilder{}
+	if options.ByUser != 0 {
+		c.Add(postgres.EXISTS(
+			postgres.SELECT(postgres.Int(1)).
+				FROM(table.FightOutcomes).
+				WHERE(
+					table.FightOutcomes.FightID.EQ(table.Fights.ID).
+						AND(table.FightOutcomes.UserID.EQ(postgres.Int64(options.ByUser))),
+				),
+		))
+	}
+	if options.ByEvent != 0 {
+		c.Add(table.Fights.EventID.EQ(postgres.Int64(options.ByEvent)))
+	}
+	ids = c.Apply(ids)
+
+	ids = ids.ORDER_BY(table.Fights.CreatedAt.DESC(), table.Fights.ID.DESC()).LIMIT(limit)
+
+	stmt := fightsQuery(table.Fights.ID.IN(ids))
+
+	dest := []Fight{}
+	err := stmt.QueryContext(ctx, db.DB, &dest)
+	if err != nil {
+		return nil, err
+	}
+
+	return dest, nil
+}
+
+func (db *PostgresDB) CreateFight(ctx context.Context, fight model.Fights, outcomes []model.FightOutcomes) (*Fight, error) {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	res, err := table.Fights.
+		INSERT(table.Fights.AllColumns).
+		MODEL(fight).
+		ON_CONFLICT(table.Fights.ClientID).
+		DO_NOTHING().
+		ExecContext(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, ErrFightExists
+	}
+
+	_, err = table.FightOutcomes.
+		INSERT(table.FightOutcomes.AllColumns).
+		MODELS(outcomes).
+		ExecContext(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return db.GetFight(ctx, fight.ID)
+}
+
+func (db *PostgresDB) GetFightStats(ctx context.Context, userID int64) (*FightStats, error) {
+	stmt := postgres.SELECT(
+		table.FightOutcomes.Outcome.AS("outcome_count.outcome"),
+		postgres.COUNT(table.FightOutcomes.FightID).AS("outcome_count.count"),
+	).FROM(
+		table.FightOutcomes,
+	).WHERE(
+		table.FightOutcomes.UserID.EQ(postgres.Int64(userID)),
+	).GROUP_BY(
+		table.FightOutcomes.Outcome,
+	)
+
+	dest := []OutcomeCount{}
+	err := stmt.QueryContext(ctx, db.DB, &dest)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := FightStats{}
+	for _, c := range dest {
+		switch c.Outcome {
+		case FightOutcomeWin:
+			stats.Wins = c.Count
+		case FightOutcomeLoss:
+			stats.Losses = c.Count
+		case FightOutcomeDraw:
+			stats.Draws = c.Count
+		}
+	}
+
+	return &stats, nil
+}
+
+type Event struct {
+	model.Events `alias:"event"`
+
+	Creator *model.Users `alias:"creator"`
+}
+
+// IsActive reports whether fights should currently be attached to the event.
+func (e *Event) IsActive(at time.Time) bool {
+	return e.ClosedAt == nil || e.ClosedAt.After(at)
+}
+
+type GetEventsOptions struct {
+	OnlyActive bool
+}
+
+type GetEventOptions struct {
+	ID   int64
+	Slug string
+}
+
+// LeaderboardEntry is one user's record within an event.
+type LeaderboardEntry struct {
+	UserID   int64
+	UserName string
+	Wins     int64
+	Losses   int64
+	Draws    int64
+}
+
+func eventsQuery() postgres.SelectStatement {
+	event := table.Events.AS("event")
+	creator := table.Users.AS("creator")
+
+	return postgres.SELECT(
+		event.AllColumns,
+		creator.AllColumns.Except(creator.Admin, creator.CreatedAt),
+	).FROM(
+		event.INNER_JOIN(creator, event.CreatorID.EQ(creator.ID)),
+	)
+}
+
+func (db *PostgresDB) GetEvents(ctx context.Context, options GetEventsOptions) ([]*Event, error) {
+	event := table.Events.AS("event")
+
+	stmt := eventsQuery()
+	if options.OnlyActive {
+		stmt = stmt.WHERE(
+			event.ClosedAt.IS_NULL().OR(event.ClosedAt.GT(postgres.TimestampT(time.Now()))),
+		)
+	}
+	stmt = stmt.ORDER_BY(event.CreatedAt.DESC(), event.ID.DESC())
+
+	dest := []*Event{}
+	err := stmt.QueryContext(ctx, db.DB, &dest)
+	if err != nil {
+		return nil, err
+	}
+
+	return dest, nil
+}
+
+func (db *PostgresDB) GetEvent(ctx context.Context, options GetEventOptions) (*Event, error) {
+	event := table.Events.AS("event")
+
+	stmt := eventsQuery()
+	if options.ID != 0 {
+		stmt = stmt.WHERE(event.ID.EQ(postgres.Int64(options.ID)))
+	} else if options.Slug != "" {
+		stmt = stmt.WHERE(event.Slug.EQ(postgres.String(options.Slug)))
+	} else {
+		return nil, errors.New("must specify ID or Slug")
+	}
+	stmt = stmt.LIMIT(1)
+
+	dest := Event{}
+	err := stmt.QueryContext(ctx, db.DB, &dest)
+	if err != nil {
+		if errors.Is(err, qrm.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &dest, nil
+}
+
+func (db *PostgresDB) CreateEvent(ctx context.Context, event model.Events) (*Event, error) {
+	stmt := table.Events.INSERT(
+		table.Events.AllColumns.Except(table.Events.CreatedAt),
+	).
+		MODEL(event)
+
+	_, err := stmt.ExecContext(ctx, db.DB)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.GetEvent(ctx, GetEventOptions{ID: event.ID})
+}
+
+func (db *PostgresDB) UpdateEvent(ctx context.Context, event model.Events) (*Event, error) {
+	stmt := table.Events.
+		UPDATE(
+			table.Events.Name,
+			table.Events.Description,
+			table.Events.ClosedAt,
+		).
+		MODEL(event).
+		WHERE(table.Events.ID.EQ(postgres.Int64(event.ID)))
+
+	res, err := stmt.ExecContext(ctx, db.DB)
+	if err != nil {
+		return nil, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, ErrNotFound
+	}
+
+	return db.GetEvent(ctx, GetEventOptions{ID: event.ID})
+}
+
+func outcomeTotal(outcome string) postgres.IntegerExpression {
+	return postgres.SUMi(
+		postgres.IntExp(
+			postgres.CASE().
+				WHEN(table.FightOutcomes.Outcome.EQ(postgres.String(outcome))).
+				THEN(postgres.Int(1)).
+				ELSE(postgres.Int(0)),
+		),
+	)
+}
+
+// GetEventLeaderboard totals the outcomes of every user that fought in the
+// event, best record first.
+func (db *PostgresDB) GetEventLeaderboard(ctx context.Context, eventID int64) ([]LeaderboardEntry, error) {
+	wins := outcomeTotal(FightOutcomeWin)
+	losses := outcomeTotal(FightOutcomeLoss)
+
+	stmt := postgres.SELECT(
+		table.FightOutcomes.UserID.AS("leaderboard_entry.user_id"),
+		table.Users.Name.AS("leaderboard_entry.user_name"),
+		wins.AS("leaderboard_entry.wins"),
+		losses.AS("leaderboard_entry.losses"),
+		outcomeTotal(FightOutcomeDraw).AS("leaderboard_entry.draws"),
+	).FROM(
+		table.FightOutcomes.
+			INNER_JOIN(table.Fights, table.FightOutcomes.FightID.EQ(table.Fights.ID)).
+			INNER_JOIN(table.Users, table.FightOutcomes.UserID.EQ(table.Users.ID)),
+	).WHERE(
+		table.Fights.EventID.EQ(postgres.Int64(eventID)),
+	).GROUP_BY(
+		table.FightOutcomes.UserID,
+		table.Users.Name,
+	).ORDER_BY(
+		wins.DESC(),
+		losses.ASC(),
+		table.Users.Name.ASC(),
+	)
+
+	dest := []LeaderboardEntry{}
+	err := stmt.QueryContext(ctx, db.DB, &dest)
+	if err != nil {
+		return nil, err
+	}
+
+	return dest, nil
+}
+
+// EventMatch is one pairing in an event's bracket. A missing user is a bye.
+type EventMatch struct {
+	model.EventMatches `alias:"event_match"`
+
+	FirstUser  *model.Users `alias:"first_user"`
+	SecondUser *model.Users `alias:"second_user"`
+}
+
+// GetEventMatches returns the bracket for an event in round and slot order.
+func (db *PostgresDB) GetEventMatches(ctx context.Context, eventID int64) ([]EventMatch, error) {
+	match := table.EventMatches.AS("event_match")
+	first := table.Users.AS("first_user")
+	second := table.Users.AS("second_user")
+
+	stmt := postgres.SELECT(
+		match.AllColumns,
+		first.ID,
+		first.Name,
+		second.ID,
+		second.Name,
+	).FROM(
+		match.
+			LEFT_JOIN(first, match.FirstUserID.EQ(first.ID)).
+			LEFT_JOIN(second, match.SecondUserID.EQ(second.ID)),
+	).WHERE(
+		match.EventID.EQ(postgres.Int64(eventID)),
+	).ORDER_BY(
+		match.Round.ASC(),
+		match.Slot.ASC(),
+	)
+
+	dest := []EventMatch{}
+	err := stmt.QueryContext(ctx, db.DB, &dest)
+	if err != nil {
+		return nil, err
+	}
+
+	return dest, nil
+}
+
+// CreateEventMatches adds a round of matches to an event's bracket.
+func (db *PostgresDB) CreateEventMatches(ctx context.Context, matches []model.EventMatches) error {
+	_, err := table.EventMatches.
+		INSERT(table.EventMatches.AllColumns).
+		MODELS(matches).
+		ExecContext(ctx, db.DB)
+	return err
+}
+
+// DecideEventMatch records the winner of a match, returning ErrNotFound if
+// the match doesn't exist or was already decided.
+func (db *PostgresDB) DecideEventMatch(ctx context.Context, match model.EventMatches) error {
+	res, err := table.EventMatches.
+		UPDATE(
+			table.EventMatches.WinnerID,
+			table.EventMatches.FightID,
+		).
+		MODEL(match).
+		WHERE(
+			table.EventMatches.EventID.EQ(postgres.Int64(match.EventID)).
+				AND(table.EventMatches.Round.EQ(postgres.Int32(match.Round))).
+				AND(table.EventMatches.Slot.EQ(postgres.Int32(match.Slot))).
+				AND(table.EventMatches.WinnerID.IS_NULL()),
+		).
+		ExecContext(ctx, db.DB)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+const (
+	ReviewPending          = "pending"
+	ReviewApproved         = "approved"
+	ReviewRejected         = "rejected"
+	ReviewChangesRequested = "changes_requested"
+)
+
+// initialReviewStatus is the status for a newly created collectable, ones
+// created by admins start out approved.
+func initialReviewStatus(c model.Collectables) string {
+	if c.ApprovedAt != nil {
+		return ReviewApproved
+	}
+	return ReviewPending
+}
+
+const (
+	TransTypePull  = "pull"
+	TransTypeIssue = "issue"
+	TransTypeTrade = "trade"
+	TransTypeForge = "forge"
+
+	TradeStatusPending   = "pending"
+	TradeStatusAccepted  = "accepted"
+	TradeStatusDeclined  = "declined"
+	TradeStatusCancelled = "cancelled"
+)
+
+type TradeOfferItem struct {
+	model.TradeOfferItems
+
+	Instance *CollectableInstance
+}
+
+type TradeOffer struct {
+	model.TradeOffers
+
+	Proposer  *model.Users `alias:"proposer"`
+	Recipient *model.Users `alias:"recipient"`
+	Items     []TradeOfferItem
+}
+
+type GetTradeOffersOptions struct {
+	ByUser int64
+	Status string
+}
+
+type OwnershipEntry struct {
+	model.OwnershipLedger
+
+	From *model.Users `alias:"from_user"`
+	To   *model.Users `alias:"to_user"`
+}
+
+func tradeOffersQuery() postgres.SelectStatement {
+	proposer := table.Users.AS("proposer")
+	recipient := table.Users.AS("recipient")
+	collectable := table.Collectables.AS("collectable")
+	creator := table.Users.AS("creator")
+	owner := table.Users.AS("owner")
+
+	return postgres.SELECT(
+		table.TradeOffers.AllColumns,
+		proposer.AllColumns.Except(proposer.Admin, proposer.CreatedAt),
+		recipient.AllColumns.Except(recipient.Admin, recipient.CreatedAt),
+		table.TradeOfferItems.AllColumns,
+		table.CollectableInstances.AllColumns,
+		collectable.AllColumns,
+		creator.AllColumns.Except(creator.Admin, creator.CreatedAt),
+		owner.AllColumns.Except(owner.Admin, owner.CreatedAt),
+		table.Editions.AllColumns,
+	).FROM(
+		table.TradeOffers.
+			INNER_JOIN(proposer, table.TradeOffers.ProposerID.EQ(proposer.ID)).
+			INNER_JOIN(recipient, table.TradeOffers.RecipientID.EQ(recipient.ID)).
+			INNER_JOIN(table.TradeOfferItems, table.TradeOfferItems.OfferID.EQ(table.TradeOffers.ID)).
+			INNER_JOIN(table.CollectableInstances, table.TradeOfferItems.InstanceID.EQ(table.CollectableInstances.ID)).
+			INNER_JOIN(collectable, table.CollectableInstances.CollectableID.EQ(collectable.ID)).
+			INNER_JOIN(creator, collectable.CreatorID.EQ(creator.ID)).
+			INNER_JOIN(owner, table.CollectableInstances.OwnerID.EQ(owner.ID)).
+			INNER_JOIN(table.Editions, table.CollectableInstances.EditionID.EQ(table.Editions.ID)),
+	).ORDER_BY(
+		table.TradeOffers.CreatedAt.DESC(),
+		table.TradeOffers.ID.DESC(),
+		table.TradeOfferItems.InstanceID.ASC(),
+	)
+}
+
+func (db *PostgresDB) GetTradeOffer(ctx context.Context, id int64) (*TradeOffer, error) {
+	stmt := tradeOffersQuery().WHERE(table.TradeOffers.ID.EQ(postgres.Int64(id)))
+
+	dest := TradeOffer{}
+	err := stmt.QueryContext(ctx, db.DB, &dest)
+	if err != nil {
+		if errors.Is(err, qrm.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &dest, nil
+}
+
+func (db *PostgresDB) GetTradeOffers(ctx context.Context, options GetTradeOffersOptions) ([]TradeOffer, error) {
+	c := ConstraintBuilder{}
+	if options.ByUser != 0 {
+		user := postgres.Int64(options.ByUser)
+		c.Add(table.TradeOffers.ProposerID.EQ(user).OR(table.TradeOffers.RecipientID.EQ(user)))
+	}
+	if options.Status != "" {
+		c.Add(table.TradeOffers.Status.EQ(postgres.String(options.Status)))
+	}
+
+	stmt := c.Apply(tradeOffersQuery())
+
+	dest := []TradeOffer{}
+	err := stmt.QueryContext(ctx, db.DB, &dest)
+	if err != nil {
+		return nil, err
+	}
+
+	return dest, nil
+}
+
+func (db *PostgresDB) CreateTradeOffer(ctx context.Context, offer model.TradeOffers, items []model.TradeOfferItems) (*TradeOffer, error) {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = table.TradeOffers.
+		INSERT(table.TradeOffers.AllColumns.Except(table.TradeOffers.CreatedAt)).
+		MODEL(offer).
+		ExecContext(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = table.TradeOfferItems.
+		INSERT(table.TradeOfferItems.AllColumns).
+		MODELS(items).
+		ExecContext(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return db.GetTradeOffer(ctx, offer.ID)
+}
+
+// ResolveTradeOffer declines or cancels a pending offer without moving any
+// instances.
+func (db *PostgresDB) ResolveTradeOffer(ctx context.Context, offerID int64, status string) (*TradeOffer, error) {
+	stmt := table.TradeOffers.
+		UPDATE(
+			table.TradeOffers.Status,
+			table.TradeOffers.ResolvedAt,
+		).
+		SET(
+			table.TradeOffers.Status.SET(postgres.String(status)),
+			table.TradeOffers.ResolvedAt.SET(postgres.TimestampT(time.Now())),
+		).
+		WHERE(
+			table.TradeOffers.ID.EQ(postgres.Int64(offerID)).
+				AND(table.TradeOffers.Status.EQ(postgres.String(TradeStatusPending))),
+		)
+
+	res, err := stmt.ExecContext(ctx, db.DB)
+	if err != nil {
+		return nil, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		_, err := db.GetTradeOffer(ctx, offerID)
+		if err != nil {
+			return nil, err
+		}
+		return nil, ErrNotPending
+	}
+
+	return db.GetTradeOffer(ctx, offerID)
+}
+
+// AcceptTradeOffer swaps every instance in the offer to the other party and
+// records the transfers in the ownership ledger. Nothing moves unless every
+// instance is still held, undeleted, by the user that offered it.
+func (db *PostgresDB) AcceptTradeOffer(ctx context.Context, offerID int64) (*TradeOffer, error) {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	offer := model.TradeOffers{}
+	err = table.TradeOffers.
+		SELECT(table.TradeOffers.AllColumns).
+		FROM(table.TradeOffers).
+		WHERE(table.TradeOffers.ID.EQ(postgres.Int64(offerID))).
+		FOR(postgres.UPDATE()).
+		QueryContext(ctx, tx, &offer)
+	if err != nil {
+		if errors.Is(err, qrm.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if offer.Status != TradeStatusPending {
+		return nil, ErrNotPending
+	}
+
+	items := []model.TradeOfferItems{}
+	err = table.TradeOfferItems.
+		SELECT(table.TradeOfferItems.AllColumns).
+		FROM(table.TradeOfferItems).
+		WHERE(table.TradeOfferItems.OfferID.EQ(postgres.Int64(offerID))).
+		QueryContext(ctx, tx, &items)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]postgres.Expression, len(items))
+	for i, item := range items {
+		ids[i] = postgres.Int64(item.InstanceID)
+	}
+
+	instances := []model.CollectableInstances{}
+	err = table.CollectableInstances.
+		SELECT(table.CollectableInstances.AllColumns).
+		FROM(table.CollectableInstances).
+		WHERE(table.CollectableInstances.ID.IN(ids...)).
+		FOR(postgres.UPDATE()).
+		QueryContext(ctx, tx, &instances)
+	if err != nil {
+		return nil, err
+	}
+
+	owners := map[int64]int64{}
+	for _, i := range instances {
+		if i.DeletedAt == nil {
+			owners[i.ID] = i.OwnerID
+		}
+	}
+
+	now := time.Now()
+	ledger := make([]model.OwnershipLedger, len(items))
+	for i, item := range items {
+		if owner, ok := owners[item.InstanceID]; !ok || owner != item.FromUserID {
+			return nil, ErrOwnershipChanged
+		}
+
+		to := offer.RecipientID
+		if item.FromUserID == offer.RecipientID {
+			to = offer.ProposerID
+		}
+
+		_, err = table.CollectableInstances.
+			UPDATE(table.CollectableInstances.OwnerID).
+			SET(table.CollectableInstances.OwnerID.SET(postgres.Int64(to))).
+			WHERE(table.CollectableInstances.ID.EQ(postgres.Int64(item.InstanceID))).
+			ExecContext(ctx, tx)
+		if err != nil {
+			return nil, err
+		}
+
+		from := item.FromUserID
+		ledger[i] = model.OwnershipLedger{
+			InstanceID:   item.InstanceID,
+			FromUserID:   &from,
+			ToUserID:     to,
+			TransType:    TransTypeTrade,
+			TradeOfferID: &offer.ID,
+			CreatedAt:    now,
+		}
+	}
+
+	// Traded knives can't stay equipped by their previous owner
+	_, err = table.UserEquipCollectableInstance.
+		DELETE().
+		WHERE(table.UserEquipCollectableInstance.InstanceID.IN(ids...)).
+		ExecContext(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = table.OwnershipLedger.
+		INSERT(
+			table.OwnershipLedger.InstanceID,
+			table.OwnershipLedger.FromUserID,
+			table.OwnershipLedger.ToUserID,
+			table.OwnershipLedger.TransType,
+			table.OwnershipLedger.TradeOfferID,
+			table.OwnershipLedger.CreatedAt,
+		).
+		MODELS(ledger).
+		ExecContext(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = table.TradeOffers.
+		UPDATE(
+			table.TradeOffers.Status,
+			table.TradeOffers.ResolvedAt,
+		).
+		SET(
+			table.TradeOffers.Status.SET(postgres.String(TradeStatusAccepted)),
+			table.TradeOffers.ResolvedAt.SET(postgres.TimestampT(now)),
+		).
+		WHERE(table.TradeOffers.ID.EQ(postgres.Int64(offerID))).
+		ExecContext(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return db.GetTradeOffer(ctx, offerID)
+}
+
+// GetOwnershipHistory returns the ledger for an instance, oldest first.
+func (db *PostgresDB) GetOwnershipHistory(ctx context.Context, instanceID int64) ([]OwnershipEntry, error) {
+	from := table.Users.AS("from_user")
+	to := table.Users.AS("to_user")
+
+	stmt := postgres.SELECT(
+		table.OwnershipLedger.AllColumns,
+		from.AllColumns.Except(from.Admin, from.CreatedAt),
+		to.AllColumns.Except(to.Admin, to.CreatedAt),
+	).FROM(
+		table.OwnershipLedger.
+			LEFT_JOIN(from, table.OwnershipLedger.FromUserID.EQ(from.ID)).
+			INNER_JOIN(to, table.OwnershipLedger.ToUserID.EQ(to.ID)),
+	).WHERE(
+		table.OwnershipLedger.InstanceID.EQ(postgres.Int64(instanceID)),
+	).ORDER_BY(
+		table.OwnershipLedger.CreatedAt.ASC(),
+		table.OwnershipLedger.ID.ASC(),
+	)
+
+	dest := []OwnershipEntry{}
+	err := stmt.QueryContext(ctx, db.DB, &dest)
+	if err != nil {
+		return nil, err
+	}
+
+	return dest, nil
+}
+
+type CraftRecipe struct {
+	model.CraftRecipes
+}
+
+func (db *PostgresDB) GetCraftRecipe(ctx context.Context, collectionID int64) (*CraftRecipe, error) {
+	stmt := table.CraftRecipes.
+		SELECT(table.CraftRecipes.AllColumns).
+		FROM(table.CraftRecipes).
+		WHERE(table.CraftRecipes.CollectionID.EQ(postgres.Int64(collectionID)))
+
+	dest := CraftRecipe{}
+	err := stmt.QueryContext(ctx, db.DB, &dest)
+	if err != nil {
+		if errors.Is(err, qrm.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &dest, nil
+}
+
+func (db *PostgresDB) SaveCraftRecipe(ctx context.Context, recipe model.CraftRecipes) (*CraftRecipe, error) {
+	recipe.UpdatedAt = time.Now()
+
+	stmt := table.CraftRecipes.
+		INSERT(table.CraftRecipes.AllColumns).
+		MODEL(recipe).
+		ON_CONFLICT(table.CraftRecipes.CollectionID).
+		DO_UPDATE(postgres.SET(
+			table.CraftRecipes.Salvage.SET(table.CraftRecipes.EXCLUDED.Salvage),
+			table.CraftRecipes.Forge.SET(table.CraftRecipes.EXCLUDED.Forge),
+			table.CraftRecipes.UpdatedBy.SET(table.CraftRecipes.EXCLUDED.UpdatedBy),
+			table.CraftRecipes.UpdatedAt.SET(table.CraftRecipes.EXCLUDED.UpdatedAt),
+		))
+
+	_, err := stmt.ExecContext(ctx, db.DB)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.GetCraftRecipe(ctx, recipe.CollectionID)
+}
+
+func (db *PostgresDB) GetMaterialBalances(ctx context.Context, userID int64) ([]model.MaterialBalances, error) {
+	stmt := table.MaterialBalances.
+		SELECT(table.MaterialBalances.AllColumns).
+		FROM(table.MaterialBalances).
+		WHERE(table.MaterialBalances.UserID.EQ(postgres.Int64(userID))).
+		ORDER_BY(table.MaterialBalances.CollectionID.ASC())
+
+	dest := []model.MaterialBalances{}
+	err := stmt.QueryContext(ctx, db.DB, &dest)
+	if err != nil {
+		return nil, err
+	}
+
+	return dest, nil
+}
+
+// SalvageCollectableInstances soft deletes instances owned by userID and
+// credits the materials they're worth. credits maps collection id to the
+// amount of materials to add for that collection.
+func (db *PostgresDB) SalvageCollectableInstances(ctx context.Context, userID int64, instanceIDs []int64, credits map[int64]int64) error {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	ids := make([]postgres.Expression, len(instanceIDs))
+	for i, id := range instanceIDs {
+		ids[i] = postgres.Int64(id)
+	}
+
+	now := time.Now()
+	res, err := table.CollectableInstances.
+		UPDATE(
+			table.CollectableInstances.DeletedAt,
+			table.CollectableInstances.SalvagedAt,
+		).
+		SET(
+			table.CollectableInstances.DeletedAt.SET(postgres.TimestampT(now)),
+			table.CollectableInstances.SalvagedAt.SET(postgres.TimestampT(now)),
+		).
+		WHERE(
+			table.CollectableInstances.ID.IN(ids...).
+				AND(table.CollectableInstances.OwnerID.EQ(postgres.Int64(userID))).
+				AND(table.CollectableInstances.DeletedAt.IS_NULL()),
+		).
+		ExecContext(ctx, tx)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n != int64(len(instanceIDs)) {
+		return ErrOwnershipChanged
+	}
+
+	_, err = table.UserEquipCollectableInstance.
+		DELETE().
+		WHERE(table.UserEquipCollectableInstance.InstanceID.IN(ids...)).
+		ExecContext(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	for collectionID, amount := range credits {
+		_, err = table.MaterialBalances.
+			INSERT(table.MaterialBalances.AllColumns).
+			MODEL(model.MaterialBalances{
+				UserID:       userID,
+				CollectionID: collectionID,
+				Balance:      amount,
+				UpdatedAt:    now,
+			}).
+			ON_CONFLICT(table.MaterialBalances.UserID, table.MaterialBalances.CollectionID).
+			DO_UPDATE(postgres.SET(
+				table.MaterialBalances.Balance.SET(table.MaterialBalances.Balance.ADD(table.MaterialBalances.EXCLUDED.Balance)),
+				table.MaterialBalances.UpdatedAt.SET(table.MaterialBalances.EXCLUDED.UpdatedAt),
+			)).
+			ExecContext(ctx, tx)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ForgeCollectableInstance spends cost materials from the owner's balance for
+// collectionID and creates instance in the same transaction.
+func (db *PostgresDB) ForgeCollectableInstance(ctx context.Context, instance model.CollectableInstances, collectionID int64, cost int64) (*CollectableInstance, error) {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	res, err := table.MaterialBalances.
+		UPDATE(
+			table.MaterialBalances.Balance,
+			table.MaterialBalances.UpdatedAt,
+		).
+		SET(
+			table.MaterialBalances.Balance.SET(table.MaterialBalances.Balance.SUB(postgres.Int64(cost))),
+			table.MaterialBalances.UpdatedAt.SET(postgres.TimestampT(time.Now())),
+		).
+		WHERE(
+			table.MaterialBalances.UserID.EQ(postgres.Int64(instance.OwnerID)).
+				AND(table.MaterialBalances.CollectionID.EQ(postgres.Int64(collectionID))).
+				AND(table.MaterialBalances.Balance.GT_EQ(postgres.Int64(cost))),
+		).
+		ExecContext(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, ErrInsufficientMaterials
+	}
+
+	err = insertCollectableInstance(ctx, tx, instance, TransTypeForge)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	instances, err := db.GetCollectableInstances(ctx, GetCollectableInstancesOptions{ByID: instance.ID})
+	if err != nil {
+		return nil, err
+	}
+	if len(instances) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return &instances[0], nil
+}
+
+// ClaimEventSubMessage records a twitch eventsub message id, returning false
+// if the message was already claimed by an earlier delivery.
+func (db *PostgresDB) ClaimEventSubMessage(ctx context.Context, msg model.EventsubMessages) (bool, error) {
+	msg.ReceivedAt = time.Now()
+
+	res, err := table.EventsubMessages.
+		INSERT(table.EventsubMessages.AllColumns).
+		MODEL(msg).
+		ON_CONFLICT(table.EventsubMessages.MessageID).
+		DO_NOTHING().
+		ExecContext(ctx, db.DB)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return n == 1, nil
+}
+
+// ReleaseEventSubMessage forgets a claimed message so that a redelivery is
+// processed again.
+func (db *PostgresDB) ReleaseEventSubMessage(ctx context.Context, messageID string) error {
+	_, err := table.EventsubMessages.
+		DELETE().
+		WHERE(table.EventsubMessages.MessageID.EQ(postgres.String(messageID))).
+		ExecContext(ctx, db.DB)
+	return err
+}
+
+func (db *PostgresDB) GetBroadcasterAuth(ctx context.Context, broadcasterID string) (*model.BroadcasterTokens, error) {
+	stmt := table.BroadcasterTokens.
+		SELECT(table.BroadcasterTokens.AllColumns).
+		FROM(table.BroadcasterTokens).
+		WHERE(table.BroadcasterTokens.BroadcasterID.EQ(postgres.String(broadcasterID)))
+
+	dest := model.BroadcasterTokens{}
+	err := stmt.QueryContext(ctx, db.DB, &dest)
+	if err != nil {
+		if errors.Is(err, qrm.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &dest, nil
+}
+
+func (db *PostgresDB) SaveBroadcasterAuth(ctx context.Context, auth model.BroadcasterTokens) error {
+	auth.UpdatedAt = time.Now()
+
+	stmt := table.BroadcasterTokens.
+		INSERT(table.BroadcasterTokens.AllColumns).
+		MODEL(auth).
+		ON_CONFLICT(table.BroadcasterTokens.BroadcasterID).
+		DO_UPDATE(postgres.SET(
+			table.BroadcasterTokens.UserID.SET(table.BroadcasterTokens.EXCLUDED.UserID),
+			table.BroadcasterTokens.AccessToken.SET(table.BroadcasterTokens.EXCLUDED.AccessToken),
+			table.BroadcasterTokens.RefreshToken.SET(table.BroadcasterTokens.EXCLUDED.RefreshToken),
+			table.BroadcasterTokens.Scopes.SET(table.BroadcasterTokens.EXCLUDED.Scopes),
+			table.BroadcasterTokens.ExpiresAt.SET(table.BroadcasterTokens.EXCLUDED.ExpiresAt),
+			table.BroadcasterTokens.UpdatedAt.SET(table.BroadcasterTokens.EXCLUDED.UpdatedAt),
+		))
+
+	_, err := stmt.ExecContext(ctx, db.DB)
+	return err
+}
+
+const (
+	RoleAdmin      = "admin"
+	RoleModerator  = "moderator"
+	RoleCurator    = "curator"
+	RoleFightJudge = "fight-judge"
+
+	RoleChangeGrant  = "grant"
+	RoleChangeRevoke = "revoke"
+)
+
+// Roles lists every role that can be granted.
+var Roles = []string{RoleAdmin, RoleModerator, RoleCurator, RoleFightJudge}
+
+func (db *PostgresDB) GetUserRoles(ctx context.Context, userID int64) ([]string, error) {
+	stmt := table.UserRoles.
+		SELECT(table.UserRoles.Role).
+		FROM(table.UserRoles).
+		WHERE(table.UserRoles.UserID.EQ(postgres.Int64(userID))).
+		ORDER_BY(table.UserRoles.Role)
+
+	dest := []string{}
+	err := stmt.QueryContext(ctx, db.DB, &dest)
+	if err != nil {
+		return nil, err
+	}
+
+	return dest, nil
+}
+
+func (db *PostgresDB) GetRoleChanges(ctx context.Context, userID int64) ([]model.UserRoleChanges, error) {
+	stmt := table.UserRoleChanges.
+		SELECT(table.UserRoleChanges.AllColumns).
+		FROM(table.UserRoleChanges).
+		WHERE(table.UserRoleChanges.UserID.EQ(postgres.Int64(userID))).
+		ORDER_BY(table.UserRoleChanges.CreatedAt.DESC(), table.UserRoleChanges.ID.DESC())
+
+	dest := []model.UserRoleChanges{}
+	err := stmt.QueryContext(ctx, db.DB, &dest)
+	if err != nil {
+		return nil, err
+	}
+
+	return dest, nil
+}
+
+// GrantRole gives userID the role and records who granted it, returning false
+// if the user already had the role.
+func (db *PostgresDB) GrantRole(ctx context.Context, userID int64, role string, actorID int64) (bool, error) {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	res, err := table.UserRoles.
+		INSERT(
+			table.UserRoles.UserID,
+			table.UserRoles.Role,
+			table.UserRoles.GrantedBy,
+			table.UserRoles.CreatedAt,
+		).
+		MODEL(model.UserRoles{
+			UserID:    userID,
+			Role:      role,
+			GrantedBy: &actorID,
+			CreatedAt: time.Now(),
+		}).
+		ON_CONFLICT(table.UserRoles.UserID, table.UserRoles.Role).
+		DO_NOTHING().
+		ExecContext(ctx, tx)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if n == 0 {
+		return false, nil
+	}
+
+	err = insertRoleChange(ctx, tx, userID, role, RoleChangeGrant, actorID)
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// RevokeRole removes the role from userID and records who revoked it,
+// returning false if the user didn't have the role.
+func (db *PostgresDB) RevokeRole(ctx context.Context, userID int64, role string, actorID int64) (bool, error) {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	res, err := table.UserRoles.
+		DELETE().
+		WHERE(
+			table.UserRoles.UserID.EQ(postgres.Int64(userID)).
+				AND(table.UserRoles.Role.EQ(postgres.String(role))),
+		).
+		ExecContext(ctx, tx)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if n == 0 {
+		return false, nil
+	}
+
+	err = insertRoleChange(ctx, tx, userID, role, RoleChangeRevoke, actorID)
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+func insertRoleChange(ctx context.Context, tx *sql.Tx, userID int64, role string, action string, actorID int64) error {
+	_, err := table.UserRoleChanges.
+		INSERT(
+			table.UserRoleChanges.UserID,
+			table.UserRoleChanges.Role,
+			table.UserRoleChanges.Action,
+			table.UserRoleChanges.ActorID,
+			table.UserRoleChanges.CreatedAt,
+		).
+		MODEL(model.UserRoleChanges{
+			UserID:    userID,
+			Role:      role,
+			Action:    action,
+			ActorID:   actorID,
+			CreatedAt: time.Now(),
+		}).
+		ExecContext(ctx, tx)
+	return err
+}
+
+type GetAuditLogOptions struct {
+	ActorID    int64
+	TargetType string
+	TargetID   int64
+	Since      time.Time
+	Until      time.Time
+	Limit      int64
+}
+
+func (db *PostgresDB) AddAuditEntry(ctx context.Context, entry model.AuditLog) error {
+	entry.CreatedAt = time.Now()
+
+	_, err := table.AuditLog.
+		INSERT(table.AuditLog.MutableColumns).
+		MODEL(entry).
+		ExecContext(ctx, db.DB)
+	return err
+}
+
+func (db *PostgresDB) GetAuditLog(ctx context.Context, options GetAuditLogOptions) ([]model.AuditLog, error) {
+	limit := options.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+
+	stmt := table.AuditLog.
+		SELECT(table.AuditLog.AllColumns).
+		FROM(table.AuditLog)
+
+	c := ConstraintBuilder{}
+	if options.ActorID != 0 {
+		c.Add(table.AuditLog.ActorID.EQ(postgres.Int64(options.ActorID)))
+	}
+	if options.TargetType != "" {
+		c.Add(table.AuditLog.TargetType.EQ(postgres.String(options.TargetType)))
+	}
+	if options.TargetID != 0 {
+		c.Add(table.AuditLog.TargetID.EQ(postgres.Int64(options.TargetID)))
+	}
+	if !options.Since.IsZero() {
+		c.Add(table.AuditLog.CreatedAt.GT_EQ(postgres.TimestampT(options.Since)))
+	}
+	if !options.Until.IsZero() {
+		c.Add(table.AuditLog.CreatedAt.LT(postgres.TimestampT(options.Until)))
+	}
+	stmt = c.Apply(stmt)
+
+	stmt = stmt.
+		ORDER_BY(table.AuditLog.CreatedAt.DESC(), table.AuditLog.ID.DESC()).
+		LIMIT(limit)
+
+	dest := []model.AuditLog{}
+	err := stmt.QueryContext(ctx, db.DB, &dest)
+	if err != nil {
+		return nil, err
+	}
+
+	return dest, nil
+}
@@ -0,0 +1,242 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	model "github.com/cconger/shindaggers/pkg/db/.gen/postgres/public/model"
+	"github.com/go-faker/faker/v4"
+)
+
+var (
+	seedRarityWeights = map[string]int{
+		"Common":     55,
+		"Uncommon":   25,
+		"Rare":       12,
+		"Super Rare": 6,
+		"Ultra Rare": 2,
+	}
+	seedKnifeTypes = []string{"Knife", "Dagger", "Blade", "Shiv", "Cleaver", "Dirk", "Stiletto"}
+)
+
+// Seed fills the MemoryDB with fake users, collectables and pulls so that the
+// site is usable in -nodb mode. adminToken is the hashed session token saved
+// for an admin user so the admin pages can be reached without twitch oauth.
+func (m *MemoryDB) Seed(adminToken []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	admin := true
+
+	adminUser := model.Users{
+		ID:        m.genID(),
+		Name:      "admin",
+		CreatedAt: now,
+		Admin:     &admin,
+	}
+	m.users[adminUser.ID] = adminUser
+	m.roles[adminUser.ID] = []model.UserRoles{{
+		UserID:    adminUser.ID,
+		Role:      RoleAdmin,
+		CreatedAt: now,
+	}}
+	m.tokens = append(m.tokens, model.UserTokens{
+		UserID:    adminUser.ID,
+		Token:     adminToken,
+		CreatedAt: now,
+		UpdatedAt: now,
+		// Outlives any dev session
+		SessionExpiresAt: now.AddDate(1, 0, 0),
+	})
+
+	users := []model.Users{adminUser}
+	for i := 0; i < 25; i++ {
+		twitchID := fmt.Sprint(rand.Int63n(1_000_000_000))
+		u := model.Users{
+			ID:        m.genID(),
+			TwitchID:  &twitchID,
+			Name:      faker.Username(),
+			CreatedAt: now.Add(-time.Duration(rand.Intn(365*24)) * time.Hour),
+		}
+		m.users[u.ID] = u
+		users = append(users, u)
+	}
+
+	m.editions[DefaultEditionID] = model.Editions{
+		ID:        DefaultEditionID,
+		Name:      "Standard",
+		CreatedAt: now,
+	}
+	firstN := int64(3)
+	m.editions[2] = model.Editions{
+		ID:        2,
+		Name:      "First Edition",
+		FirstN:    &firstN,
+		CreatedAt: now,
+		ActiveAt:  &now,
+	}
+
+	encoded, err := json.Marshal(seedRarityWeights)
+	if err != nil {
+		return err
+	}
+	weights := string(encoded)
+	collection := model.Collections{
+		ID:        1,
+		Name:      "Shindaggers",
+		Weights:   &weights,
+		CreatorID: adminUser.ID,
+		CreatedAt: now,
+		UpdatedAt: now,
+		ActiveAt:  &now,
+	}
+	m.collections[collection.ID] = collection
+
+	m.craftRecipes[collection.ID] = model.CraftRecipes{
+		CollectionID: collection.ID,
+		Salvage:      `{"Common": 1, "Uncommon": 3, "Rare": 8, "Super Rare": 20, "Ultra Rare": 50}`,
+		Forge:        `{"Uncommon": 5, "Rare": 15, "Super Rare": 40, "Ultra Rare": 100}`,
+		UpdatedBy:    &adminUser.ID,
+		UpdatedAt:    now,
+	}
+
+	byRarity := map[string][]int64{}
+	for rarity, weight := range seedRarityWeights {
+		// Keep at least one of every rarity so pulls can always resolve
+		for i := 0; i < 1+weight/5; i++ {
+			creator := users[rand.Intn(len(users))]
+			approvedAt := now.Add(-time.Duration(rand.Intn(90*24)) * time.Hour)
+			c := model.Collectables{
+				ID:           m.genID(),
+				Name:         seedKnifeName(),
+				CollectionID: &collection.ID,
+				CreatorID:    creator.ID,
+				Rarity:       rarity,
+				Imagepath:    fmt.Sprintf("%s.png", faker.Word()),
+				ApprovedAt:   &approvedAt,
+				ApprovedBy:   &adminUser.ID,
+				ReviewStatus: ReviewApproved,
+				CreatedAt:    approvedAt,
+			}
+			m.collectables[c.ID] = c
+			byRarity[rarity] = append(byRarity[rarity], c.ID)
+		}
+	}
+
+	// A few submissions waiting in the approval queue
+	for i := 0; i < 3; i++ {
+		creator := users[1+rand.Intn(len(users)-1)]
+		c := model.Collectables{
+			ID:           m.genID(),
+			Name:         seedKnifeName(),
+			CollectionID: &collection.ID,
+			CreatorID:    creator.ID,
+			Rarity:       "Common",
+			Imagepath:    fmt.Sprintf("%s.png", faker.Word()),
+			ReviewStatus: ReviewPending,
+			CreatedAt:    now.Add(-time.Duration(rand.Intn(48)) * time.Hour),
+		}
+		m.collectables[c.ID] = c
+	}
+
+	for i := 0; i < 200; i++ {
+		owner := users[1+rand.Intn(len(users)-1)]
+		rarity := seedRarity()
+		ids := byRarity[rarity]
+		tags := fmt.Sprintf(`{"subscriber": %t, "verified": %t}`, rand.Intn(3) == 0, rand.Intn(100) == 0)
+		inst := model.CollectableInstances{
+			ID:            m.genID(),
+			CollectableID: ids[rand.Intn(len(ids))],
+			OwnerID:       owner.ID,
+			EditionID:     DefaultEditionID,
+			CreatedAt:     now.Add(-time.Duration(rand.Intn(30*24*60)) * time.Minute),
+			Tags:          &tags,
+		}
+		m.instances[inst.ID] = inst
+		m.recordOwnership(inst)
+
+		if _, ok := m.equipped[owner.ID]; !ok && rand.Intn(2) == 0 {
+			instID := inst.ID
+			m.equipped[owner.ID] = model.UserEquipCollectableInstance{
+				UserID:     owner.ID,
+				InstanceID: &instID,
+				EquippedAt: &now,
+			}
+		}
+	}
+
+	// Some fight history between users with knives equipped
+	// The first batch of fights belong to an event that has already closed
+	eventStart := now.Add(-14 * 24 * time.Hour)
+	eventEnd := eventStart.Add(3 * time.Hour)
+	description := "The first ever shindaggers knife fight tournament"
+	event := model.Events{
+		ID:          m.genID(),
+		Name:        "Launch Tournament",
+		Slug:        "launch-tournament",
+		Description: &description,
+		CreatorID:   adminUser.ID,
+		CreatedAt:   eventStart,
+		ClosedAt:    &eventEnd,
+	}
+	m.events[event.ID] = event
+
+	fighters := []int64{}
+	for userID := range m.equipped {
+		fighters = append(fighters, userID)
+	}
+	for i := 0; len(fighters) > 1 && i < 40; i++ {
+		fight := model.Fights{
+			ID:        m.genID(),
+			CreatedAt: now.Add(-time.Duration(rand.Intn(30*24*60)) * time.Minute),
+		}
+		if i < 15 {
+			fight.CreatedAt = eventStart.Add(time.Duration(rand.Intn(180)) * time.Minute)
+			fight.EventID = &event.ID
+		}
+		m.fights[fight.ID] = fight
+
+		perm := rand.Perm(len(fighters))
+		outcomes := []string{FightOutcomeWin, FightOutcomeLoss}
+		if rand.Intn(10) == 0 {
+			outcomes = []string{FightOutcomeDraw, FightOutcomeDraw}
+		}
+		for j, outcome := range outcomes {
+			userID := fighters[perm[j]]
+			instance := m.instances[*m.equipped[userID].InstanceID]
+			m.fightOutcomes = append(m.fightOutcomes, model.FightOutcomes{
+				FightID:       fight.ID,
+				UserID:        userID,
+				InstanceID:    &instance.ID,
+				CollectableID: &instance.CollectableID,
+				Outcome:       outcome,
+			})
+		}
+	}
+
+	return nil
+}
+
+func seedKnifeName() string {
+	word := faker.Word()
+	return strings.ToUpper(word[:1]) + word[1:] + " " + seedKnifeTypes[rand.Intn(len(seedKnifeTypes))]
+}
+
+func seedRarity() string {
+	sum := 0
+	for _, w := range seedRarityWeights {
+		sum += w
+	}
+	roll := rand.Intn(sum)
+	for rarity, w := range seedRarityWeights {
+		if roll < w {
+			return rarity
+		}
+		roll -= w
+	}
+	return "Common"
+}
@@ -0,0 +1,212 @@
+package discord
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var errRejected = errors.New("discord rejected message")
+
+const (
+	queueSize   = 64
+	maxAttempts = 5
+)
+
+// Backoff between retries, vars so tests don't have to wait on them
+var (
+	baseBackoff = time.Second
+	maxBackoff  = time.Minute
+)
+
+// Message is the body of a webhook execution, only the fields we use.
+type Message struct {
+	Content string  `json:"content,omitempty"`
+	Embeds  []Embed `json:"embeds,omitempty"`
+}
+
+type Embed struct {
+	Title       string       `json:"title,omitempty"`
+	Description string       `json:"description,omitempty"`
+	URL         string       `json:"url,omitempty"`
+	Color       int          `json:"color,omitempty"`
+	Image       *EmbedImage  `json:"image,omitempty"`
+	Fields      []EmbedField `json:"fields,omitempty"`
+	Timestamp   *time.Time   `json:"timestamp,omitempty"`
+}
+
+type EmbedImage struct {
+	URL string `json:"url"`
+}
+
+type EmbedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline,omitempty"`
+}
+
+// Webhook posts messages to a discord webhook from a background goroutine so
+// callers never wait on discord. A nil *Webhook drops everything, which is
+// what you get when no webhook is configured.
+type Webhook struct {
+	URL    string
+	Client *http.Client
+
+	mu     sync.Mutex
+	closed bool
+	queue  chan Message
+	done   chan struct{}
+
+	// Discord tells us when a bucket is empty, hold off until it resets
+	pauseUntil time.Time
+}
+
+func NewWebhook(url string, client *http.Client) *Webhook {
+	w := &Webhook{
+		URL:    url,
+		Client: client,
+		queue:  make(chan Message, queueSize),
+		done:   make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Send queues a message. If discord is so far behind that the queue is full
+// the message is dropped rather than blocking the request that sent it.
+func (w *Webhook) Send(m Message) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		slog.Warn("discord webhook closed, dropping message")
+		return
+	}
+	select {
+	case w.queue <- m:
+	default:
+		slog.Warn("discord webhook queue full, dropping message")
+	}
+}
+
+// Close stops accepting messages and waits for the queue to drain.
+func (w *Webhook) Close() {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.queue)
+	}
+	w.mu.Unlock()
+	<-w.done
+}
+
+func (w *Webhook) run() {
+	defer close(w.done)
+	for m := range w.queue {
+		err := w.deliver(context.Background(), m)
+		if err != nil {
+			slog.Error("sending discord webhook", "err", err)
+		}
+	}
+}
+
+// deliver posts m, retrying server errors and network failures with
+// exponential backoff and waiting out any rate limit discord reports.
+func (w *Webhook) deliver(ctx context.Context, m Message) error {
+	body, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	backoff := baseBackoff
+	for attempt := 1; ; attempt++ {
+		if wait := time.Until(w.pauseUntil); wait > 0 {
+			time.Sleep(wait)
+		}
+
+		retryAfter, err := w.post(ctx, body)
+		if err == nil || errors.Is(err, errRejected) {
+			return err
+		}
+		if attempt >= maxAttempts {
+			return fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+		}
+
+		wait := retryAfter
+		if wait == 0 {
+			wait = backoff
+			backoff *= 2
+			if backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		}
+		slog.Warn("retrying discord webhook", "attempt", attempt, "wait", wait, "err", err)
+		time.Sleep(wait)
+	}
+}
+
+// post makes a single attempt. A nil error means the message was accepted,
+// a non zero duration is how long discord asked us to wait before retrying.
+func (w *Webhook) post(ctx context.Context, body []byte) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		w.pauseUntil = time.Now().Add(headerSeconds(resp.Header.Get("X-RateLimit-Reset-After")))
+	}
+
+	switch {
+	case resp.StatusCode < 300:
+		return 0, nil
+	case resp.StatusCode == http.StatusTooManyRequests:
+		var limited struct {
+			RetryAfter float64 `json:"retry_after"`
+		}
+		json.Unmarshal(respBody, &limited)
+		wait := time.Duration(limited.RetryAfter * float64(time.Second))
+		if wait == 0 {
+			wait = headerSeconds(resp.Header.Get("Retry-After"))
+		}
+		if wait < baseBackoff {
+			wait = baseBackoff
+		}
+		return wait, fmt.Errorf("rate limited: %s", respBody)
+	case resp.StatusCode >= 500:
+		return 0, fmt.Errorf("discord returned %d: %s", resp.StatusCode, respBody)
+	default:
+		// Anything else is our fault and won't get better by retrying
+		return 0, fmt.Errorf("%w: discord returned %d: %s", errRejected, resp.StatusCode, respBody)
+	}
+}
+
+func headerSeconds(v string) time.Duration {
+	s, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,171 @@
+package discord
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// standIn records every message posted to it and answers with whatever
+// respond returns for that attempt, counting from 1.
+type standIn struct {
+	mu       sync.Mutex
+	received []Message
+	times    []time.Time
+	respond  func(attempt int, w http.ResponseWriter)
+}
+
+func newStandIn(t *testing.T, respond func(attempt int, w http.ResponseWriter)) (*standIn, *Webhook) {
+	t.Helper()
+
+	oldBase, oldMax := baseBackoff, maxBackoff
+	baseBackoff, maxBackoff = 10*time.Millisecond, 50*time.Millisecond
+	t.Cleanup(func() {
+		baseBackoff, maxBackoff = oldBase, oldMax
+	})
+
+	s := &standIn{respond: respond}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var m Message
+		err := json.NewDecoder(r.Body).Decode(&m)
+		if err != nil {
+			t.Errorf("decoding message: %s", err)
+		}
+
+		s.mu.Lock()
+		s.received = append(s.received, m)
+		s.times = append(s.times, time.Now())
+		attempt := len(s.received)
+		s.mu.Unlock()
+
+		if s.respond == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		s.respond(attempt, w)
+	}))
+	t.Cleanup(srv.Close)
+
+	return s, NewWebhook(srv.URL, srv.Client())
+}
+
+func (s *standIn) attempts() ([]Message, []time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.received, s.times
+}
+
+func TestWebhookPosts(t *testing.T) {
+	s, hook := newStandIn(t, nil)
+
+	hook.Send(Message{Content: "a knife was pulled"})
+	hook.Close()
+
+	received, _ := s.attempts()
+	if len(received) != 1 {
+		t.Fatalf("got %d posts, want 1", len(received))
+	}
+	if received[0].Content != "a knife was pulled" {
+		t.Errorf("got content %q", received[0].Content)
+	}
+}
+
+func TestWebhookRetriesServerErrors(t *testing.T) {
+	s, hook := newStandIn(t, func(attempt int, w http.ResponseWriter) {
+		if attempt < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	hook.Send(Message{Content: "retry me"})
+	hook.Close()
+
+	received, _ := s.attempts()
+	if len(received) != 3 {
+		t.Fatalf("got %d attempts, want 3", len(received))
+	}
+	for _, m := range received {
+		if m.Content != "retry me" {
+			t.Errorf("got content %q", m.Content)
+		}
+	}
+}
+
+func TestWebhookDoesNotRetryRejections(t *testing.T) {
+	s, hook := newStandIn(t, func(attempt int, w http.ResponseWriter) {
+		w.WriteHeader(http.StatusBadRequest)
+	})
+
+	hook.Send(Message{Content: "bad embed"})
+	hook.Close()
+
+	received, _ := s.attempts()
+	if len(received) != 1 {
+		t.Fatalf("got %d attempts, want 1", len(received))
+	}
+}
+
+func TestWebhookHonoursRetryAfter(t *testing.T) {
+	const retryAfter = 200 * time.Millisecond
+
+	s, hook := newStandIn(t, func(attempt int, w http.ResponseWriter) {
+		if attempt == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			json.NewEncoder(w).Encode(map[string]any{
+				"message":     "You are being rate limited.",
+				"retry_after": retryAfter.Seconds(),
+				"global":      false,
+			})
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	hook.Send(Message{Content: "slow down"})
+	hook.Close()
+
+	_, times := s.attempts()
+	if len(times) != 2 {
+		t.Fatalf("got %d attempts, want 2", len(times))
+	}
+	// The body's retry_after is more precise than the header and wins
+	if waited := times[1].Sub(times[0]); waited < retryAfter || waited >= time.Second {
+		t.Errorf("retried after %s, want %s", waited, retryAfter)
+	}
+}
+
+func TestWebhookCloseDrainsQueue(t *testing.T) {
+	s, hook := newStandIn(t, func(attempt int, w http.ResponseWriter) {
+		// Slow enough that messages are still queued when Close is called
+		time.Sleep(20 * time.Millisecond)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	want := []string{"one", "two", "three", "four", "five"}
+	for _, content := range want {
+		hook.Send(Message{Content: content})
+	}
+	hook.Close()
+
+	received, _ := s.attempts()
+	if len(received) != len(want) {
+		t.Fatalf("got %d posts, want %d", len(received), len(want))
+	}
+	for i, m := range received {
+		if m.Content != want[i] {
+			t.Errorf("post %d got %q, want %q", i, m.Content, want[i])
+		}
+	}
+
+	// Messages sent after closing are dropped
+	hook.Send(Message{Content: "late"})
+	if received, _ := s.attempts(); len(received) != len(want) {
+		t.Errorf("got %d posts after close, want %d", len(received), len(want))
+	}
+}
@@ -0,0 +1,261 @@
+// Package images validates uploaded knife images and produces the versions
+// we store: the original re-encoded without metadata plus fixed size
+// thumbnails for cards and mini cards.
+package images
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"math/bits"
+	"net/http"
+	"path"
+	"strings"
+
+	// Registers the gif decoder, gifs are stored as png
+	_ "image/gif"
+)
+
+var (
+	ErrNotImage = errors.New("not a supported image")
+	ErrTooLarge = errors.New("image dimensions too large")
+)
+
+const (
+	// MaxDimension is the largest width or height accepted, checked before
+	// decoding so huge images can't exhaust memory.
+	MaxDimension = 4096
+
+	CardSize = 480
+	MiniSize = 200
+
+	jpegQuality = 90
+
+	// NearDuplicate is the most bits two hashes can differ by and still be
+	// considered the same picture, enough to allow for small crops and
+	// re-encoding.
+	NearDuplicate = 10
+)
+
+// Image is one encoded version of an upload.
+type Image struct {
+	Data        []byte
+	ContentType string
+	Ext         string
+	Width       int
+	Height      int
+}
+
+type Processed struct {
+	Original Image
+	Card     Image
+	Mini     Image
+
+	// Hash is a perceptual hash of the image, see Distance.
+	Hash uint64
+}
+
+// CardName and MiniName are where the thumbnails for an image are stored,
+// next to the original.
+func CardName(name string) string {
+	return thumbnailName(name, "card")
+}
+
+func MiniName(name string) string {
+	return thumbnailName(name, "mini")
+}
+
+func thumbnailName(name, suffix string) string {
+	return strings.TrimSuffix(name, path.Ext(name)) + "_" + suffix + ".png"
+}
+
+// Process checks data is an image we accept by looking at its contents
+// rather than trusting what the client says, then re-encodes it. Decoding and
+// re-encoding drops any EXIF or other metadata.
+func Process(data []byte) (*Processed, error) {
+	contentType := http.DetectContentType(data)
+	switch contentType {
+	case "image/png", "image/jpeg", "image/gif":
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrNotImage, contentType)
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotImage, err)
+	}
+	if cfg.Width > MaxDimension || cfg.Height > MaxDimension {
+		return nil, fmt.Errorf("%w: %dx%d", ErrTooLarge, cfg.Width, cfg.Height)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotImage, err)
+	}
+
+	var original Image
+	if contentType == "image/jpeg" {
+		original, err = encodeJPEG(img)
+	} else {
+		original, err = encodePNG(img)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	src := toRGBA(img)
+
+	card, err := encodePNG(fit(src, CardSize))
+	if err != nil {
+		return nil, err
+	}
+	mini, err := encodePNG(fit(src, MiniSize))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Processed{
+		Original: original,
+		Card:     card,
+		Mini:     mini,
+		Hash:     hash(src),
+	}, nil
+}
+
+func encodePNG(img image.Image) (Image, error) {
+	var buf bytes.Buffer
+	err := png.Encode(&buf, img)
+	if err != nil {
+		return Image{}, err
+	}
+	b := img.Bounds()
+	return Image{
+		Data:        buf.Bytes(),
+		ContentType: "image/png",
+		Ext:         ".png",
+		Width:       b.Dx(),
+		Height:      b.Dy(),
+	}, nil
+}
+
+func encodeJPEG(img image.Image) (Image, error) {
+	var buf bytes.Buffer
+	err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+	if err != nil {
+		return Image{}, err
+	}
+	b := img.Bounds()
+	return Image{
+		Data:        buf.Bytes(),
+		ContentType: "image/jpeg",
+		Ext:         ".jpg",
+		Width:       b.Dx(),
+		Height:      b.Dy(),
+	}, nil
+}
+
+// fit scales src down to fit in a size x size square, keeping its aspect
+// ratio, and centers it on a transparent canvas. Smaller images are centered
+// without being scaled up.
+func fit(src *image.RGBA, size int) *image.RGBA {
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	if w > size || h > size {
+		if w >= h {
+			w, h = size, h*size/w
+		} else {
+			w, h = w*size/h, size
+		}
+	}
+	if w < 1 {
+		w = 1
+	}
+	if h < 1 {
+		h = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	offset := image.Pt((size-w)/2, (size-h)/2)
+	scale(dst, image.Rectangle{Min: offset, Max: offset.Add(image.Pt(w, h))}, src)
+	return dst
+}
+
+func toRGBA(img image.Image) *image.RGBA {
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Src)
+	return dst
+}
+
+// hash is a difference hash: the image shrunk to 9x8 and each bit set when
+// a pixel is darker than its right neighbour. Similar pictures differ in few
+// bits whatever their size or encoding.
+func hash(src *image.RGBA) uint64 {
+	small := image.NewRGBA(image.Rect(0, 0, 9, 8))
+	scale(small, small.Bounds(), src)
+
+	var h uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			if luminance(small, x, y) < luminance(small, x+1, y) {
+				h |= 1 << (y*8 + x)
+			}
+		}
+	}
+	return h
+}
+
+func luminance(img *image.RGBA, x, y int) int {
+	i := img.PixOffset(x, y)
+	p := img.Pix[i : i+3]
+	return 299*int(p[0]) + 587*int(p[1]) + 114*int(p[2])
+}
+
+// Distance is how many bits two hashes differ by, 0 for the same picture.
+func Distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// scale is an area average resize of src into r of dst. It works on
+// premultiplied colour so transparent pixels don't darken the edges.
+func scale(dst *image.RGBA, r image.Rectangle, src *image.RGBA) {
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	dw, dh := r.Dx(), r.Dy()
+
+	for y := 0; y < dh; y++ {
+		y0 := y * sh / dh
+		y1 := (y + 1) * sh / dh
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < dw; x++ {
+			x0 := x * sw / dw
+			x1 := (x + 1) * sw / dw
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			var sr, sg, sb, sa, n int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					sr += int(p[0])
+					sg += int(p[1])
+					sb += int(p[2])
+					sa += int(p[3])
+					n++
+				}
+			}
+
+			i := dst.PixOffset(r.Min.X+x, r.Min.Y+y)
+			dst.Pix[i] = uint8(sr / n)
+			dst.Pix[i+1] = uint8(sg / n)
+			dst.Pix[i+2] = uint8(sb / n)
+			dst.Pix[i+3] = uint8(sa / n)
+		}
+	}
+}
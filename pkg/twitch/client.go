@@ -10,6 +10,7 @@ import (
 	"net/url"
 	"strings"
 	"sync"
+	"time"
 )
 
 var (
@@ -17,10 +18,17 @@ var (
 	errCannotRefresh = errors.New("cannot refresh")
 )
 
+const (
+	DefaultAuthURL  = "https://id.twitch.tv"
+	DefaultHelixURL = "https://api.twitch.tv/helix"
+)
+
 type TwitchClient interface {
 	OAuthGetToken(context.Context, string, string) (*GetTokenResponse, error)
 	GetUsersByID(context.Context, ...string) ([]*TwitchUser, error)
 	UserClient(*UserAuth) UserClient
+	CreateEventSubSubscription(context.Context, EventSubSubscriptionRequest) (*EventSubSubscription, error)
+	GetBroadcasterSubscriptions(context.Context, *UserAuth, string, ...string) ([]*Subscription, error)
 }
 
 type UserClient interface {
@@ -32,76 +40,155 @@ type Client struct {
 	ClientID     string
 	ClientSecret string
 
+	// AuthURL and HelixURL can be pointed at a local stand-in for twitch
+	AuthURL  string
+	HelixURL string
+
 	auth AuthProvider
 }
 
 type AuthProvider interface {
-	Token() (string, error)
-	Refresh()
+	Token(context.Context) (string, error)
+	Refresh(context.Context) error
 }
 
 type AppAuth struct {
-	ID     string
-	Secret string
+	ID      string
+	Secret  string
+	AuthURL string
 
-	once sync.Once
-	t    string
+	mu        sync.Mutex
+	t         string
+	expiresAt time.Time
 }
 
-func (a *AppAuth) Token() (string, error) {
-	var err error
-	a.once.Do(func() {
-		a.t, err = a.getToken()
-		if err != nil {
-			slog.Error("getting appToken", "err", err)
-			a.once = sync.Once{}
-		}
-	})
-	return a.t, err
+// Token returns the app token, fetching a new one when there is none yet or
+// it is about to expire. Failed fetches are retried on the next call.
+func (a *AppAuth) Token(ctx context.Context) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// A token without an expiry is kept until twitch rejects it
+	if a.t != "" && (a.expiresAt.IsZero() || time.Now().Before(a.expiresAt.Add(-userTokenExpirySkew))) {
+		return a.t, nil
+	}
+
+	t, err := a.getToken()
+	if err != nil {
+		slog.Error("getting appToken", "err", err)
+		return "", err
+	}
+	a.t = t.AccessToken
+	a.expiresAt = time.Time{}
+	if t.ExpiresIn > 0 {
+		a.expiresAt = time.Now().Add(time.Duration(t.ExpiresIn) * time.Second)
+	}
+	return a.t, nil
 }
 
-func (a *AppAuth) getToken() (string, error) {
+func (a *AppAuth) getToken() (*GetTokenResponse, error) {
 	slog.Info("getting appToken")
 	params := url.Values{}
 	params.Add("client_id", a.ID)
 	params.Add("client_secret", a.Secret)
 	params.Add("grant_type", "client_credentials")
 
-	req, err := http.NewRequest(http.MethodPost, "https://id.twitch.tv/oauth2/token", strings.NewReader(params.Encode()))
+	req, err := http.NewRequest(http.MethodPost, a.AuthURL+"/oauth2/token", strings.NewReader(params.Encode()))
 	if err != nil {
-		return "", fmt.Errorf("creating oauth request: %w", err)
+		return nil, fmt.Errorf("creating oauth request: %w", err)
 	}
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("post oauth request: %w", err)
+		return nil, fmt.Errorf("post oauth request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	var tokenResp GetTokenResponse
 	err = json.NewDecoder(resp.Body).Decode(&tokenResp)
 	if err != nil {
-		return "", fmt.Errorf("deserializing oauth: %w", err)
+		return nil, fmt.Errorf("deserializing oauth: %w", err)
+	}
+	if tokenResp.AccessToken == "" {
+		return nil, fmt.Errorf("oauth returned %d without a token", resp.StatusCode)
 	}
 
-	return tokenResp.AccessToken, nil
+	return &tokenResp, nil
 }
 
-func (a *AppAuth) Refresh() {
-	a.once = sync.Once{}
+// Refresh drops the app token so the next call to Token fetches a new one.
+func (a *AppAuth) Refresh(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.t = ""
+	return nil
 }
 
+// Refresh user tokens a little before twitch would start rejecting them
+const userTokenExpirySkew = time.Minute
+
 type UserAuth struct {
 	AccessToken  string
 	RefreshToken string
+	// ExpiresAt is when AccessToken stops working, the zero value means unknown
+	ExpiresAt time.Time
+
+	// OnRefresh is called after the tokens have been refreshed so that they can
+	// be persisted.
+	OnRefresh func(context.Context, *UserAuth) error
+
+	mu      sync.Mutex
+	refresh func(context.Context, *UserAuth) (*GetTokenResponse, error)
 }
 
-func (ua *UserAuth) Token() (string, error) {
+func (ua *UserAuth) Token(ctx context.Context) (string, error) {
+	ua.mu.Lock()
+	defer ua.mu.Unlock()
+
+	if !ua.ExpiresAt.IsZero() && time.Now().After(ua.ExpiresAt.Add(-userTokenExpirySkew)) {
+		err := ua.refreshLocked(ctx)
+		if err != nil {
+			return "", err
+		}
+	}
+
 	return ua.AccessToken, nil
 }
 
-func (ua *UserAuth) Refresh() {
-	slog.Warn("refreshing user token not implemented")
+func (ua *UserAuth) Refresh(ctx context.Context) error {
+	ua.mu.Lock()
+	defer ua.mu.Unlock()
+
+	return ua.refreshLocked(ctx)
+}
+
+func (ua *UserAuth) refreshLocked(ctx context.Context) error {
+	if ua.refresh == nil || ua.RefreshToken == "" {
+		return errCannotRefresh
+	}
+
+	slog.Info("refreshing user token")
+	t, err := ua.refresh(ctx, ua)
+	if err != nil {
+		return fmt.Errorf("refreshing user token: %w", err)
+	}
+
+	ua.AccessToken = t.AccessToken
+	if t.RefreshToken != "" {
+		ua.RefreshToken = t.RefreshToken
+	}
+	ua.ExpiresAt = time.Now().Add(time.Duration(t.ExpiresIn) * time.Second)
+
+	if ua.OnRefresh != nil {
+		err = ua.OnRefresh(ctx, ua)
+		if err != nil {
+			// The new token is still good for this client
+			slog.Error("persisting refreshed user token", "err", err)
+		}
+	}
+
+	return nil
 }
 
 func NewClient(clientID string, clientSecret string, httpClient *http.Client) (*Client, error) {
@@ -117,30 +204,84 @@ func NewClient(clientID string, clientSecret string, httpClient *http.Client) (*
 		Client:       httpClient,
 		ClientID:     clientID,
 		ClientSecret: clientSecret,
+		AuthURL:      DefaultAuthURL,
+		HelixURL:     DefaultHelixURL,
 		auth: &AppAuth{
-			ID:     clientID,
-			Secret: clientSecret,
+			ID:      clientID,
+			Secret:  clientSecret,
+			AuthURL: DefaultAuthURL,
 		},
 	}, nil
 }
 
+// SetEndpoints overrides the twitch auth and helix base urls, empty values
+// leave the current endpoint in place.
+func (c *Client) SetEndpoints(authURL string, helixURL string) {
+	if authURL != "" {
+		c.AuthURL = strings.TrimSuffix(authURL, "/")
+		if app, ok := c.auth.(*AppAuth); ok {
+			app.AuthURL = c.AuthURL
+			app.Refresh(context.Background())
+		}
+	}
+	if helixURL != "" {
+		c.HelixURL = strings.TrimSuffix(helixURL, "/")
+	}
+}
+
 func (c *Client) UserClient(ua *UserAuth) UserClient {
+	return c.userClient(ua)
+}
+
+// userClient makes requests with the user's token, refreshing it through c
+// when it expires.
+func (c *Client) userClient(ua *UserAuth) *Client {
+	ua.mu.Lock()
+	ua.refresh = c.OAuthRefreshToken
+	ua.mu.Unlock()
+
 	return &Client{
 		Client:       c.Client,
 		ClientID:     c.ClientID,
 		ClientSecret: c.ClientSecret,
+		AuthURL:      c.AuthURL,
+		HelixURL:     c.HelixURL,
 		auth:         ua,
 	}
 }
 
 func (c *Client) do(req *http.Request) (*http.Response, error) {
-	token, err := c.auth.Token()
+	resp, err := c.doOnce(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	resp.Body.Close()
+
+	// The token was rejected early, refresh it and try once more
+	err = c.auth.Refresh(req.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		retry.Body, err = req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return c.doOnce(retry)
+}
+
+func (c *Client) doOnce(req *http.Request) (*http.Response, error) {
+	token, err := c.auth.Token(req.Context())
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
-	req.Header.Add("Client-Id", c.ClientID)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	req.Header.Set("Client-Id", c.ClientID)
 
 	resp, err := c.Client.Do(req)
 	if err != nil {
@@ -175,7 +316,7 @@ func (c *Client) OAuthGetToken(ctx context.Context, code string, redirectURI str
 		"redirect_uri":  []string{redirectURI},
 	}
 
-	r, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://id.twitch.tv/oauth2/token", strings.NewReader(payload.Encode()))
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, c.AuthURL+"/oauth2/token", strings.NewReader(payload.Encode()))
 	if err != nil {
 		return nil, err
 	}
@@ -206,12 +347,13 @@ func (c *Client) OAuthRefreshToken(ctx context.Context, ua *UserAuth) (*GetToken
 	r, err := http.NewRequestWithContext(
 		ctx,
 		http.MethodPost,
-		"https://id.twitch.tv/oauth2/token",
+		c.AuthURL+"/oauth2/token",
 		strings.NewReader(payload.Encode()),
 	)
 	if err != nil {
 		return nil, err
 	}
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
 	resp, err := c.Client.Do(r)
 	if err != nil {
@@ -219,8 +361,9 @@ func (c *Client) OAuthRefreshToken(ctx context.Context, ua *UserAuth) (*GetToken
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusUnauthorized {
-		return nil, errCannotRefresh
+	// Twitch answers 400 when the refresh token is invalid or revoked
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: status %d", errCannotRefresh, resp.StatusCode)
 	}
 
 	var response GetTokenResponse
@@ -254,7 +397,7 @@ func (c *Client) GetUser(ctx context.Context) (*TwitchUser, error) {
 }
 
 func (c *Client) GetUsersByLogin(ctx context.Context, login ...string) ([]*TwitchUser, error) {
-	u, err := url.Parse("https://api.twitch.tv/helix/users")
+	u, err := url.Parse(c.HelixURL + "/users")
 	if err != nil {
 		return nil, err
 	}
@@ -290,7 +433,7 @@ func (c *Client) GetUsersByLogin(ctx context.Context, login ...string) ([]*Twitc
 
 // GetUsersByID retrieves the twitch users for the given twitch userids
 func (c *Client) GetUsersByID(ctx context.Context, id ...string) ([]*TwitchUser, error) {
-	u, err := url.Parse("https://api.twitch.tv/helix/users")
+	u, err := url.Parse(c.HelixURL + "/users")
 	if err != nil {
 		return nil, err
 	}
@@ -323,3 +466,59 @@ func (c *Client) GetUsersByID(ctx context.Context, id ...string) ([]*TwitchUser,
 
 	return payload.Data, nil
 }
+
+// SubscriptionsScope is the scope a broadcaster has to grant for
+// GetBroadcasterSubscriptions.
+const SubscriptionsScope = "channel:read:subscriptions"
+
+type Subscription struct {
+	BroadcasterID string `json:"broadcaster_id"`
+	UserID        string `json:"user_id"`
+	UserLogin     string `json:"user_login"`
+	UserName      string `json:"user_name"`
+	Tier          string `json:"tier"`
+	IsGift        bool   `json:"is_gift"`
+}
+
+type SubscriptionsPayload struct {
+	Data []*Subscription `json:"data"`
+}
+
+// GetBroadcasterSubscriptions returns the subscriptions to broadcasterID held
+// by the given twitch userids, users that aren't subscribed are left out.
+// ua must be the broadcaster's own token with SubscriptionsScope.
+func (c *Client) GetBroadcasterSubscriptions(ctx context.Context, ua *UserAuth, broadcasterID string, userID ...string) ([]*Subscription, error) {
+	u, err := url.Parse(c.HelixURL + "/subscriptions")
+	if err != nil {
+		return nil, err
+	}
+
+	params := url.Values{
+		"broadcaster_id": []string{broadcasterID},
+		"user_id":        userID,
+	}
+	u.RawQuery = params.Encode()
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.userClient(ua).do(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("getting subscriptions: %d", resp.StatusCode)
+	}
+
+	var payload SubscriptionsPayload
+	err = json.NewDecoder(resp.Body).Decode(&payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return payload.Data, nil
+}
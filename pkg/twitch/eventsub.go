@@ -0,0 +1,168 @@
+package twitch
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	EventSubMessageID        = "Twitch-Eventsub-Message-Id"
+	EventSubMessageTimestamp = "Twitch-Eventsub-Message-Timestamp"
+	EventSubMessageSignature = "Twitch-Eventsub-Message-Signature"
+	EventSubMessageType      = "Twitch-Eventsub-Message-Type"
+
+	EventSubTypeNotification = "notification"
+	EventSubTypeVerification = "webhook_callback_verification"
+	EventSubTypeRevocation   = "revocation"
+
+	RedemptionAddSubscription = "channel.channel_points_custom_reward_redemption.add"
+
+	// Twitch recommends dropping messages older than this to prevent replays
+	eventSubMaxAge = 10 * time.Minute
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid eventsub signature")
+	ErrStaleMessage     = errors.New("stale eventsub message")
+	// ErrSubscriptionExists is returned when twitch already has a matching
+	// subscription for this client.
+	ErrSubscriptionExists = errors.New("eventsub subscription already exists")
+)
+
+// VerifyEventSubMessage checks the HMAC signature twitch attaches to every
+// webhook message and rejects messages that are too old to be trusted.
+func VerifyEventSubMessage(secret string, h http.Header, body []byte, now time.Time) error {
+	id := h.Get(EventSubMessageID)
+	timestamp := h.Get(EventSubMessageTimestamp)
+	signature := h.Get(EventSubMessageSignature)
+	if id == "" || timestamp == "" || signature == "" {
+		return fmt.Errorf("%w: missing headers", ErrInvalidSignature)
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(id))
+	mac.Write([]byte(timestamp))
+	mac.Write(body)
+	expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+
+	sent, err := time.Parse(time.RFC3339Nano, timestamp)
+	if err != nil {
+		return fmt.Errorf("%w: bad timestamp %q", ErrInvalidSignature, timestamp)
+	}
+	if now.Sub(sent) > eventSubMaxAge {
+		return ErrStaleMessage
+	}
+
+	return nil
+}
+
+type EventSubTransport struct {
+	Method   string `json:"method"`
+	Callback string `json:"callback"`
+	Secret   string `json:"secret,omitempty"`
+}
+
+type EventSubSubscription struct {
+	ID        string            `json:"id"`
+	Status    string            `json:"status"`
+	Type      string            `json:"type"`
+	Version   string            `json:"version"`
+	Condition map[string]string `json:"condition"`
+	Transport EventSubTransport `json:"transport"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+type EventSubSubscriptionRequest struct {
+	Type      string            `json:"type"`
+	Version   string            `json:"version"`
+	Condition map[string]string `json:"condition"`
+	Transport EventSubTransport `json:"transport"`
+}
+
+// EventSubMessage is the body of every eventsub webhook, only the fields
+// relevant to the message type are set.
+type EventSubMessage struct {
+	Challenge    string               `json:"challenge"`
+	Subscription EventSubSubscription `json:"subscription"`
+	Event        json.RawMessage      `json:"event"`
+}
+
+type RedemptionReward struct {
+	ID     string `json:"id"`
+	Title  string `json:"title"`
+	Cost   int    `json:"cost"`
+	Prompt string `json:"prompt"`
+}
+
+type RedemptionEvent struct {
+	ID                   string           `json:"id"`
+	BroadcasterUserID    string           `json:"broadcaster_user_id"`
+	BroadcasterUserLogin string           `json:"broadcaster_user_login"`
+	BroadcasterUserName  string           `json:"broadcaster_user_name"`
+	UserID               string           `json:"user_id"`
+	UserLogin            string           `json:"user_login"`
+	UserName             string           `json:"user_name"`
+	UserInput            string           `json:"user_input"`
+	Status               string           `json:"status"`
+	Reward               RedemptionReward `json:"reward"`
+	RedeemedAt           time.Time        `json:"redeemed_at"`
+}
+
+type eventSubSubscriptionsPayload struct {
+	Data []*EventSubSubscription `json:"data"`
+}
+
+// CreateEventSubSubscription registers a webhook subscription, it must be
+// called with the app client rather than a UserClient.
+func (c *Client) CreateEventSubSubscription(ctx context.Context, sub EventSubSubscriptionRequest) (*EventSubSubscription, error) {
+	body, err := json.Marshal(sub)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, c.HelixURL+"/eventsub/subscriptions", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.do(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusConflict {
+		return nil, ErrSubscriptionExists
+	}
+	if resp.StatusCode != http.StatusAccepted {
+		var msg bytes.Buffer
+		msg.ReadFrom(resp.Body)
+		return nil, fmt.Errorf("creating eventsub subscription: %d %s", resp.StatusCode, strings.TrimSpace(msg.String()))
+	}
+
+	var payload eventSubSubscriptionsPayload
+	err = json.NewDecoder(resp.Body).Decode(&payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(payload.Data) < 1 {
+		return nil, fmt.Errorf("no results")
+	}
+
+	return payload.Data[0], nil
+}
@@ -0,0 +1,163 @@
+package twitch
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const testSecret = "eventsub-secret"
+
+func signedHeaders(secret, id string, sent time.Time, body []byte) http.Header {
+	timestamp := sent.UTC().Format(time.RFC3339Nano)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(id))
+	mac.Write([]byte(timestamp))
+	mac.Write(body)
+
+	h := http.Header{}
+	h.Set(EventSubMessageID, id)
+	h.Set(EventSubMessageTimestamp, timestamp)
+	h.Set(EventSubMessageSignature, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	return h
+}
+
+func TestVerifyEventSubMessage(t *testing.T) {
+	now := time.Now()
+	body := []byte(`{"subscription":{"type":"channel.channel_points_custom_reward_redemption.add"}}`)
+
+	tests := []struct {
+		name    string
+		headers http.Header
+		body    []byte
+		want    error
+	}{
+		{
+			name:    "good signature",
+			headers: signedHeaders(testSecret, "msg-1", now, body),
+			body:    body,
+		},
+		{
+			name:    "signed with another secret",
+			headers: signedHeaders("not-the-secret", "msg-1", now, body),
+			body:    body,
+			want:    ErrInvalidSignature,
+		},
+		{
+			name:    "body changed after signing",
+			headers: signedHeaders(testSecret, "msg-1", now, body),
+			body:    []byte(`{"subscription":{}}`),
+			want:    ErrInvalidSignature,
+		},
+		{
+			name:    "missing headers",
+			headers: http.Header{},
+			body:    body,
+			want:    ErrInvalidSignature,
+		},
+		{
+			name:    "stale timestamp",
+			headers: signedHeaders(testSecret, "msg-1", now.Add(-eventSubMaxAge-time.Minute), body),
+			body:    body,
+			want:    ErrStaleMessage,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyEventSubMessage(testSecret, tt.headers, tt.body, now)
+			if tt.want == nil && err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// newHelixStandIn serves the app token endpoint and hands eventsub
+// subscription requests to subscribe.
+func newHelixStandIn(t *testing.T, subscribe http.HandlerFunc) *Client {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "app-token",
+			"expires_in":   3600,
+		})
+	})
+	mux.HandleFunc("/helix/eventsub/subscriptions", subscribe)
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	c, err := NewClient("client-id", "client-secret", srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetEndpoints(srv.URL, srv.URL+"/helix")
+	return c
+}
+
+func TestCreateEventSubSubscription(t *testing.T) {
+	var got EventSubSubscriptionRequest
+	c := newHelixStandIn(t, func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); auth != "Bearer app-token" {
+			t.Errorf("got authorization %q", auth)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]any{
+			"data": []map[string]any{{
+				"id":     "sub-1",
+				"status": "webhook_callback_verification_pending",
+				"type":   got.Type,
+			}},
+		})
+	})
+
+	sub, err := c.CreateEventSubSubscription(context.Background(), EventSubSubscriptionRequest{
+		Type:      RedemptionAddSubscription,
+		Version:   "1",
+		Condition: map[string]string{"broadcaster_user_id": "1234"},
+		Transport: EventSubTransport{
+			Method:   "webhook",
+			Callback: "https://example.com/api/twitch/eventsub",
+			Secret:   testSecret,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sub.ID != "sub-1" {
+		t.Errorf("got subscription %q", sub.ID)
+	}
+	if got.Transport.Secret != testSecret || got.Condition["broadcaster_user_id"] != "1234" {
+		t.Errorf("unexpected request %+v", got)
+	}
+}
+
+func TestCreateEventSubSubscriptionExists(t *testing.T) {
+	c := newHelixStandIn(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+	})
+
+	_, err := c.CreateEventSubSubscription(context.Background(), EventSubSubscriptionRequest{
+		Type: RedemptionAddSubscription,
+	})
+	if !errors.Is(err, ErrSubscriptionExists) {
+		t.Fatalf("got %v, want %v", err, ErrSubscriptionExists)
+	}
+}
@@ -22,3 +22,11 @@ func (m *MockClient) GetUsersByID(ctx context.Context, users ...string) ([]*Twit
 func (m *MockClient) UserClient(ua *UserAuth) UserClient {
 	return m
 }
+
+func (m *MockClient) CreateEventSubSubscription(ctx context.Context, sub EventSubSubscriptionRequest) (*EventSubSubscription, error) {
+	return nil, fmt.Errorf("cannot subscribe to eventsub with mock twitch client")
+}
+
+func (m *MockClient) GetBroadcasterSubscriptions(ctx context.Context, ua *UserAuth, broadcasterID string, users ...string) ([]*Subscription, error) {
+	return nil, fmt.Errorf("cannot lookup subscriptions with mock twitch client")
+}
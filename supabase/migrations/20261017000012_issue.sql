@@ -0,0 +1,2 @@
+ALTER TABLE collectable_instances ADD COLUMN issued_by BIGINT REFERENCES users(id);
+ALTER TABLE collectable_instances ADD COLUMN issue_reason TEXT;
@@ -0,0 +1,2 @@
+ALTER TABLE collectable_instances ADD COLUMN revoked_by BIGINT REFERENCES users(id);
+ALTER TABLE collectable_instances ADD COLUMN revoke_reason TEXT;
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS collection_weight_history (
+  id BIGSERIAL PRIMARY KEY,
+  collection_id BIGINT NOT NULL REFERENCES collections(id),
+  version BIGINT NOT NULL,
+  weights JSONB NOT NULL,
+  changed_by BIGINT REFERENCES users(id),
+  created_at TIMESTAMP NOT NULL DEFAULT now(),
+  UNIQUE (collection_id, version)
+);
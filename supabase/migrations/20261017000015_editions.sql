@@ -0,0 +1,4 @@
+ALTER TABLE editions ADD COLUMN collection_id BIGINT REFERENCES collections(id);
+ALTER TABLE editions ADD COLUMN first_n BIGINT;
+ALTER TABLE editions ADD COLUMN active_at TIMESTAMP;
+ALTER TABLE editions ADD COLUMN retired_at TIMESTAMP;
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS fights (
+  id BIGINT PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS fight_outcomes (
+  fight_id BIGINT NOT NULL REFERENCES fights(id),
+  user_id BIGINT NOT NULL REFERENCES users(id),
+  instance_id BIGINT REFERENCES collectable_instances(id),
+  collectable_id BIGINT REFERENCES collectables(id),
+  outcome TEXT NOT NULL CHECK (outcome IN ('win', 'loss', 'draw')),
+  PRIMARY KEY (fight_id, user_id)
+);
+
+CREATE INDEX fight_outcomes_user_id ON fight_outcomes(user_id);
+CREATE INDEX fight_outcomes_collectable_id ON fight_outcomes(collectable_id);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS events (
+  id BIGINT PRIMARY KEY,
+  name TEXT NOT NULL,
+  slug TEXT NOT NULL UNIQUE,
+  description TEXT,
+  creator_id BIGINT NOT NULL REFERENCES users(id),
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  closed_at TIMESTAMP
+);
+
+ALTER TABLE fights ADD COLUMN event_id BIGINT REFERENCES events(id);
+
+CREATE INDEX fights_event_id ON fights(event_id);
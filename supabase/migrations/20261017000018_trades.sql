@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS trade_offers (
+  id BIGINT PRIMARY KEY,
+  proposer_id BIGINT NOT NULL REFERENCES users(id),
+  recipient_id BIGINT NOT NULL REFERENCES users(id),
+  status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted', 'declined', 'cancelled')),
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  resolved_at TIMESTAMP
+);
+
+CREATE INDEX trade_offers_proposer_id ON trade_offers(proposer_id);
+CREATE INDEX trade_offers_recipient_id ON trade_offers(recipient_id);
+
+CREATE TABLE IF NOT EXISTS trade_offer_items (
+  offer_id BIGINT NOT NULL REFERENCES trade_offers(id),
+  instance_id BIGINT NOT NULL REFERENCES collectable_instances(id),
+  from_user_id BIGINT NOT NULL REFERENCES users(id),
+  PRIMARY KEY (offer_id, instance_id)
+);
+
+CREATE TABLE IF NOT EXISTS ownership_ledger (
+  id BIGSERIAL PRIMARY KEY,
+  instance_id BIGINT NOT NULL REFERENCES collectable_instances(id),
+  from_user_id BIGINT REFERENCES users(id),
+  to_user_id BIGINT NOT NULL REFERENCES users(id),
+  trans_type TEXT NOT NULL CHECK (trans_type IN ('pull', 'issue', 'trade')),
+  trade_offer_id BIGINT REFERENCES trade_offers(id),
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX ownership_ledger_instance_id ON ownership_ledger(instance_id);
+
+-- Every existing instance is still with the user it was pulled or issued to
+INSERT INTO ownership_ledger (instance_id, to_user_id, trans_type, created_at)
+  SELECT id, owner_id, CASE WHEN issued_by IS NULL THEN 'pull' ELSE 'issue' END, created_at
+  FROM collectable_instances;
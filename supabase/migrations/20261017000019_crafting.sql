@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS craft_recipes (
+  collection_id BIGINT PRIMARY KEY REFERENCES collections(id),
+  salvage TEXT NOT NULL,
+  forge TEXT NOT NULL,
+  updated_by BIGINT REFERENCES users(id),
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS material_balances (
+  user_id BIGINT NOT NULL REFERENCES users(id),
+  collection_id BIGINT NOT NULL REFERENCES collections(id),
+  balance BIGINT NOT NULL DEFAULT 0 CHECK (balance >= 0),
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (user_id, collection_id)
+);
+
+ALTER TABLE collectable_instances ADD COLUMN salvaged_at TIMESTAMP;
+
+ALTER TABLE ownership_ledger DROP CONSTRAINT ownership_ledger_trans_type_check;
+ALTER TABLE ownership_ledger ADD CONSTRAINT ownership_ledger_trans_type_check CHECK (trans_type IN ('pull', 'issue', 'trade', 'forge'));
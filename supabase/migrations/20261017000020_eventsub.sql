@@ -0,0 +1,8 @@
+-- Twitch redelivers eventsub notifications until it sees a 2xx, message ids
+-- are recorded so that each redemption only pulls once.
+CREATE TABLE IF NOT EXISTS eventsub_messages (
+  message_id TEXT PRIMARY KEY,
+  message_type TEXT NOT NULL,
+  subscription_type TEXT NOT NULL,
+  received_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
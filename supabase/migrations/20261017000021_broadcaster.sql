@@ -0,0 +1,11 @@
+-- The broadcaster's own twitch token, used for lookups that need the
+-- broadcaster's permission such as checking subscriptions.
+CREATE TABLE IF NOT EXISTS broadcaster_tokens (
+  broadcaster_id TEXT PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id),
+  access_token TEXT NOT NULL,
+  refresh_token TEXT NOT NULL,
+  scopes TEXT NOT NULL,
+  expires_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
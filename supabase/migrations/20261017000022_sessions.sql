@@ -0,0 +1,7 @@
+-- Session tokens are stored as sha256 hashes so that reading the table doesn't
+-- hand out working tokens. Existing sessions are hashed in place.
+UPDATE user_tokens SET token = sha256(token);
+
+ALTER TABLE user_tokens ADD COLUMN session_expires_at TIMESTAMP NOT NULL DEFAULT (CURRENT_TIMESTAMP + INTERVAL '30 days');
+
+CREATE INDEX user_tokens_user_id ON user_tokens(user_id);
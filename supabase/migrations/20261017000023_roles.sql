@@ -0,0 +1,25 @@
+-- Permissions are granted per role instead of the all-or-nothing users.admin
+-- flag. Existing admins keep full access through the admin role, the column
+-- is no longer read.
+CREATE TABLE IF NOT EXISTS user_roles (
+  user_id BIGINT NOT NULL REFERENCES users(id),
+  role TEXT NOT NULL,
+  granted_by BIGINT REFERENCES users(id),
+  created_at TIMESTAMP NOT NULL DEFAULT now(),
+  PRIMARY KEY (user_id, role)
+);
+
+INSERT INTO user_roles (user_id, role)
+  SELECT id, 'admin' FROM users WHERE admin;
+
+-- Every grant and revoke is kept so role changes can be traced
+CREATE TABLE IF NOT EXISTS user_role_changes (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id),
+  role TEXT NOT NULL,
+  action TEXT NOT NULL,
+  actor_id BIGINT NOT NULL REFERENCES users(id),
+  created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX user_role_changes_user_id ON user_role_changes(user_id);
@@ -0,0 +1,27 @@
+-- Administrative and economy changing actions, with the row before and after
+-- the change. Rows can only be added.
+CREATE TABLE IF NOT EXISTS audit_log (
+  id BIGSERIAL PRIMARY KEY,
+  actor_id BIGINT NOT NULL REFERENCES users(id),
+  action TEXT NOT NULL,
+  target_type TEXT NOT NULL,
+  target_id BIGINT NOT NULL,
+  before JSONB,
+  after JSONB,
+  request_id TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX audit_log_created_at ON audit_log(created_at);
+CREATE INDEX audit_log_actor_id ON audit_log(actor_id, created_at);
+CREATE INDEX audit_log_target ON audit_log(target_type, target_id, created_at);
+
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'audit_log is append only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_append_only
+  BEFORE UPDATE OR DELETE ON audit_log
+  FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
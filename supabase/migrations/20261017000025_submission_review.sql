@@ -0,0 +1,11 @@
+-- Submissions can be sent back to the submitter instead of only approved or
+-- deleted. review_feedback holds the latest reason given by the reviewer.
+ALTER TABLE collectables ADD COLUMN review_status TEXT NOT NULL DEFAULT 'pending';
+ALTER TABLE collectables ADD COLUMN review_feedback TEXT;
+ALTER TABLE collectables ADD COLUMN reviewed_by BIGINT REFERENCES users(id);
+ALTER TABLE collectables ADD COLUMN reviewed_at TIMESTAMP;
+
+UPDATE collectables SET review_status = 'approved', reviewed_by = approved_by, reviewed_at = approved_at
+  WHERE approved_at IS NOT NULL;
+
+CREATE INDEX collectables_review_status ON collectables(review_status) WHERE deleted_at IS NULL;
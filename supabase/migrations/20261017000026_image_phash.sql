@@ -0,0 +1,5 @@
+-- Perceptual hash of each upload so re-uploads of the same art can be spotted
+-- in the approval queue. Uploads from before hashing stay NULL.
+ALTER TABLE image_uploads ADD COLUMN phash BIGINT;
+
+CREATE INDEX image_uploads_imagepath ON image_uploads(imagepath);
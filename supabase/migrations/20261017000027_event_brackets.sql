@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS event_matches (
+  event_id BIGINT NOT NULL REFERENCES events(id),
+  round INTEGER NOT NULL,
+  slot INTEGER NOT NULL,
+  first_user_id BIGINT REFERENCES users(id),
+  second_user_id BIGINT REFERENCES users(id),
+  winner_id BIGINT REFERENCES users(id),
+  fight_id BIGINT REFERENCES fights(id),
+  PRIMARY KEY (event_id, round, slot)
+);
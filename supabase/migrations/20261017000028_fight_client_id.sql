@@ -0,0 +1,3 @@
+-- The bot retries fight reports that time out, client_id lets it mark a
+-- report so a retry returns the recorded fight rather than recording another.
+ALTER TABLE fights ADD COLUMN client_id TEXT UNIQUE;